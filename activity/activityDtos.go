@@ -0,0 +1,11 @@
+package activity
+
+import "time"
+
+type ActivityDto struct {
+	Id            uint      `json:"id"`
+	Kind          string    `json:"kind"`
+	ProjectId     uint      `json:"projectId,omitempty"`
+	RelatedUserId uint      `json:"relatedUserId,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
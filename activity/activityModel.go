@@ -0,0 +1,28 @@
+package activity
+
+import "gorm.io/gorm"
+
+const (
+	// The user gave the ownership of a project to RelatedUserId.
+	KindProjectOwnershipGiven = "project-ownership-given"
+	// The user received the ownership of a project from RelatedUserId.
+	KindProjectOwnershipReceived = "project-ownership-received"
+	// RelatedUserId suggested one of the project's roles to the user.
+	KindRoleSuggested = "role-suggested"
+	// RelatedUserId accepted a role the user suggested.
+	KindRoleSuggestionAccepted = "role-suggestion-accepted"
+)
+
+// Something that happened to a user, shown in the user's activity feed.
+type Activity struct {
+	gorm.Model
+
+	UserId uint
+	Kind   string
+
+	// Project related to the activity, if any.
+	ProjectId uint
+
+	// Another user involved in the activity, if any.
+	RelatedUserId uint
+}
@@ -0,0 +1,47 @@
+package activity
+
+import (
+	"github.com/open-collaboration/server/auth"
+	"github.com/open-collaboration/server/utils"
+	"net/http"
+)
+
+// @Summary List your activity feed
+// @Tags users
+// @Router /users/me/activity [get]
+// @Param pageSize query int false "Maximum amount of activities in the response. Default is 20, max is 50."
+// @Param pageOffset query int false "Response page number."
+// @Success 200 {array} dtos.ActivityDto
+func RouteListActivities(
+	writer http.ResponseWriter,
+	request *http.Request,
+	activityService Service,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	pageSize, _ := utils.IntFromQuery(request, "pageSize", 20)
+	pageOffset, _ := utils.IntFromQuery(request, "pageOffset", 0)
+
+	if pageSize < 1 || pageSize > 50 {
+		pageSize = 20
+	}
+
+	if pageOffset < 1 {
+		pageOffset = 0
+	}
+
+	activities, err := activityService.ListActivities(request.Context(), session.UserId(), uint(pageSize), uint(pageOffset))
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusOK, activities)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package activity
+
+import (
+	"context"
+	"github.com/apex/log"
+	"gorm.io/gorm"
+)
+
+type Service interface {
+	// Store activities. The activities are stored using db, which allows
+	// callers to pass a transaction and record activities atomically with
+	// the changes they describe.
+	RecordActivities(ctx context.Context, db *gorm.DB, activities ...Activity) error
+
+	// List a user's activities, newest to oldest.
+	ListActivities(ctx context.Context, userId uint, pageSize uint, pageOffset uint) ([]ActivityDto, error)
+
+	// Permanently delete a user's activities.
+	DeleteActivities(ctx context.Context, userId uint) error
+}
+
+type serviceImpl struct {
+	Db *gorm.DB
+}
+
+func NewService(db *gorm.DB) Service {
+	return &serviceImpl{Db: db}
+}
+
+func (s *serviceImpl) RecordActivities(ctx context.Context, db *gorm.DB, activities ...Activity) error {
+	if len(activities) < 1 {
+		return nil
+	}
+
+	err := db.Create(&activities).Error
+	if err != nil {
+		log.FromContext(ctx).WithError(err).Error("Failed to record activities")
+		return err
+	}
+
+	return nil
+}
+
+func (s *serviceImpl) ListActivities(
+	ctx context.Context,
+	userId uint,
+	pageSize uint,
+	pageOffset uint,
+) ([]ActivityDto, error) {
+	logger := log.FromContext(ctx).WithField("userId", userId)
+
+	var activities []Activity
+	err := s.Db.
+		Where("user_id = ?", userId).
+		Order("created_at desc").
+		Limit(int(pageSize)).
+		Offset(int(pageOffset * pageSize)).
+		Find(&activities).
+		Error
+	if err != nil {
+		logger.WithError(err).Error("Failed to list activities")
+		return nil, err
+	}
+
+	dtos := make([]ActivityDto, len(activities))
+	for i, activity := range activities {
+		dtos[i] = ActivityDto{
+			Id:            activity.ID,
+			Kind:          activity.Kind,
+			ProjectId:     activity.ProjectId,
+			RelatedUserId: activity.RelatedUserId,
+			CreatedAt:     activity.CreatedAt,
+		}
+	}
+
+	return dtos, nil
+}
+
+func (s *serviceImpl) DeleteActivities(ctx context.Context, userId uint) error {
+	err := s.Db.Unscoped().Where("user_id = ?", userId).Delete(&Activity{}).Error
+	if err != nil {
+		log.FromContext(ctx).WithError(err).WithField("userId", userId).Error("Failed to delete activities")
+		return err
+	}
+
+	return nil
+}
@@ -1,7 +1,34 @@
 package auth
 
+import "time"
+
 type LoginDto struct {
 	UsernameOrEmail string `json:"usernameOrEmail"`
 	Password        string `json:"password"`
 	RecaptchaToken  string `json:"recaptchaToken"`
 }
+
+type SessionDto struct {
+	// Derived from the session key, which is not exposed.
+	Id        string    `json:"id"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type ChangePasswordDto struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	NewPassword     string `json:"newPassword" validate:"required,min=6,max=255"`
+}
+
+type ChangeEmailDto struct {
+	Password string `json:"password" validate:"required"`
+	Email    string `json:"email" validate:"required,email,max=255"`
+}
+
+type PasswordResetRequestDto struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type PasswordResetConfirmDto struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=6,max=255"`
+}
@@ -14,7 +14,8 @@ import (
 // @Param credentials body dtos.LoginDto true "The user's credentials"
 // @Success 200 {object} dtos.UserDataDto "User successfully authenticated"
 // @Header 200 {string} Set-Cookie "Session token. E.g. sessionToken=72f34c69-6eb0-47cf-83ed-c2b5ad3989df"
-// @Failure 401
+// @Failure 401 "Wrong username, email or password"
+// @Failure 403 "The user's account is suspended"
 func RouteAuthenticateUser(
 	writer http.ResponseWriter,
 	request *http.Request,
@@ -42,8 +43,9 @@ func RouteAuthenticateUser(
 		}
 
 		userData := users.UserDataDto{
-			Username: user.Username,
-			Email:    user.Email,
+			Username:      user.Username,
+			Email:         user.Email,
+			EmailVerified: user.IsEmailVerified(),
 		}
 
 		cookieHeader := fmt.Sprintf("%s=%s", "sessionToken", sessionToken)
@@ -59,3 +61,127 @@ func RouteAuthenticateUser(
 
 	return nil
 }
+
+// @Summary Request a password reset
+// @Description Emails a link to reset the password if the email is registered. The response is the same
+// @Description whether or not it is.
+// @Tags users
+// @Router /password-reset/request [post]
+// @Param email body dtos.PasswordResetRequestDto true "The account's email"
+// @Success 202
+func RouteRequestPasswordReset(
+	writer http.ResponseWriter,
+	request *http.Request,
+	authService Service,
+) error {
+	dto := PasswordResetRequestDto{}
+	err := utils.ReadJson(request.Context(), request, &dto)
+	if err != nil {
+		return err
+	}
+
+	err = authService.RequestPasswordReset(request.Context(), dto.Email)
+	if err != nil {
+		return err
+	}
+
+	writer.WriteHeader(http.StatusAccepted)
+
+	return nil
+}
+
+// @Summary Reset your password
+// @Description Sets a new password with a token sent by /password-reset/request. All of the user's sessions are
+// @Description invalidated.
+// @Tags users
+// @Router /password-reset/confirm [post]
+// @Param reset body dtos.PasswordResetConfirmDto true "The token and the new password"
+// @Success 204
+func RouteConfirmPasswordReset(
+	writer http.ResponseWriter,
+	request *http.Request,
+	authService Service,
+) error {
+	dto := PasswordResetConfirmDto{}
+	err := utils.ReadJson(request.Context(), request, &dto)
+	if err != nil {
+		return err
+	}
+
+	err = authService.ResetPassword(request.Context(), dto.Token, dto.Password)
+	if err != nil {
+		return err
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// @Summary Change your password
+// @Description All of your other sessions are invalidated.
+// @Tags users
+// @Router /users/me/password [post]
+// @Param passwords body dtos.ChangePasswordDto true "The current and new passwords"
+// @Success 204
+// @Failure 403 "Wrong current password"
+func RouteChangePassword(
+	writer http.ResponseWriter,
+	request *http.Request,
+	authService Service,
+) error {
+	session, err := CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	dto := ChangePasswordDto{}
+	err = utils.ReadJson(request.Context(), request, &dto)
+	if err != nil {
+		return err
+	}
+
+	err = authService.ChangePassword(request.Context(), session, dto.CurrentPassword, dto.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// @Summary Change your email
+// @Description The new email is only used once confirmed with the link sent to it, see /users/confirm-email.
+// @Description Your current email is told about the change.
+// @Tags users
+// @Router /users/me/email [post]
+// @Param email body dtos.ChangeEmailDto true "Your password and the new email"
+// @Success 202
+// @Failure 403 "Wrong password"
+// @Failure 429 "A verification email was sent too recently"
+func RouteChangeEmail(
+	writer http.ResponseWriter,
+	request *http.Request,
+	authService Service,
+) error {
+	session, err := CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	dto := ChangeEmailDto{}
+	err = utils.ReadJson(request.Context(), request, &dto)
+	if err != nil {
+		return err
+	}
+
+	err = authService.ChangeEmail(request.Context(), session.UserId(), dto.Password, dto.Email)
+	if err != nil {
+		return err
+	}
+
+	writer.WriteHeader(http.StatusAccepted)
+
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/apex/log"
 	"github.com/go-redis/redis/v8"
 	"github.com/gofrs/uuid"
+	"github.com/open-collaboration/server/mail"
 	"github.com/open-collaboration/server/users"
 	"gorm.io/gorm"
 	"time"
@@ -17,11 +18,18 @@ import (
 
 var ErrInvalidSessionToken = errors.New("invalid session key")
 var ErrWrongPassword = errors.New("wrong password")
+var ErrUserSuspended = errors.New("user suspended")
+
+// Returned when logging in with a username/email that isn't registered or
+// a wrong password. Both cases return the same error so that callers can't
+// tell whether an account exists.
+var ErrInvalidCredentials = errors.New("invalid credentials")
 
 type Service interface {
 	// Authenticate a user with username or email and a password.
-	// Returns ErrUserNotFound if a user with a matching username/email and password pair cannot be found.
-	// Returns ErrWrongPassword if the hashed password does not equal the user's stored password hash.
+	// Returns ErrInvalidCredentials if there's no user with the username/email or the password is wrong.
+	// Returns ErrUserSuspended if the user's account has been suspended by a moderator.
+	// Authenticating cancels the scheduled deletion of the user's account.
 	AuthenticateUser(ctx context.Context, authUser LoginDto) (*users.User, error)
 
 	// Check if a session exists and, if it does, return the session's user.
@@ -33,19 +41,59 @@ type Service interface {
 
 	// Invalidate (delete) all sessions of a user.
 	InvalidateSessions(ctx context.Context, userId uint) error
+
+	// Email a single use password reset token to the user with the given
+	// email. Nothing happens if there's no such user, so that callers can't
+	// tell whether an email is registered. Requesting a new token
+	// invalidates the previous one.
+	RequestPasswordReset(ctx context.Context, email string) error
+
+	// Set a user's password with a token sent by RequestPasswordReset and
+	// invalidate all of the user's sessions.
+	// Returns ErrInvalidResetToken if the token doesn't exist, expired or
+	// was already used.
+	ResetPassword(ctx context.Context, token string, newPassword string) error
+
+	// Change the password of a session's user and invalidate all of the
+	// user's other sessions.
+	// Returns ErrWrongPassword if currentPassword is not the user's password.
+	ChangePassword(ctx context.Context, session Session, currentPassword string, newPassword string) error
+
+	// Check a user's password and stage a change of the user's email, see
+	// users.Service.RequestEmailChange.
+	// Returns ErrWrongPassword if password is not the user's password and the
+	// errors of users.Service.RequestEmailChange.
+	ChangeEmail(ctx context.Context, userId uint, password string, newEmail string) error
+
+	// Check a user's password, for actions that need more than a session.
+	// Returns ErrWrongPassword if password is not the user's password.
+	CheckPassword(ctx context.Context, userId uint, password string) error
+
+	// List a user's sessions. The session keys themselves are not included.
+	ListSessions(ctx context.Context, userId uint) ([]SessionDto, error)
 }
 
 type serviceImpl struct {
-	Db           *gorm.DB
-	Redis        *redis.Client
-	UsersService users.Service
+	Db            *gorm.DB
+	Redis         *redis.Client
+	UsersService  users.Service
+	Mailer        mail.Mailer
+	PasswordReset PasswordResetConfig
 }
 
-func NewService(db *gorm.DB, redisDb *redis.Client, usersService users.Service) Service {
+func NewService(
+	db *gorm.DB,
+	redisDb *redis.Client,
+	usersService users.Service,
+	mailer mail.Mailer,
+	passwordReset PasswordResetConfig,
+) Service {
 	return &serviceImpl{
-		Db:           db,
-		Redis:        redisDb,
-		UsersService: usersService,
+		Db:            db,
+		Redis:         redisDb,
+		UsersService:  usersService,
+		Mailer:        mailer,
+		PasswordReset: passwordReset,
 	}
 }
 
@@ -58,7 +106,15 @@ func (s *serviceImpl) AuthenticateUser(ctx context.Context, authUser LoginDto) (
 
 	user, err := s.UsersService.FindUserByUsernameOrEmail(ctx, authUser.UsernameOrEmail)
 	if err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			logger.Debug("User not found")
+
+			return nil, ErrInvalidCredentials
+		}
+
 		logger.WithError(err).Error("Failed to authenticate user")
+
+		return nil, err
 	}
 
 	logger.Debug("Comparing passwords")
@@ -69,13 +125,30 @@ func (s *serviceImpl) AuthenticateUser(ctx context.Context, authUser LoginDto) (
 
 		return nil, err
 	} else if passwordMatch {
+		if user.IsSuspended() {
+			logger.Debug("Passwords match, but the user is suspended")
+
+			return nil, ErrUserSuspended
+		}
+
 		logger.Debug("Passwords match, user authenticated")
 
+		if user.DeletionScheduledAt != nil {
+			logger.Debug("Cancelling the user's account deletion")
+
+			_, err := s.UsersService.CancelDeletion(ctx, user.ID)
+			if err != nil {
+				return nil, err
+			}
+
+			user.DeletionScheduledAt = nil
+		}
+
 		return user, nil
 	} else {
 		logger.Debug("Wrong password")
 
-		return nil, ErrWrongPassword
+		return nil, ErrInvalidCredentials
 	}
 }
 
@@ -156,13 +229,11 @@ func (s *serviceImpl) InvalidateSessions(ctx context.Context, userId uint) error
 
 	logger.Debug("Invalidating all sessions of user")
 
-	var sessionsSet []string
-
 	// Get all session tokens of the user by getting the user's
 	// sessions inverted index. It's basically a set that contains
 	// all of the user's sessions.
 	redisKey := sessionInvertedIndexRedisKey(userId)
-	err := s.Redis.GetSet(ctx, redisKey, &sessionsSet).Err()
+	sessionsSet, err := s.Redis.SMembers(ctx, redisKey).Result()
 	if err != nil {
 		logger.WithError(err).Error("Failed to get all of a user's session tokens")
 		return err
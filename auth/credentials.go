@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/apex/log"
+	"github.com/open-collaboration/server/users"
+	"time"
+)
+
+func (s *serviceImpl) ChangePassword(ctx context.Context, session Session, currentPassword string, newPassword string) error {
+	err := s.CheckPassword(ctx, session.UserId(), currentPassword)
+	if err != nil {
+		return err
+	}
+
+	err = s.UsersService.SetPassword(ctx, session.UserId(), newPassword)
+	if err != nil {
+		return err
+	}
+
+	err = s.invalidateOtherSessions(ctx, session)
+	if err != nil {
+		return err
+	}
+
+	return s.UsersService.RecordSecurityEvent(ctx, users.SecurityEvent{
+		UserId: session.UserId(),
+		Kind:   users.SecurityEventPasswordChanged,
+	})
+}
+
+func (s *serviceImpl) ChangeEmail(ctx context.Context, userId uint, password string, newEmail string) error {
+	err := s.CheckPassword(ctx, userId, password)
+	if err != nil {
+		return err
+	}
+
+	return s.UsersService.RequestEmailChange(ctx, userId, newEmail)
+}
+
+func (s *serviceImpl) CheckPassword(ctx context.Context, userId uint, password string) error {
+	user, err := s.UsersService.GetUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	passwordMatch, err := user.ComparePassword(password)
+	if err != nil {
+		log.FromContext(ctx).WithError(err).Error("Error comparing passwords")
+		return err
+	}
+
+	if !passwordMatch {
+		return ErrWrongPassword
+	}
+
+	return nil
+}
+
+// Invalidate all sessions of a user except the given one.
+func (s *serviceImpl) invalidateOtherSessions(ctx context.Context, session Session) error {
+	logger := log.FromContext(ctx).WithField("userId", session.UserId())
+
+	redisKey := sessionInvertedIndexRedisKey(session.UserId())
+	sessionsSet, err := s.Redis.SMembers(ctx, redisKey).Result()
+	if err != nil {
+		logger.WithError(err).Error("Failed to get all of a user's session tokens")
+		return err
+	}
+
+	var others []string
+	var keysToDelete []string
+	for _, key := range sessionsSet {
+		if key != session.token {
+			others = append(others, key)
+			keysToDelete = append(keysToDelete, sessionRedisKey(key))
+		}
+	}
+
+	if len(others) < 1 {
+		return nil
+	}
+
+	err = s.Redis.Del(ctx, keysToDelete...).Err()
+	if err != nil {
+		logger.WithError(err).Error("Failed to delete session token keys")
+		return err
+	}
+
+	err = s.Redis.SRem(ctx, redisKey, others).Err()
+	if err != nil {
+		logger.WithError(err).Error("Failed to remove session tokens from the user's sessions")
+		return err
+	}
+
+	return nil
+}
+
+func (s *serviceImpl) ListSessions(ctx context.Context, userId uint) ([]SessionDto, error) {
+	logger := log.FromContext(ctx).WithField("userId", userId)
+
+	sessionsSet, err := s.Redis.SMembers(ctx, sessionInvertedIndexRedisKey(userId)).Result()
+	if err != nil {
+		logger.WithError(err).Error("Failed to get all of a user's session tokens")
+		return nil, err
+	}
+
+	sessions := []SessionDto{}
+	for _, key := range sessionsSet {
+		ttl, err := s.Redis.TTL(ctx, sessionRedisKey(key)).Result()
+		if err != nil {
+			logger.WithError(err).Error("Failed to get session expiry")
+			return nil, err
+		}
+
+		// Expired sessions stay in the inverted index until the next
+		// InvalidateSessions
+		if ttl < 0 {
+			continue
+		}
+
+		hash := sha256.Sum256([]byte(key))
+		sessions = append(sessions, SessionDto{
+			Id:        hex.EncodeToString(hash[:8]),
+			ExpiresAt: time.Now().Add(ttl).Truncate(time.Second),
+		})
+	}
+
+	return sessions, nil
+}
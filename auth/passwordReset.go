@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/apex/log"
+	"github.com/go-redis/redis/v8"
+	"github.com/open-collaboration/server/mail"
+	"github.com/open-collaboration/server/users"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var ErrInvalidResetToken = errors.New("invalid password reset token")
+
+// Settings of password resets.
+type PasswordResetConfig struct {
+	// How long reset tokens are valid for.
+	TokenTtl time.Duration
+
+	// Page where users choose their new password. The token is added to it
+	// as the token query parameter.
+	ResetUrl string
+}
+
+func (s *serviceImpl) RequestPasswordReset(ctx context.Context, email string) error {
+	logger := log.FromContext(ctx)
+
+	user, err := s.UsersService.FindUserByUsernameOrEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			logger.Debug("Password reset requested for an unknown email")
+			return nil
+		}
+
+		return err
+	}
+
+	// Usernames can't be used to request a reset
+	if !strings.EqualFold(user.Email, email) {
+		logger.Debug("Password reset requested for an unknown email")
+		return nil
+	}
+
+	tokenBytes := make([]byte, 32)
+	_, err = rand.Read(tokenBytes)
+	if err != nil {
+		logger.WithError(err).Error("Failed to generate a password reset token")
+		return err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(tokenBytes)
+	tokenHash := hashResetToken(token)
+
+	// Only the latest token of a user is valid, older ones are deleted.
+	previousHash, err := s.Redis.Get(ctx, passwordResetIndexRedisKey(user.ID)).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		logger.WithError(err).Error("Failed to get the previous password reset token")
+		return err
+	}
+
+	_, err = s.Redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if previousHash != "" {
+			pipe.Del(ctx, passwordResetRedisKey(previousHash))
+		}
+
+		pipe.Set(ctx, passwordResetRedisKey(tokenHash), user.ID, s.PasswordReset.TokenTtl)
+		pipe.Set(ctx, passwordResetIndexRedisKey(user.ID), tokenHash, s.PasswordReset.TokenTtl)
+
+		return nil
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to store password reset token")
+		return err
+	}
+
+	// Sent in the background so that the response takes as long whether
+	// or not the email exists.
+	message := passwordResetMessage(user, s.PasswordReset, token)
+	go func() {
+		err := s.Mailer.Send(context.Background(), message)
+		if err != nil {
+			logger.WithError(err).WithField("userId", user.ID).Error("Failed to send password reset email")
+		}
+	}()
+
+	return nil
+}
+
+func (s *serviceImpl) ResetPassword(ctx context.Context, token string, newPassword string) error {
+	logger := log.FromContext(ctx)
+
+	tokenHash := hashResetToken(token)
+
+	// Get and delete the token at once, so that it can't be used twice
+	var get *redis.StringCmd
+	_, err := s.Redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, passwordResetRedisKey(tokenHash))
+		pipe.Del(ctx, passwordResetRedisKey(tokenHash))
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		logger.WithError(err).Error("Failed to get password reset token")
+		return err
+	}
+
+	userId, err := get.Uint64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return ErrInvalidResetToken
+		}
+
+		logger.WithError(err).Error("Failed to get password reset token")
+		return err
+	}
+
+	err = s.Redis.Del(ctx, passwordResetIndexRedisKey(uint(userId))).Err()
+	if err != nil {
+		logger.WithError(err).Error("Failed to delete password reset token index")
+	}
+
+	err = s.UsersService.SetPassword(ctx, uint(userId), newPassword)
+	if err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			return ErrInvalidResetToken
+		}
+
+		return err
+	}
+
+	err = s.InvalidateSessions(ctx, uint(userId))
+	if err != nil {
+		return err
+	}
+
+	return s.UsersService.RecordSecurityEvent(ctx, users.SecurityEvent{
+		UserId: uint(userId),
+		Kind:   users.SecurityEventPasswordReset,
+	})
+}
+
+func passwordResetMessage(user *users.User, config PasswordResetConfig, token string) mail.Message {
+	link := config.ResetUrl + "?token=" + url.QueryEscape(token)
+	if strings.Contains(config.ResetUrl, "?") {
+		link = config.ResetUrl + "&token=" + url.QueryEscape(token)
+	}
+
+	return mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\n"+
+				"Someone asked to reset the password of your account. "+
+				"Open the link below to choose a new password, it can only be used once and "+
+				"is valid for %d minutes.\n\n"+
+				"%s\n\n"+
+				"If it wasn't you, you can ignore this email. Your password won't change.\n",
+			user.Username,
+			int(config.TokenTtl.Minutes()),
+			link,
+		),
+	}
+}
+
+// Tokens are stored hashed, so that reading redis isn't enough to reset
+// someone's password.
+func hashResetToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// Maps the hash of a password reset token to a user id.
+func passwordResetRedisKey(tokenHash string) string {
+	return fmt.Sprintf("password-reset:%s:user.id", tokenHash)
+}
+
+// Maps a user id to the hash of the user's latest password reset token.
+//
+// It's an inverted index of passwordResetRedisKey.
+func passwordResetIndexRedisKey(userId uint) string {
+	return fmt.Sprintf("user:%d:password-reset.token", userId)
+}
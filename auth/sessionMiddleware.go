@@ -13,7 +13,13 @@ type Session struct {
 	userId uint
 }
 
+// Id of the user that owns the session.
+func (s Session) UserId() uint {
+	return s.userId
+}
+
 var ErrUnauthenticated = errors.New("unauthenticated")
+var ErrForbidden = errors.New("forbidden")
 
 // Checks the incoming request for a session token. If the session token
 // exists and is valid, a session is added to the request's context.
@@ -71,10 +77,19 @@ func getSessionFromRequest(r *http.Request, authService Service) (Session, error
 // Intended to be used inside route handlers (or any handler that executes
 // after SessionMiddleware).
 func CheckSession(r *http.Request) (Session, error) {
-	session := r.Context().Value(Session{})
-	if session == nil {
+	session, ok := SessionFromContext(r.Context())
+	if !ok {
 		return Session{}, ErrUnauthenticated
 	}
 
-	return session.(Session), nil
+	return session, nil
+}
+
+// Get the session added to a request's context by SessionMiddleware.
+// Returns false if the request has no valid session. Intended for services
+// whose behaviour depends on who is making the request.
+func SessionFromContext(ctx context.Context) (Session, bool) {
+	session, ok := ctx.Value(Session{}).(Session)
+
+	return session, ok
 }
@@ -0,0 +1,179 @@
+package embeds
+
+import (
+	"bytes"
+	"math"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// Badge styles, named after the shields.io styles they look like.
+const (
+	BadgeStyleFlat       = "flat"
+	BadgeStyleFlatSquare = "flat-square"
+	BadgeStylePlastic    = "plastic"
+)
+
+// Colours that can be given by name instead of hex.
+var badgeColors = map[string]string{
+	"brightgreen": "#4c1",
+	"green":       "#97ca00",
+	"yellow":      "#dfb317",
+	"yellowgreen": "#a4a61d",
+	"orange":      "#fe7d37",
+	"red":         "#e05d44",
+	"blue":        "#007ec6",
+	"grey":        "#555",
+	"lightgrey":   "#9f9f9f",
+}
+
+var hexColor = regexp.MustCompile(`^#?([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// Widths of the printable ASCII characters (from space to ~) in Verdana at
+// 11px, the font badges are rendered with.
+var verdanaWidths = [95]float64{
+	3.87, 4.33, 5.05, 9.0, 7.0, 11.84, 7.99, 2.95, 4.99, 4.99, 7.0, 9.0, 4.0, 4.99, 4.0, 4.99,
+	7.0, 7.0, 7.0, 7.0, 7.0, 7.0, 7.0, 7.0, 7.0, 7.0,
+	4.99, 4.99, 9.0, 9.0, 9.0, 6.0, 11.0,
+	7.52, 7.54, 7.68, 8.48, 6.96, 6.32, 8.53, 8.27, 4.63, 5.0, 7.62, 6.12, 9.27,
+	8.23, 8.66, 6.63, 8.66, 7.65, 7.52, 6.78, 8.05, 7.52, 10.88, 7.54, 6.77, 7.54,
+	4.99, 4.99, 4.99, 9.0, 7.0, 7.0,
+	6.61, 6.85, 5.73, 6.85, 6.55, 3.87, 6.85, 6.96, 3.02, 3.79, 6.51, 3.02, 10.75,
+	6.96, 6.68, 6.85, 6.85, 4.69, 5.73, 4.33, 6.96, 6.51, 8.98, 6.51, 6.51, 5.78,
+	6.98, 4.99, 6.98, 9.0,
+}
+
+// Width of characters missing from verdanaWidths. Wide (e.g. CJK) characters
+// take about a full em.
+const (
+	defaultCharWidth = 7.0
+	wideCharWidth    = 11.0
+)
+
+// Horizontal padding on each side of a badge's texts.
+const badgePadding = 5
+
+// Get the width in pixels of a text rendered in Verdana at 11px.
+func textWidth(text string) float64 {
+	width := 0.0
+	for _, char := range text {
+		switch {
+		case char >= ' ' && char <= '~':
+			width += verdanaWidths[char-' ']
+		case char >= 0x2e80:
+			width += wideCharWidth
+		default:
+			width += defaultCharWidth
+		}
+	}
+
+	return width
+}
+
+// Get a badge colour as hex, from a colour name or a hex colour with or
+// without the leading #.
+// Returns false if the colour is neither.
+func badgeColor(color string) (string, bool) {
+	if hex, ok := badgeColors[strings.ToLower(color)]; ok {
+		return hex, true
+	}
+
+	if hexColor.MatchString(color) {
+		return "#" + strings.TrimPrefix(color, "#"), true
+	}
+
+	return "", false
+}
+
+type badgeData struct {
+	Style      string
+	Label      string
+	Message    string
+	LabelColor string
+	Color      string
+	Link       string
+
+	Height       int
+	Width        int
+	LabelWidth   int
+	MessageWidth int
+
+	// Positions and lengths of the texts, in tenths of a pixel since the
+	// texts are scaled down by 10 to get sub-pixel positioning.
+	LabelX        int
+	LabelLength   int
+	MessageX      int
+	MessageLength int
+}
+
+var badgeTemplate = template.Must(template.New("badge").Parse(
+	`<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="{{.Width}}" height="{{.Height}}" role="img" aria-label="{{.Label}}: {{.Message}}">` +
+		`<title>{{.Label}}: {{.Message}}</title>` +
+		`{{if eq .Style "flat"}}<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>{{end}}` +
+		`{{if eq .Style "plastic"}}<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#fff" stop-opacity=".7"/><stop offset=".1" stop-color="#aaa" stop-opacity=".1"/><stop offset=".9" stop-color="#000" stop-opacity=".3"/><stop offset="1" stop-color="#000" stop-opacity=".5"/></linearGradient>{{end}}` +
+		`<clipPath id="r"><rect width="{{.Width}}" height="{{.Height}}" rx="{{if eq .Style "flat-square"}}0{{else if eq .Style "plastic"}}4{{else}}3{{end}}" fill="#fff"/></clipPath>` +
+		`<a xlink:href="{{.Link}}" target="_blank">` +
+		`<g clip-path="url(#r)">` +
+		`<rect width="{{.LabelWidth}}" height="{{.Height}}" fill="{{.LabelColor}}"/>` +
+		`<rect x="{{.LabelWidth}}" width="{{.MessageWidth}}" height="{{.Height}}" fill="{{.Color}}"/>` +
+		`{{if ne .Style "flat-square"}}<rect width="{{.Width}}" height="{{.Height}}" fill="url(#s)"/>{{end}}` +
+		`</g>` +
+		`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" text-rendering="geometricPrecision" font-size="110">` +
+		`{{if ne .Style "flat-square"}}<text aria-hidden="true" x="{{.LabelX}}" y="{{if eq .Style "plastic"}}140{{else}}150{{end}}" fill="#010101" fill-opacity=".3" transform="scale(.1)" textLength="{{.LabelLength}}">{{.Label}}</text>{{end}}` +
+		`<text x="{{.LabelX}}" y="{{if eq .Style "plastic"}}130{{else}}140{{end}}" transform="scale(.1)" fill="#fff" textLength="{{.LabelLength}}">{{.Label}}</text>` +
+		`{{if ne .Style "flat-square"}}<text aria-hidden="true" x="{{.MessageX}}" y="{{if eq .Style "plastic"}}140{{else}}150{{end}}" fill="#010101" fill-opacity=".3" transform="scale(.1)" textLength="{{.MessageLength}}">{{.Message}}</text>{{end}}` +
+		`<text x="{{.MessageX}}" y="{{if eq .Style "plastic"}}130{{else}}140{{end}}" transform="scale(.1)" fill="#fff" textLength="{{.MessageLength}}">{{.Message}}</text>` +
+		`</g>` +
+		`</a>` +
+		`</svg>`,
+))
+
+// Render a badge. Texts are escaped here, the other values must already be
+// safe to put in the SVG.
+func renderBadge(style string, label string, message string, labelColor string, color string, link string) ([]byte, error) {
+	labelTextWidth := textWidth(label)
+	messageTextWidth := textWidth(message)
+
+	labelWidth := int(math.Ceil(labelTextWidth)) + 2*badgePadding
+	messageWidth := int(math.Ceil(messageTextWidth)) + 2*badgePadding
+
+	height := 20
+	if style == BadgeStylePlastic {
+		height = 18
+	}
+
+	data := badgeData{
+		Style:      style,
+		Label:      escapeXml(label),
+		Message:    escapeXml(message),
+		LabelColor: labelColor,
+		Color:      color,
+		Link:       escapeXml(link),
+
+		Height:       height,
+		Width:        labelWidth + messageWidth,
+		LabelWidth:   labelWidth,
+		MessageWidth: messageWidth,
+
+		LabelX:        labelWidth * 10 / 2,
+		LabelLength:   int(math.Round(labelTextWidth * 10)),
+		MessageX:      labelWidth*10 + messageWidth*10/2,
+		MessageLength: int(math.Round(messageTextWidth * 10)),
+	}
+
+	body := &bytes.Buffer{}
+	err := badgeTemplate.Execute(body, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return body.Bytes(), nil
+}
+
+func escapeXml(text string) string {
+	body := &bytes.Buffer{}
+	template.HTMLEscape(body, []byte(text))
+
+	return body.String()
+}
@@ -0,0 +1,65 @@
+package embeds
+
+import "html/template"
+
+// Data of a project's card.
+type cardData struct {
+	Url              string
+	OEmbedUrl        string
+	SiteName         string
+	Name             string
+	ShortDescription string
+	Tags             []string
+	Roles            []cardRole
+	Locale           string
+}
+
+type cardRole struct {
+	Title  string
+	Skills []string
+}
+
+var cardTemplate = template.Must(template.New("card").Parse(`<!DOCTYPE html>
+<html lang="{{.Locale}}">
+<head>
+<meta charset="utf-8">
+<title>{{.Name}}</title>
+<meta name="description" content="{{.ShortDescription}}">
+<meta property="og:type" content="website">
+<meta property="og:site_name" content="{{.SiteName}}">
+<meta property="og:title" content="{{.Name}}">
+<meta property="og:description" content="{{.ShortDescription}}">
+<meta property="og:url" content="{{.Url}}">
+<meta name="twitter:card" content="summary">
+<meta name="twitter:title" content="{{.Name}}">
+<meta name="twitter:description" content="{{.ShortDescription}}">
+<link rel="canonical" href="{{.Url}}">
+<link rel="alternate" type="application/json+oembed" href="{{.OEmbedUrl}}&format=json" title="{{.Name}}">
+<link rel="alternate" type="text/xml+oembed" href="{{.OEmbedUrl}}&format=xml" title="{{.Name}}">
+<style>
+body { margin: 0; font-family: sans-serif; color: #222; }
+.card { padding: 16px; border: 1px solid #ddd; border-radius: 8px; }
+.card h1 { margin: 0 0 8px; font-size: 20px; }
+.card h1 a { color: inherit; text-decoration: none; }
+.card p { margin: 0 0 12px; }
+.tags span, .skills span { display: inline-block; margin: 0 4px 4px 0; padding: 2px 8px; border-radius: 12px; background: #eee; font-size: 12px; }
+.roles { margin: 12px 0 0; padding: 0; list-style: none; }
+.roles li { margin-bottom: 8px; }
+</style>
+</head>
+<body>
+<div class="card">
+<h1><a href="{{.Url}}" target="_blank" rel="noopener">{{.Name}}</a></h1>
+<p>{{.ShortDescription}}</p>
+<div class="tags">{{range .Tags}}<span>{{.}}</span>{{end}}</div>
+{{- if .Roles}}
+<ul class="roles">
+{{- range .Roles}}
+<li><strong>{{.Title}}</strong>{{if .Skills}}<div class="skills">{{range .Skills}}<span>{{.}}</span>{{end}}</div>{{end}}</li>
+{{- end}}
+</ul>
+{{- end}}
+</div>
+</body>
+</html>
+`))
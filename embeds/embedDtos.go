@@ -0,0 +1,31 @@
+package embeds
+
+import "encoding/xml"
+
+type BadgeOptions struct {
+	// One of the BadgeStyle constants, flat by default.
+	Style string `validate:"omitempty,oneof=flat flat-square plastic"`
+
+	// Text on the left side of the badge, "open-collab" by default.
+	Label string `validate:"max=40"`
+
+	// Colours of the left and right sides of the badge, as a colour name
+	// (e.g. "brightgreen" or "grey") or a hex colour. By default the right
+	// side is green when the project has open roles and grey otherwise.
+	LabelColor string
+	Color      string
+}
+
+// An oEmbed response (https://oembed.com), serialized as JSON or XML.
+type OEmbedDto struct {
+	XMLName xml.Name `json:"-" xml:"oembed"`
+
+	Type         string `json:"type" xml:"type"`
+	Version      string `json:"version" xml:"version"`
+	Title        string `json:"title" xml:"title"`
+	ProviderName string `json:"provider_name" xml:"provider_name"`
+	ProviderUrl  string `json:"provider_url" xml:"provider_url"`
+	Html         string `json:"html" xml:"html"`
+	Width        int    `json:"width" xml:"width"`
+	Height       int    `json:"height" xml:"height"`
+}
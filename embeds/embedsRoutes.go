@@ -0,0 +1,177 @@
+package embeds
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/open-collaboration/server/utils"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// How long badges can be cached, in seconds.
+const badgeMaxAge = 300
+
+// Returned when an oEmbed response is requested in a format other than json
+// or xml.
+var ErrUnsupportedFormat = errors.New("unsupported oembed format")
+
+// @Summary oEmbed provider for project links
+// @Description Supports links to projects by id, by slug, short links and cards.
+// @Tags embeds
+// @Router /oembed [get]
+// @Param url query string true "The project URL"
+// @Param format query string false "json (default) or xml"
+// @Param maxwidth query int false "Maximum width of the embedded card"
+// @Param maxheight query int false "Maximum height of the embedded card"
+// @Success 200 {object} dtos.OEmbedDto
+// @Success 501 "Unsupported format"
+func RouteOEmbed(
+	writer http.ResponseWriter,
+	request *http.Request,
+	embedsService Service,
+) error {
+	query := request.URL.Query()
+
+	rawUrl := query.Get("url")
+	if rawUrl == "" {
+		return utils.ErrMissingParam
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = "json"
+	}
+
+	if format != "json" && format != "xml" {
+		return ErrUnsupportedFormat
+	}
+
+	maxWidth, _ := utils.IntFromQuery(request, "maxwidth", 0)
+	maxHeight, _ := utils.IntFromQuery(request, "maxheight", 0)
+
+	dto, err := embedsService.GetOEmbed(request.Context(), rawUrl, maxWidth, maxHeight)
+	if err != nil {
+		return err
+	}
+
+	if format == "json" {
+		err = utils.WriteJson(writer, request.Context(), http.StatusOK, dto)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	body, err := xml.Marshal(dto)
+	if err != nil {
+		return err
+	}
+
+	body = append([]byte(xml.Header), body...)
+
+	writer.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	writer.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	writer.WriteHeader(http.StatusOK)
+
+	_, err = writer.Write(body)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary HTML card of a project, for link previews and embedding
+// @Tags embeds
+// @Router /projects/{projectId}/card [get]
+// @Param projectId path int true "The project ID"
+// @Param lang query string false "Preferred locales, e.g. pt-BR,pt. Takes precedence over Accept-Language."
+// @Success 200
+func RouteProjectCard(
+	writer http.ResponseWriter,
+	request *http.Request,
+	embedsService Service,
+) error {
+	projectId, err := utils.UintFromVars(request, "projectId")
+	if err != nil {
+		return err
+	}
+
+	body, err := embedsService.RenderCard(request.Context(), projectId, utils.LocalesFromRequest(request))
+	if err != nil {
+		return err
+	}
+
+	writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	writer.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	writer.WriteHeader(http.StatusOK)
+
+	_, err = writer.Write(body)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary SVG badge showing whether a project is looking for contributors
+// @Description Meant for READMEs, e.g. "open-collab | 3 roles open". Cached for a few minutes and
+// @Description supports conditional requests through If-None-Match.
+// @Tags embeds
+// @Router /projects/{projectId}/badge.svg [get]
+// @Param projectId path int true "The project ID"
+// @Param style query string false "flat (default), flat-square or plastic"
+// @Param label query string false "Text on the left side. Default is open-collab."
+// @Param labelColor query string false "Colour of the left side, a name (e.g. grey) or hex"
+// @Param color query string false "Colour of the right side, a name (e.g. brightgreen) or hex"
+// @Success 200
+// @Success 304
+func RouteProjectBadge(
+	writer http.ResponseWriter,
+	request *http.Request,
+	embedsService Service,
+) error {
+	projectId, err := utils.UintFromVars(request, "projectId")
+	if err != nil {
+		return err
+	}
+
+	query := request.URL.Query()
+
+	body, err := embedsService.RenderBadge(request.Context(), projectId, BadgeOptions{
+		Style:      query.Get("style"),
+		Label:      query.Get("label"),
+		LabelColor: query.Get("labelColor"),
+		Color:      query.Get("color"),
+	})
+	if err != nil {
+		return err
+	}
+
+	etag := utils.ETag(body)
+
+	// Image proxies (e.g. GitHub's) cache badges as long as they're told
+	// to, so badges are only cached briefly to keep their status live.
+	header := writer.Header()
+	header.Set("ETag", etag)
+	header.Set("Cache-Control", fmt.Sprintf("public, max-age=%d, s-maxage=%d", badgeMaxAge, badgeMaxAge))
+
+	if utils.IsNotModified(request, etag, time.Time{}) {
+		writer.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	header.Set("Content-Type", "image/svg+xml; charset=utf-8")
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	writer.WriteHeader(http.StatusOK)
+
+	_, err = writer.Write(body)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,248 @@
+package embeds
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/open-collaboration/server/projects"
+	"github.com/open-collaboration/server/utils"
+	"golang.org/x/text/language"
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const providerName = "Open Collaboration"
+
+// Size of the card embedded by oEmbed consumers, unless they ask for a
+// smaller one.
+const (
+	cardWidth  = 600
+	cardHeight = 320
+)
+
+// Returned when an oEmbed URL is not the URL of a project.
+var ErrUnsupportedUrl = errors.New("url is not a project url")
+
+// Paths of project URLs that can be embedded.
+var (
+	projectIdPath = regexp.MustCompile(`^/projects/([0-9]+)(/card)?/?$`)
+	slugPath      = regexp.MustCompile(`^/projects/by-slug/([^/]+)/?$`)
+	shortLinkPath = regexp.MustCompile(`^/s/([0-9A-Za-z]+)/?$`)
+)
+
+type Service interface {
+	// Get the oEmbed response for a project URL, i.e. the URL of a project by
+	// id, by slug, its short link or its card. The embedded card is at most
+	// maxWidth by maxHeight, zero means no limit.
+	// Returns ErrUnsupportedUrl if the URL is not a project URL and
+	// projects.ErrProjectNotFound if the project can't be seen.
+	GetOEmbed(ctx context.Context, rawUrl string, maxWidth int, maxHeight int) (OEmbedDto, error)
+
+	// Render the HTML card of a project, with Open Graph and Twitter meta tags
+	// for link previews. The card is in the locale that best matches locales.
+	// Returns projects.ErrProjectNotFound if the project can't be seen.
+	RenderCard(ctx context.Context, projectId uint, locales []language.Tag) ([]byte, error)
+
+	// Render an SVG badge showing how many roles a project has open, linking
+	// to the project.
+	// Returns projects.ErrProjectNotFound if the project can't be seen and
+	// utils.ErrInvalidParam if the options have an unknown colour.
+	RenderBadge(ctx context.Context, projectId uint, options BadgeOptions) ([]byte, error)
+}
+
+type serviceImpl struct {
+	ProjectsService projects.Service
+
+	// Public URL of the API, without a trailing slash.
+	PublicUrl string
+}
+
+func NewService(projectsService projects.Service, publicUrl string) Service {
+	return &serviceImpl{
+		ProjectsService: projectsService,
+		PublicUrl:       strings.TrimSuffix(publicUrl, "/"),
+	}
+}
+
+func (s *serviceImpl) GetOEmbed(ctx context.Context, rawUrl string, maxWidth int, maxHeight int) (OEmbedDto, error) {
+	project, err := s.findProject(ctx, rawUrl)
+	if err != nil {
+		return OEmbedDto{}, err
+	}
+
+	width := cardWidth
+	if maxWidth > 0 && maxWidth < width {
+		width = maxWidth
+	}
+
+	height := cardHeight
+	if maxHeight > 0 && maxHeight < height {
+		height = maxHeight
+	}
+
+	cardUrl := fmt.Sprintf("%s/projects/%d/card", s.PublicUrl, project.Id)
+
+	return OEmbedDto{
+		Type:         "rich",
+		Version:      "1.0",
+		Title:        project.Name,
+		ProviderName: providerName,
+		ProviderUrl:  s.PublicUrl,
+		Html: fmt.Sprintf(
+			`<iframe src="%s" width="%d" height="%d" frameborder="0" scrolling="no" title="%s"></iframe>`,
+			cardUrl,
+			width,
+			height,
+			html.EscapeString(project.Name),
+		),
+		Width:  width,
+		Height: height,
+	}, nil
+}
+
+// Find the project a URL points to.
+func (s *serviceImpl) findProject(ctx context.Context, rawUrl string) (projects.ProjectDto, error) {
+	parsed, err := url.Parse(rawUrl)
+	if err != nil {
+		return projects.ProjectDto{}, ErrUnsupportedUrl
+	}
+
+	public, err := url.Parse(s.PublicUrl)
+	if err != nil || !strings.EqualFold(parsed.Host, public.Host) {
+		return projects.ProjectDto{}, ErrUnsupportedUrl
+	}
+
+	path := strings.TrimPrefix(parsed.Path, public.Path)
+
+	if match := projectIdPath.FindStringSubmatch(path); match != nil {
+		projectId, err := strconv.ParseUint(match[1], 10, 0)
+		if err != nil {
+			return projects.ProjectDto{}, ErrUnsupportedUrl
+		}
+
+		return s.ProjectsService.GetProject(ctx, uint(projectId), nil)
+	}
+
+	slug := ""
+	if match := slugPath.FindStringSubmatch(path); match != nil {
+		slug = match[1]
+	} else if match := shortLinkPath.FindStringSubmatch(path); match != nil {
+		linkUid, ok := utils.DecodeBase62(match[1])
+		if !ok {
+			return projects.ProjectDto{}, projects.ErrProjectNotFound
+		}
+
+		slug, err = s.ProjectsService.GetSlugByLinkUid(ctx, uint(linkUid))
+		if err != nil {
+			return projects.ProjectDto{}, err
+		}
+	} else {
+		return projects.ProjectDto{}, ErrUnsupportedUrl
+	}
+
+	project, err := s.ProjectsService.GetProjectBySlug(ctx, slug, nil)
+
+	// Old slugs still point to the project
+	var slugMoved *projects.SlugMovedError
+	if errors.As(err, &slugMoved) {
+		project, err = s.ProjectsService.GetProjectBySlug(ctx, slugMoved.Slug, nil)
+	}
+
+	return project, err
+}
+
+func (s *serviceImpl) RenderCard(ctx context.Context, projectId uint, locales []language.Tag) ([]byte, error) {
+	project, err := s.ProjectsService.GetProject(ctx, projectId, locales)
+	if err != nil {
+		return nil, err
+	}
+
+	projectUrl := s.PublicUrl + "/projects/by-slug/" + project.Slug
+
+	data := cardData{
+		Url:              projectUrl,
+		OEmbedUrl:        s.PublicUrl + "/oembed?url=" + url.QueryEscape(projectUrl),
+		SiteName:         providerName,
+		Name:             project.Name,
+		ShortDescription: project.ShortDescription,
+		Tags:             project.Tags,
+		Roles:            make([]cardRole, len(project.Roles)),
+		Locale:           project.Locale,
+	}
+
+	// ProjectDto only has the open roles
+	for i, role := range project.Roles {
+		data.Roles[i] = cardRole{
+			Title:  role.Title,
+			Skills: role.Skills,
+		}
+	}
+
+	body := &bytes.Buffer{}
+	err = cardTemplate.Execute(body, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return body.Bytes(), nil
+}
+
+func (s *serviceImpl) RenderBadge(ctx context.Context, projectId uint, options BadgeOptions) ([]byte, error) {
+	err := validator.New().Struct(options)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Style == "" {
+		options.Style = BadgeStyleFlat
+	}
+
+	if options.Label == "" {
+		options.Label = "open-collab"
+	}
+
+	if options.LabelColor == "" {
+		options.LabelColor = "grey"
+	}
+
+	labelColor, ok := badgeColor(options.LabelColor)
+	if !ok {
+		return nil, utils.ErrInvalidParam
+	}
+
+	project, err := s.ProjectsService.GetProject(ctx, projectId, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// ProjectDto only has the open roles
+	openRoles := len(project.Roles)
+
+	message := "not recruiting"
+	if openRoles == 1 {
+		message = "1 role open"
+	} else if openRoles > 1 {
+		message = fmt.Sprintf("%d roles open", openRoles)
+	}
+
+	if options.Color == "" {
+		options.Color = "lightgrey"
+		if openRoles > 0 {
+			options.Color = "brightgreen"
+		}
+	}
+
+	color, ok := badgeColor(options.Color)
+	if !ok {
+		return nil, utils.ErrInvalidParam
+	}
+
+	link := s.PublicUrl + "/projects/by-slug/" + project.Slug
+
+	return renderBadge(options.Style, options.Label, message, labelColor, color, link)
+}
@@ -0,0 +1,76 @@
+package events
+
+import (
+	"context"
+	"gorm.io/gorm"
+)
+
+// Types of events, named "<subject>.<action>".
+const (
+	ProjectCreated     = "project.created"
+	ProjectUpdated     = "project.updated"
+	ProjectTransferred = "project.transferred"
+	ProjectHidden      = "project.hidden"
+	ProjectUnhidden    = "project.unhidden"
+	RoleOpened         = "role.opened"
+	RoleClosed         = "role.closed"
+	MilestoneCreated   = "milestone.created"
+	MilestoneUpdated   = "milestone.updated"
+	TaskCreated        = "task.created"
+	TaskUpdated        = "task.updated"
+	TaskMoved          = "task.moved"
+	TaskDeleted        = "task.deleted"
+)
+
+// Something that happened in a project.
+type Event struct {
+	Type      string
+	ProjectId uint
+
+	// User that caused the event, 0 if it's unknown.
+	ActorId uint
+
+	// Data specific to the event type, sent as JSON.
+	Data interface{}
+}
+
+// Publishes events to whoever is interested in them (e.g. webhooks).
+// Publishers are kept behind this interface so that the packages that
+// emit events don't depend on the packages that consume them.
+type Publisher interface {
+	// Publish events. Events are published using db, which allows callers
+	// to pass a transaction so that the events are only published if the
+	// changes they describe are committed.
+	Publish(ctx context.Context, db *gorm.DB, events ...Event) error
+}
+
+// Notified of events once the changes they describe are committed. Unlike
+// publishers, listeners don't take part in the changes' transaction, which
+// suits consumers that must not act before the commit (e.g. caches, which
+// could be refilled with the old data if invalidated before it).
+type Listener interface {
+	// Handle events whose changes were committed. Listeners can't fail
+	// the changes, so errors are up to them.
+	Committed(ctx context.Context, events ...Event)
+}
+
+type multiPublisher struct {
+	Publishers []Publisher
+}
+
+// Create a Publisher that publishes events to each of the given publishers,
+// in order. Publishing stops at the first publisher that fails.
+func NewMultiPublisher(publishers ...Publisher) Publisher {
+	return &multiPublisher{Publishers: publishers}
+}
+
+func (p *multiPublisher) Publish(ctx context.Context, db *gorm.DB, events ...Event) error {
+	for _, publisher := range p.Publishers {
+		err := publisher.Publish(ctx, db, events...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
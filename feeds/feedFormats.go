@@ -0,0 +1,232 @@
+package feeds
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+const (
+	FormatAtom = "atom"
+	FormatRss  = "rss"
+	FormatJson = "json"
+)
+
+// Content type of each feed format.
+var contentTypes = map[string]string{
+	FormatAtom: "application/atom+xml; charset=utf-8",
+	FormatRss:  "application/rss+xml; charset=utf-8",
+	FormatJson: "application/feed+json; charset=utf-8",
+}
+
+// Write a feed in the given format.
+func writeFeed(writer io.Writer, format string, feed Feed) error {
+	switch format {
+	case FormatAtom:
+		return writeXml(writer, atomFeedOf(feed))
+	case FormatRss:
+		return writeXml(writer, rssFeedOf(feed))
+	default:
+		return json.NewEncoder(writer).Encode(jsonFeedOf(feed))
+	}
+}
+
+func writeXml(writer io.Writer, feed interface{}) error {
+	_, err := io.WriteString(writer, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	return xml.NewEncoder(writer).Encode(feed)
+}
+
+// Atom, see RFC 4287.
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Id       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle"`
+	Updated  string      `xml:"updated"`
+	Author   atomAuthor  `xml:"author"`
+	Links    []atomLink  `xml:"link"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomEntry struct {
+	Id         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Link       atomLink       `xml:"link"`
+	Summary    string         `xml:"summary"`
+	Categories []atomCategory `xml:"category"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+}
+
+func atomFeedOf(feed Feed) atomFeed {
+	atom := atomFeed{
+		Id:       feed.Id,
+		Title:    feed.Title,
+		Subtitle: feed.Description,
+		// Atom requires an update time even for empty feeds
+		Updated: atomTime(feed.Updated),
+		Author:  atomAuthor{Name: "Open Collaboration"},
+		Links: []atomLink{
+			{Rel: "self", Type: contentTypes[FormatAtom], Href: feed.FeedUrl},
+			{Rel: "alternate", Href: feed.HomePageUrl},
+		},
+		Entries: make([]atomEntry, len(feed.Items)),
+	}
+
+	for i, item := range feed.Items {
+		categories := make([]atomCategory, len(item.Tags))
+		for j, tag := range item.Tags {
+			categories[j] = atomCategory{Term: tag}
+		}
+
+		atom.Entries[i] = atomEntry{
+			Id:         item.Id,
+			Title:      item.Title,
+			Link:       atomLink{Rel: "alternate", Href: item.Url},
+			Summary:    item.Summary,
+			Categories: categories,
+			Published:  atomTime(item.Published),
+			Updated:    atomTime(item.Updated),
+		}
+	}
+
+	return atom
+}
+
+func atomTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// RSS 2.0, see https://www.rssboard.org/rss-specification.
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssGuid struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	Guid        rssGuid  `xml:"guid"`
+	Categories  []string `xml:"category"`
+	PubDate     string   `xml:"pubDate"`
+}
+
+func rssFeedOf(feed Feed) rssFeed {
+	rss := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       feed.Title,
+			Link:        feed.HomePageUrl,
+			Description: feed.Description,
+			Items:       make([]rssItem, len(feed.Items)),
+		},
+	}
+
+	if !feed.Updated.IsZero() {
+		rss.Channel.LastBuildDate = rssTime(feed.Updated)
+	}
+
+	for i, item := range feed.Items {
+		rss.Channel.Items[i] = rssItem{
+			Title:       item.Title,
+			Link:        item.Url,
+			Description: item.Summary,
+			Guid:        rssGuid{IsPermaLink: false, Value: item.Id},
+			Categories:  item.Tags,
+			// RSS items only have one date, use the update time so that
+			// readers show updated projects again.
+			PubDate: rssTime(item.Updated),
+		}
+	}
+
+	return rss
+}
+
+func rssTime(t time.Time) string {
+	return t.UTC().Format(time.RFC1123Z)
+}
+
+// JSON Feed 1.1, see https://www.jsonfeed.org/version/1.1.
+
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	HomePageUrl string         `json:"home_page_url"`
+	FeedUrl     string         `json:"feed_url"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	Id            string   `json:"id"`
+	Url           string   `json:"url"`
+	Title         string   `json:"title"`
+	Summary       string   `json:"summary"`
+	ContentText   string   `json:"content_text"`
+	Tags          []string `json:"tags,omitempty"`
+	DatePublished string   `json:"date_published"`
+	DateModified  string   `json:"date_modified"`
+}
+
+func jsonFeedOf(feed Feed) jsonFeed {
+	jf := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       feed.Title,
+		Description: feed.Description,
+		HomePageUrl: feed.HomePageUrl,
+		FeedUrl:     feed.FeedUrl,
+		Items:       make([]jsonFeedItem, len(feed.Items)),
+	}
+
+	for i, item := range feed.Items {
+		jf.Items[i] = jsonFeedItem{
+			Id:    item.Id,
+			Url:   item.Url,
+			Title: item.Title,
+			// Items must have content, the summary is all there is
+			Summary:       item.Summary,
+			ContentText:   item.Summary,
+			Tags:          item.Tags,
+			DatePublished: atomTime(item.Published),
+			DateModified:  atomTime(item.Updated),
+		}
+	}
+
+	return jf
+}
@@ -0,0 +1,33 @@
+package feeds
+
+import "time"
+
+// A feed, independent of the format it's served in.
+type Feed struct {
+	// Unique and permanent id of the feed.
+	Id          string
+	Title       string
+	Description string
+
+	// Link to the page the feed is about.
+	HomePageUrl string
+
+	// Link to the feed itself.
+	FeedUrl string
+
+	// When the most recent item was updated. Zero if the feed is empty.
+	Updated time.Time
+
+	Items []Item
+}
+
+type Item struct {
+	// Unique and permanent id of the item.
+	Id        string
+	Url       string
+	Title     string
+	Summary   string
+	Tags      []string
+	Published time.Time
+	Updated   time.Time
+}
@@ -0,0 +1,67 @@
+package feeds
+
+import (
+	"bytes"
+	"github.com/gorilla/mux"
+	"github.com/open-collaboration/server/utils"
+	"net/http"
+	"strconv"
+)
+
+// @Summary Feed of new and updated projects
+// @Description Available as Atom (/feeds/projects.atom), RSS 2.0 (/feeds/projects.rss) and
+// @Description JSON Feed 1.1 (/feeds/projects.json). Supports conditional requests through
+// @Description If-None-Match and If-Modified-Since.
+// @Tags feeds
+// @Router /feeds/projects.{format} [get]
+// @Param format path string true "atom, rss or json"
+// @Param tags query []string false "Only include projects with at least one of these tags"
+// @Success 200
+// @Success 304
+func RouteProjectsFeed(
+	writer http.ResponseWriter,
+	request *http.Request,
+	feedsService Service,
+) error {
+	format := mux.Vars(request)["format"]
+	if _, ok := contentTypes[format]; !ok {
+		return utils.ErrInvalidParam
+	}
+
+	feed, err := feedsService.GetProjectsFeed(request.Context(), format, utils.ListFromQuery(request, "tags"))
+	if err != nil {
+		return err
+	}
+
+	// Feeds are small, rendering them up front gives an ETag that changes
+	// whenever the content does.
+	body := &bytes.Buffer{}
+	err = writeFeed(body, format, feed)
+	if err != nil {
+		return err
+	}
+
+	etag := utils.ETag(body.Bytes())
+
+	header := writer.Header()
+	header.Set("ETag", etag)
+	if !feed.Updated.IsZero() {
+		header.Set("Last-Modified", feed.Updated.UTC().Format(http.TimeFormat))
+	}
+
+	if utils.IsNotModified(request, etag, feed.Updated) {
+		writer.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	header.Set("Content-Type", contentTypes[format])
+	header.Set("Content-Length", strconv.Itoa(body.Len()))
+	writer.WriteHeader(http.StatusOK)
+
+	_, err = writer.Write(body.Bytes())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
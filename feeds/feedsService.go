@@ -0,0 +1,78 @@
+package feeds
+
+import (
+	"context"
+	"fmt"
+	"github.com/open-collaboration/server/projects"
+	"net/url"
+	"strings"
+)
+
+// Maximum amount of items in a feed.
+const feedSize = 50
+
+type Service interface {
+	// Get a feed of the most recently created or updated projects, optionally
+	// filtered by tags. format is the extension of the feed's URL (atom, rss
+	// or json), used to build the feed's link to itself.
+	GetProjectsFeed(ctx context.Context, format string, tags []string) (Feed, error)
+}
+
+type serviceImpl struct {
+	ProjectsService projects.Service
+
+	// Public URL of the API, without a trailing slash. Feeds are read
+	// outside of the API's clients, so their links must be absolute.
+	PublicUrl string
+}
+
+func NewService(projectsService projects.Service, publicUrl string) Service {
+	return &serviceImpl{
+		ProjectsService: projectsService,
+		PublicUrl:       strings.TrimSuffix(publicUrl, "/"),
+	}
+}
+
+func (s *serviceImpl) GetProjectsFeed(ctx context.Context, format string, tags []string) (Feed, error) {
+	recentProjects, err := s.ProjectsService.ListRecentProjects(ctx, tags, feedSize)
+	if err != nil {
+		return Feed{}, err
+	}
+
+	feedPath := "/feeds/projects"
+	title := "Open Collaboration projects"
+	query := ""
+	if len(tags) > 0 {
+		title += " tagged " + strings.Join(tags, ", ")
+		query = "?" + url.Values{"tags": []string{strings.Join(tags, ",")}}.Encode()
+	}
+
+	feed := Feed{
+		Id:          s.PublicUrl + feedPath + query,
+		Title:       title,
+		Description: "New and updated projects looking for collaborators",
+		HomePageUrl: s.PublicUrl + "/projects" + query,
+		FeedUrl:     s.PublicUrl + feedPath + "." + format + query,
+		Items:       make([]Item, len(recentProjects)),
+	}
+
+	for i, project := range recentProjects {
+		feed.Items[i] = Item{
+			// Slugs change when projects are renamed, ids don't
+			Id:        fmt.Sprintf("%s/projects/%d", s.PublicUrl, project.Id),
+			Url:       s.PublicUrl + "/projects/by-slug/" + project.Slug,
+			Title:     project.Name,
+			Summary:   project.ShortDescription,
+			Tags:      project.Tags,
+			Published: project.CreatedAt,
+			Updated:   project.UpdatedAt,
+		}
+	}
+
+	// Projects are ordered by their update time
+	if len(recentProjects) > 0 {
+		feed.Updated = recentProjects[0].UpdatedAt
+	}
+
+	return feed, nil
+}
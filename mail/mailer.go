@@ -0,0 +1,80 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrUnknownDriver = errors.New("unknown mail driver")
+
+// Returned when the recipient or subject of an email has line breaks, which
+// could be used to inject headers.
+var ErrInvalidHeader = errors.New("line break in email header")
+
+// An email with a plain text body.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sends emails. Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// Mail settings, read from the environment in main.
+type Config struct {
+	// One of "smtp", "file" or "memory".
+	Driver string
+
+	// Address the emails are sent from.
+	From string
+
+	// SMTP server, used by the smtp driver.
+	SmtpHost     string
+	SmtpPort     int
+	SmtpUsername string
+	SmtpPassword string
+
+	// Directory the file driver writes emails to.
+	OutboxDir string
+}
+
+// Create the mailer selected by config.Driver.
+// Returns ErrUnknownDriver if the driver doesn't exist.
+func NewMailer(config Config) (Mailer, error) {
+	switch config.Driver {
+	case "smtp":
+		return NewSmtpMailer(config.SmtpHost, config.SmtpPort, config.SmtpUsername, config.SmtpPassword, config.From), nil
+	case "file":
+		return NewFileOutbox(config.OutboxDir, config.From), nil
+	case "memory":
+		return NewMemoryOutbox(), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDriver, config.Driver)
+	}
+}
+
+// Format a message as an RFC 5322 email.
+func formatMessage(from string, message Message, date time.Time) []byte {
+	builder := strings.Builder{}
+	builder.WriteString("From: " + from + "\r\n")
+	builder.WriteString("To: " + message.To + "\r\n")
+	builder.WriteString("Subject: " + message.Subject + "\r\n")
+	builder.WriteString("Date: " + date.Format(time.RFC1123Z) + "\r\n")
+	builder.WriteString("MIME-Version: 1.0\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+
+	return []byte(builder.String())
+}
+
+// Whether a header value would let the sender inject other headers.
+func hasLineBreak(value string) bool {
+	return strings.ContainsAny(value, "\r\n")
+}
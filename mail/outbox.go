@@ -0,0 +1,72 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type fileOutbox struct {
+	Dir  string
+	From string
+}
+
+// Create a mailer that writes emails to .eml files in dir instead of
+// sending them. Meant for development.
+func NewFileOutbox(dir string, from string) Mailer {
+	return &fileOutbox{
+		Dir:  dir,
+		From: from,
+	}
+}
+
+func (m *fileOutbox) Send(ctx context.Context, message Message) error {
+	if hasLineBreak(message.To) || hasLineBreak(message.Subject) {
+		return ErrInvalidHeader
+	}
+
+	err := os.MkdirAll(m.Dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("%d-%s.eml", now.UnixNano(), message.To)
+
+	return ioutil.WriteFile(filepath.Join(m.Dir, name), formatMessage(m.From, message, now), 0644)
+}
+
+// A mailer that keeps sent emails in memory. Meant for tests.
+type MemoryOutbox struct {
+	mutex    sync.Mutex
+	messages []Message
+}
+
+func NewMemoryOutbox() *MemoryOutbox {
+	return &MemoryOutbox{}
+}
+
+func (m *MemoryOutbox) Send(ctx context.Context, message Message) error {
+	if hasLineBreak(message.To) || hasLineBreak(message.Subject) {
+		return ErrInvalidHeader
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.messages = append(m.messages, message)
+
+	return nil
+}
+
+// Get the emails sent so far, oldest first.
+func (m *MemoryOutbox) Messages() []Message {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return append([]Message(nil), m.messages...)
+}
@@ -0,0 +1,37 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"time"
+)
+
+type smtpMailer struct {
+	Addr string
+	Auth smtp.Auth
+	From string
+}
+
+// Create a mailer that sends emails through an SMTP server. Authentication
+// is skipped if username is empty.
+func NewSmtpMailer(host string, port int, username string, password string, from string) Mailer {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &smtpMailer{
+		Addr: fmt.Sprintf("%s:%d", host, port),
+		Auth: auth,
+		From: from,
+	}
+}
+
+func (m *smtpMailer) Send(ctx context.Context, message Message) error {
+	if hasLineBreak(message.To) || hasLineBreak(message.Subject) {
+		return ErrInvalidHeader
+	}
+
+	return smtp.SendMail(m.Addr, m.Auth, m.From, []string{message.To}, formatMessage(m.From, message, time.Now()))
+}
@@ -6,17 +6,27 @@ import (
 	"github.com/apex/log"
 	"github.com/go-redis/redis/v8"
 	"github.com/joho/godotenv"
+	"github.com/open-collaboration/server/activity"
 	"github.com/open-collaboration/server/auth"
+	"github.com/open-collaboration/server/embeds"
+	"github.com/open-collaboration/server/feeds"
+	"github.com/open-collaboration/server/mail"
 	"github.com/open-collaboration/server/migrations"
+	"github.com/open-collaboration/server/moderation"
+	"github.com/open-collaboration/server/privacy"
+	"github.com/open-collaboration/server/profiles"
 	"github.com/open-collaboration/server/projects"
 	router2 "github.com/open-collaboration/server/router"
+	"github.com/open-collaboration/server/sitemap"
 	"github.com/open-collaboration/server/users"
 	"github.com/open-collaboration/server/utils"
+	"github.com/open-collaboration/server/webhooks"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 	"net/http"
 	"os"
+	"time"
 )
 
 func main() {
@@ -72,18 +82,108 @@ func main() {
 	}
 
 	// Setup server
-	usersService := users.NewService(db)
+	host := utils.GetEnvOrPanic("HOST")
+	port := utils.GetEnvOrPanic("PORT")
+	publicUrl := utils.GetEnvOrDefault("PUBLIC_URL", fmt.Sprintf("http://%s:%s", host, port))
+
+	publisher := webhooks.NewPublisher()
+
+	mailer, err := mail.NewMailer(mail.Config{
+		Driver:       utils.GetEnvOrDefault("MAIL_DRIVER", "file"),
+		From:         utils.GetEnvOrDefault("MAIL_FROM", "no-reply@localhost"),
+		SmtpHost:     utils.GetEnvOrDefault("SMTP_HOST", "localhost"),
+		SmtpPort:     utils.GetEnvIntOrDefault("SMTP_PORT", 587),
+		SmtpUsername: os.Getenv("SMTP_USERNAME"),
+		SmtpPassword: os.Getenv("SMTP_PASSWORD"),
+		OutboxDir:    utils.GetEnvOrDefault("MAIL_OUTBOX_DIR", "outbox"),
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to setup mailer.")
+		panic(err)
+	}
+
+	sessionSecret := utils.GetEnvOrPanic("SESSION_SECRET")
+	if sessionSecret == "" {
+		panic("\"SESSION_SECRET\" environment variable is empty")
+	}
+
+	usersService := users.NewService(db, mailer, users.VerificationConfig{
+		Secret:         []byte(sessionSecret),
+		TokenTtl:       time.Duration(utils.GetEnvIntOrDefault("EMAIL_VERIFICATION_TTL_HOURS", 48)) * time.Hour,
+		ResendCooldown: time.Duration(utils.GetEnvIntOrDefault("EMAIL_VERIFICATION_RESEND_MINUTES", 5)) * time.Minute,
+		VerifyUrl:      utils.GetEnvOrDefault("EMAIL_VERIFICATION_URL", publicUrl+"/verify-email"),
+		EmailChangeUrl: utils.GetEnvOrDefault("EMAIL_CHANGE_URL", publicUrl+"/confirm-email"),
+	})
+	skillsService := users.NewSkillsService(db)
+	directoryService := users.NewDirectoryService(db)
+	blocksService := users.NewBlocksService(db)
+	authService := auth.NewService(db, redisDb, usersService, mailer, auth.PasswordResetConfig{
+		TokenTtl: time.Duration(utils.GetEnvIntOrDefault("PASSWORD_RESET_TTL_MINUTES", 30)) * time.Minute,
+		ResetUrl: utils.GetEnvOrDefault("PASSWORD_RESET_URL", publicUrl+"/reset-password"),
+	})
+	projectsService := projects.NewService(db, projects.QuotaPolicy{
+		MaxOwnedProjects:      utils.GetEnvIntOrDefault("PROJECT_QUOTA_MAX_OWNED", 1),
+		MaxRecruitingProjects: utils.GetEnvIntOrDefault("PROJECT_QUOTA_MAX_RECRUITING", 1),
+		MaxProjectsPerDay:     utils.GetEnvIntOrDefault("PROJECT_QUOTA_MAX_PER_DAY", 3),
+	}, publisher, sitemap.NewCacheInvalidator(redisDb))
+	activityService := activity.NewService(db)
+
+	reportThreshold := utils.GetEnvIntOrDefault("REPORT_AUTO_HIDE_THRESHOLD", 5)
+	moderationService := moderation.NewService(db, usersService, projectsService, authService, reportThreshold)
+	privacyService := privacy.NewService(
+		usersService,
+		skillsService,
+		projectsService,
+		activityService,
+		moderationService,
+		authService,
+		time.Duration(utils.GetEnvIntOrDefault("ACCOUNT_DELETION_COOLING_OFF_DAYS", 14))*24*time.Hour,
+	)
+	transferExpiry := time.Duration(utils.GetEnvIntOrDefault("PROJECT_TRANSFER_EXPIRY_HOURS", 7*24)) * time.Hour
 
 	providers := []interface{}{
-		auth.NewService(db, redisDb, usersService),
+		authService,
 		usersService,
-		projects.NewService(db),
+		skillsService,
+		directoryService,
+		blocksService,
+		projectsService,
+		activityService,
+		projects.NewTransfersService(db, usersService, blocksService, projectsService, activityService, publisher, transferExpiry),
+		projects.NewSuggestionsService(db, projectsService, directoryService, blocksService, activityService),
+		projects.NewTasksService(db, projectsService, publisher),
+		moderationService,
+		privacyService,
+		feeds.NewService(projectsService, publicUrl),
+		embeds.NewService(projectsService, publicUrl),
+		profiles.NewService(usersService, skillsService, blocksService, projectsService),
+		sitemap.NewService(
+			projectsService,
+			redisDb,
+			publicUrl,
+			time.Duration(utils.GetEnvIntOrDefault("SITEMAP_CACHE_TTL_MINUTES", 60))*time.Minute,
+		),
+		webhooks.NewService(db, projectsService),
 	}
 
+	// Send webhook deliveries in the background
+	dispatcher := webhooks.NewDispatcher(
+		db,
+		webhooks.NewClient(time.Duration(utils.GetEnvIntOrDefault("WEBHOOK_TIMEOUT_SECONDS", 10))*time.Second),
+		webhooks.RetryPolicy{
+			MaxAttempts: utils.GetEnvIntOrDefault("WEBHOOK_MAX_ATTEMPTS", 8),
+			BaseDelay:   time.Duration(utils.GetEnvIntOrDefault("WEBHOOK_RETRY_BASE_SECONDS", 30)) * time.Second,
+			MaxDelay:    time.Duration(utils.GetEnvIntOrDefault("WEBHOOK_RETRY_MAX_SECONDS", 6*60*60)) * time.Second,
+		},
+		5*time.Second,
+	)
+	go dispatcher.Run(context.Background())
+
+	// Delete accounts once their cooling-off period is over
+	go privacy.NewDeleter(privacyService, time.Hour).Run(context.Background())
+
 	router := router2.SetupRoutes(providers[:])
 
-	host := utils.GetEnvOrPanic("HOST")
-	port := utils.GetEnvOrPanic("PORT")
 	server := &http.Server{
 		Addr:    fmt.Sprintf("%s:%s", host, port),
 		Handler: router,
@@ -1,9 +1,13 @@
 package migrations
 
 import (
+	"fmt"
 	"github.com/go-gormigrate/gormigrate/v2"
 	"github.com/lib/pq"
+	"github.com/open-collaboration/server/utils"
 	"gorm.io/gorm"
+	"strings"
+	"time"
 )
 
 var usersTable = gormigrate.Migration{
@@ -44,9 +48,896 @@ var projectsTable = gormigrate.Migration{
 	},
 }
 
+var moderation = gormigrate.Migration{
+	ID: "2",
+	Migrate: func(db *gorm.DB) error {
+		type User struct {
+			Role        string `gorm:"type: VARCHAR(16); not null; default: 'user'"`
+			SuspendedAt *time.Time
+			HiddenAt    *time.Time
+		}
+
+		type Project struct {
+			OwnerId  uint `gorm:"not null; default: 0; index"`
+			HiddenAt *time.Time
+		}
+
+		type ModerationCase struct {
+			gorm.Model
+
+			TargetType  string `gorm:"type: VARCHAR(16); not null"`
+			TargetId    uint   `gorm:"not null"`
+			Status      string `gorm:"type: VARCHAR(16); not null"`
+			ReportCount int    `gorm:"not null; default: 0"`
+			AutoHidden  bool   `gorm:"not null; default: false"`
+			AssigneeId  *uint
+			Resolution  string `gorm:"type: VARCHAR(1000)"`
+			ResolvedBy  *uint
+			ResolvedAt  *time.Time
+		}
+
+		type Report struct {
+			gorm.Model
+
+			CaseId     uint   `gorm:"not null; index"`
+			ReporterId uint   `gorm:"not null"`
+			Reason     string `gorm:"type: VARCHAR(16); not null"`
+			Note       string `gorm:"type: VARCHAR(1000)"`
+		}
+
+		type ModerationAction struct {
+			gorm.Model
+
+			CaseId       uint   `gorm:"not null; index"`
+			ModeratorId  uint   `gorm:"not null"`
+			Action       string `gorm:"type: VARCHAR(16); not null"`
+			TargetUserId uint   `gorm:"not null; index"`
+			Note         string `gorm:"type: VARCHAR(1000)"`
+		}
+
+		err := db.AutoMigrate(&User{}, &Project{}, &ModerationCase{}, &Report{}, &ModerationAction{})
+		if err != nil {
+			return err
+		}
+
+		// Only one case per target can be under review at a time, reports
+		// for a target are aggregated into it.
+		err = db.Exec(`
+			CREATE UNIQUE INDEX idx_moderation_cases_active_target
+			ON moderation_cases (target_type, target_id)
+			WHERE status IN ('open', 'triaged') AND deleted_at IS NULL
+		`).Error
+		if err != nil {
+			return err
+		}
+
+		// A user can only report a case once
+		return db.Exec(`
+			CREATE UNIQUE INDEX idx_reports_case_reporter
+			ON reports (case_id, reporter_id)
+			WHERE deleted_at IS NULL
+		`).Error
+	},
+	Rollback: func(db *gorm.DB) error {
+		err := db.Migrator().DropTable("moderation_actions", "reports", "moderation_cases")
+		if err != nil {
+			return err
+		}
+
+		err = db.Exec("ALTER TABLE projects DROP COLUMN owner_id, DROP COLUMN hidden_at").Error
+		if err != nil {
+			return err
+		}
+
+		return db.Exec("ALTER TABLE users DROP COLUMN role, DROP COLUMN suspended_at, DROP COLUMN hidden_at").Error
+	},
+}
+
+var projectOwnership = gormigrate.Migration{
+	ID: "3",
+	Migrate: func(db *gorm.DB) error {
+		type ProjectMember struct {
+			gorm.Model
+
+			ProjectId uint   `gorm:"not null"`
+			UserId    uint   `gorm:"not null; index"`
+			Role      string `gorm:"type: VARCHAR(16); not null"`
+		}
+
+		type ProjectTransfer struct {
+			gorm.Model
+
+			ProjectId  uint      `gorm:"not null; index"`
+			FromUserId uint      `gorm:"not null"`
+			ToUserId   uint      `gorm:"not null; index"`
+			Status     string    `gorm:"type: VARCHAR(16); not null"`
+			ExpiresAt  time.Time `gorm:"not null"`
+		}
+
+		type Activity struct {
+			gorm.Model
+
+			UserId        uint   `gorm:"not null; index"`
+			Kind          string `gorm:"type: VARCHAR(64); not null"`
+			ProjectId     uint   `gorm:"not null; default: 0"`
+			RelatedUserId uint   `gorm:"not null; default: 0"`
+		}
+
+		err := db.AutoMigrate(&ProjectMember{}, &ProjectTransfer{}, &Activity{})
+		if err != nil {
+			return err
+		}
+
+		err = db.Exec(`
+			CREATE UNIQUE INDEX idx_project_members_project_user
+			ON project_members (project_id, user_id)
+			WHERE deleted_at IS NULL
+		`).Error
+		if err != nil {
+			return err
+		}
+
+		// A user can have at most one pending outgoing transfer per project
+		err = db.Exec(`
+			CREATE UNIQUE INDEX idx_project_transfers_pending
+			ON project_transfers (project_id, from_user_id)
+			WHERE status = 'pending' AND deleted_at IS NULL
+		`).Error
+		if err != nil {
+			return err
+		}
+
+		// Owners of existing projects become members of their projects
+		return db.Exec(`
+			INSERT INTO project_members (created_at, updated_at, project_id, user_id, role)
+			SELECT now(), now(), id, owner_id, 'owner'
+			FROM projects
+			WHERE owner_id <> 0 AND deleted_at IS NULL
+		`).Error
+	},
+	Rollback: func(db *gorm.DB) error {
+		return db.Migrator().DropTable("activities", "project_transfers", "project_members")
+	},
+}
+
+var projectQuotas = gormigrate.Migration{
+	ID: "4",
+	Migrate: func(db *gorm.DB) error {
+		type ProjectRole struct {
+			gorm.Model
+
+			ProjectId   uint           `gorm:"not null; index"`
+			Title       string         `gorm:"type: VARCHAR(64); not null"`
+			Description string         `gorm:"type: VARCHAR(2000)"`
+			Skills      pq.StringArray `gorm:"type: TEXT[]"`
+			ClosedAt    *time.Time
+		}
+
+		type ProjectQuotaOverride struct {
+			gorm.Model
+
+			UserId                uint `gorm:"not null; uniqueIndex"`
+			MaxOwnedProjects      *int
+			MaxRecruitingProjects *int
+			MaxProjectsPerDay     *int
+		}
+
+		return db.AutoMigrate(&ProjectRole{}, &ProjectQuotaOverride{})
+	},
+	Rollback: func(db *gorm.DB) error {
+		return db.Migrator().DropTable("project_quota_overrides", "project_roles")
+	},
+}
+
+var projectSlugs = gormigrate.Migration{
+	ID: "5",
+	Migrate: func(db *gorm.DB) error {
+		type Project struct {
+			Slug string `gorm:"type: VARCHAR(64)"`
+		}
+
+		type ProjectSlugRedirect struct {
+			gorm.Model
+
+			Slug      string `gorm:"type: VARCHAR(64); not null"`
+			ProjectId uint   `gorm:"not null; index"`
+		}
+
+		err := db.AutoMigrate(&Project{}, &ProjectSlugRedirect{})
+		if err != nil {
+			return err
+		}
+
+		// Give existing projects a slug, oldest projects get the
+		// slugs without a suffix.
+		var projects []struct {
+			ID   uint
+			Name string
+		}
+		err = db.Table("projects").Select("id", "name").Order("id asc").Find(&projects).Error
+		if err != nil {
+			return err
+		}
+
+		taken := map[string]bool{}
+		for _, project := range projects {
+			base := utils.Slugify(project.Name)
+			if base == "" {
+				base = "project"
+			}
+
+			slug := base
+			for suffix := 2; taken[slug]; suffix++ {
+				slug = fmt.Sprintf("%s-%d", base, suffix)
+			}
+			taken[slug] = true
+
+			err = db.Exec("UPDATE projects SET slug = ? WHERE id = ?", slug, project.ID).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		err = db.Exec(`
+			CREATE UNIQUE INDEX idx_projects_slug
+			ON projects (slug)
+			WHERE deleted_at IS NULL
+		`).Error
+		if err != nil {
+			return err
+		}
+
+		return db.Exec(`
+			CREATE UNIQUE INDEX idx_project_slug_redirects_slug
+			ON project_slug_redirects (slug)
+			WHERE deleted_at IS NULL
+		`).Error
+	},
+	Rollback: func(db *gorm.DB) error {
+		err := db.Migrator().DropTable("project_slug_redirects")
+		if err != nil {
+			return err
+		}
+
+		return db.Exec("ALTER TABLE projects DROP COLUMN slug").Error
+	},
+}
+
+var projectDuplicates = gormigrate.Migration{
+	ID: "6",
+	Migrate: func(db *gorm.DB) error {
+		type Project struct {
+			GithubLinkNormalized string `gorm:"index"`
+		}
+
+		err := db.AutoMigrate(&Project{})
+		if err != nil {
+			return err
+		}
+
+		var projects []struct {
+			ID         uint
+			GithubLink string
+		}
+		err = db.Table("projects").Select("id", "github_link").Find(&projects).Error
+		if err != nil {
+			return err
+		}
+
+		for _, project := range projects {
+			err = db.Exec(
+				"UPDATE projects SET github_link_normalized = ? WHERE id = ?",
+				utils.NormalizeRepoLink(project.GithubLink),
+				project.ID,
+			).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		// Trigram indexes used to find projects with similar
+		// names and descriptions.
+		err = db.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm").Error
+		if err != nil {
+			return err
+		}
+
+		err = db.Exec("CREATE INDEX idx_projects_name_trgm ON projects USING GIN (name gin_trgm_ops)").Error
+		if err != nil {
+			return err
+		}
+
+		return db.Exec(`
+			CREATE INDEX idx_projects_short_description_trgm
+			ON projects USING GIN (short_description gin_trgm_ops)
+		`).Error
+	},
+	Rollback: func(db *gorm.DB) error {
+		err := db.Exec("DROP INDEX idx_projects_short_description_trgm, idx_projects_name_trgm").Error
+		if err != nil {
+			return err
+		}
+
+		return db.Exec("ALTER TABLE projects DROP COLUMN github_link_normalized").Error
+	},
+}
+
+var projectTasks = gormigrate.Migration{
+	ID: "7",
+	Migrate: func(db *gorm.DB) error {
+		type ProjectMilestone struct {
+			gorm.Model
+
+			ProjectId   uint   `gorm:"not null; index"`
+			Title       string `gorm:"type: VARCHAR(100); not null"`
+			Description string `gorm:"type: VARCHAR(2000)"`
+			DueAt       *time.Time
+			ClosedAt    *time.Time
+		}
+
+		type ProjectTask struct {
+			gorm.Model
+
+			ProjectId   uint   `gorm:"not null; index:idx_project_tasks_board"`
+			MilestoneId *uint  `gorm:"index"`
+			Title       string `gorm:"type: VARCHAR(200); not null"`
+			Description string `gorm:"type: VARCHAR(5000)"`
+			Status      string `gorm:"type: VARCHAR(16); not null; index:idx_project_tasks_board"`
+			AssigneeId  *uint
+			Labels      pq.StringArray `gorm:"type: TEXT[]"`
+			DueAt       *time.Time
+			Position    int `gorm:"not null; default: 0; index:idx_project_tasks_board"`
+		}
+
+		return db.AutoMigrate(&ProjectMilestone{}, &ProjectTask{})
+	},
+	Rollback: func(db *gorm.DB) error {
+		return db.Migrator().DropTable("project_tasks", "project_milestones")
+	},
+}
+
+var projectWebhooks = gormigrate.Migration{
+	ID: "8",
+	Migrate: func(db *gorm.DB) error {
+		type ProjectWebhook struct {
+			gorm.Model
+
+			ProjectId  uint           `gorm:"not null; index"`
+			Url        string         `gorm:"type: VARCHAR(2000); not null"`
+			Secret     string         `gorm:"type: VARCHAR(64); not null"`
+			EventTypes pq.StringArray `gorm:"type: TEXT[]"`
+			Active     bool           `gorm:"not null; default: true"`
+		}
+
+		type WebhookDelivery struct {
+			gorm.Model
+
+			WebhookId     uint      `gorm:"not null; index"`
+			EventId       string    `gorm:"type: VARCHAR(32); not null"`
+			EventType     string    `gorm:"type: VARCHAR(32); not null"`
+			Payload       string    `gorm:"not null"`
+			Status        string    `gorm:"type: VARCHAR(16); not null; index:idx_webhook_deliveries_due"`
+			Attempts      int       `gorm:"not null; default: 0"`
+			NextAttemptAt time.Time `gorm:"not null; index:idx_webhook_deliveries_due"`
+			RedeliveryOf  *uint
+		}
+
+		type WebhookDeliveryAttempt struct {
+			gorm.Model
+
+			DeliveryId   uint `gorm:"not null; index"`
+			StatusCode   int  `gorm:"not null; default: 0"`
+			Error        string
+			ResponseBody string `gorm:"type: VARCHAR(1024)"`
+			DurationMs   int64  `gorm:"not null; default: 0"`
+		}
+
+		return db.AutoMigrate(&ProjectWebhook{}, &WebhookDelivery{}, &WebhookDeliveryAttempt{})
+	},
+	Rollback: func(db *gorm.DB) error {
+		return db.Migrator().DropTable("webhook_delivery_attempts", "webhook_deliveries", "project_webhooks")
+	},
+}
+
+var projectVisibility = gormigrate.Migration{
+	ID: "9",
+	Migrate: func(db *gorm.DB) error {
+		return db.Exec("ALTER TABLE projects ADD COLUMN visibility VARCHAR(16) NOT NULL DEFAULT 'public'").Error
+	},
+	Rollback: func(db *gorm.DB) error {
+		return db.Exec("ALTER TABLE projects DROP COLUMN visibility").Error
+	},
+}
+
+var projectTranslations = gormigrate.Migration{
+	ID: "10",
+	Migrate: func(db *gorm.DB) error {
+		type ProjectTranslation struct {
+			gorm.Model
+
+			ProjectId        uint   `gorm:"not null; index"`
+			Locale           string `gorm:"type: VARCHAR(35); not null"`
+			Name             string `gorm:"type: VARCHAR(32); not null"`
+			ShortDescription string `gorm:"type: VARCHAR(200); not null"`
+			LongDescription  string `gorm:"type: VARCHAR(10000); not null"`
+		}
+
+		err := db.Exec("ALTER TABLE projects ADD COLUMN default_locale VARCHAR(35) NOT NULL DEFAULT 'en'").Error
+		if err != nil {
+			return err
+		}
+
+		err = db.AutoMigrate(&ProjectTranslation{})
+		if err != nil {
+			return err
+		}
+
+		return db.Exec(`
+			CREATE UNIQUE INDEX idx_project_translations_locale
+			ON project_translations (project_id, locale)
+			WHERE deleted_at IS NULL
+		`).Error
+	},
+	Rollback: func(db *gorm.DB) error {
+		err := db.Migrator().DropTable("project_translations")
+		if err != nil {
+			return err
+		}
+
+		return db.Exec("ALTER TABLE projects DROP COLUMN default_locale").Error
+	},
+}
+
+var userProfiles = gormigrate.Migration{
+	ID: "11",
+	Migrate: func(db *gorm.DB) error {
+		type User struct {
+			DisplayName  string `gorm:"type: VARCHAR(64); not null; default: ''"`
+			Bio          string `gorm:"type: VARCHAR(2000); not null; default: ''"`
+			Location     string `gorm:"type: VARCHAR(100); not null; default: ''"`
+			Website      string `gorm:"type: VARCHAR(255); not null; default: ''"`
+			GithubHandle string `gorm:"type: VARCHAR(39); not null; default: ''"`
+			GitlabHandle string `gorm:"type: VARCHAR(255); not null; default: ''"`
+			Timezone     string `gorm:"type: VARCHAR(64); not null; default: ''"`
+		}
+
+		return db.AutoMigrate(&User{})
+	},
+	Rollback: func(db *gorm.DB) error {
+		for _, column := range []string{
+			"display_name",
+			"bio",
+			"location",
+			"website",
+			"github_handle",
+			"gitlab_handle",
+			"timezone",
+		} {
+			err := db.Exec("ALTER TABLE users DROP COLUMN " + column).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	},
+}
+
+var userSkills = gormigrate.Migration{
+	ID: "12",
+	Migrate: func(db *gorm.DB) error {
+		type UserSkill struct {
+			gorm.Model
+
+			UserId            uint   `gorm:"not null; index"`
+			Name              string `gorm:"type: VARCHAR(40); not null; index"`
+			Proficiency       string `gorm:"type: VARCHAR(16); not null"`
+			YearsOfExperience int    `gorm:"not null; default: 0"`
+		}
+
+		type SkillEndorsement struct {
+			gorm.Model
+
+			SkillId    uint `gorm:"not null; index"`
+			EndorserId uint `gorm:"not null"`
+		}
+
+		err := db.AutoMigrate(&UserSkill{}, &SkillEndorsement{})
+		if err != nil {
+			return err
+		}
+
+		err = db.Exec(`
+			CREATE UNIQUE INDEX idx_user_skills_name
+			ON user_skills (user_id, name)
+			WHERE deleted_at IS NULL
+		`).Error
+		if err != nil {
+			return err
+		}
+
+		return db.Exec(`
+			CREATE UNIQUE INDEX idx_skill_endorsements_endorser
+			ON skill_endorsements (skill_id, endorser_id)
+			WHERE deleted_at IS NULL
+		`).Error
+	},
+	Rollback: func(db *gorm.DB) error {
+		return db.Migrator().DropTable("skill_endorsements", "user_skills")
+	},
+}
+
+var emailVerification = gormigrate.Migration{
+	ID: "13",
+	Migrate: func(db *gorm.DB) error {
+		type User struct {
+			EmailVerifiedAt    *time.Time
+			VerificationSentAt *time.Time
+		}
+
+		err := db.AutoMigrate(&User{})
+		if err != nil {
+			return err
+		}
+
+		// Users who registered before emails were verified keep their access
+		return db.Exec("UPDATE users SET email_verified_at = created_at").Error
+	},
+	Rollback: func(db *gorm.DB) error {
+		err := db.Exec("ALTER TABLE users DROP COLUMN email_verified_at").Error
+		if err != nil {
+			return err
+		}
+
+		return db.Exec("ALTER TABLE users DROP COLUMN verification_sent_at").Error
+	},
+}
+
+var securityEvents = gormigrate.Migration{
+	ID: "14",
+	Migrate: func(db *gorm.DB) error {
+		type User struct {
+			PendingEmail string `gorm:"not null; default: ''"`
+		}
+
+		type SecurityEvent struct {
+			gorm.Model
+
+			UserId    uint   `gorm:"not null; index"`
+			Kind      string `gorm:"type: VARCHAR(32); not null"`
+			Ip        string `gorm:"type: VARCHAR(45); not null; default: ''"`
+			UserAgent string `gorm:"not null; default: ''"`
+			OldEmail  string `gorm:"not null; default: ''"`
+			NewEmail  string `gorm:"not null; default: ''"`
+		}
+
+		return db.AutoMigrate(&User{}, &SecurityEvent{})
+	},
+	Rollback: func(db *gorm.DB) error {
+		err := db.Migrator().DropTable("security_events")
+		if err != nil {
+			return err
+		}
+
+		return db.Exec("ALTER TABLE users DROP COLUMN pending_email").Error
+	},
+}
+
+var accountDeletion = gormigrate.Migration{
+	ID: "15",
+	Migrate: func(db *gorm.DB) error {
+		type User struct {
+			DeletionScheduledAt *time.Time `gorm:"index"`
+		}
+
+		return db.AutoMigrate(&User{})
+	},
+	Rollback: func(db *gorm.DB) error {
+		return db.Exec("ALTER TABLE users DROP COLUMN deletion_scheduled_at").Error
+	},
+}
+
+var uniqueUsers = gormigrate.Migration{
+	ID: "16",
+	Migrate: func(db *gorm.DB) error {
+		// Duplicates can't be merged automatically, they have to be fixed
+		// by hand before the indexes can be created.
+		for _, column := range []string{"username", "email"} {
+			var duplicates []string
+			err := db.
+				Table("users").
+				Where("deleted_at IS NULL").
+				Group("LOWER("+column+")").
+				Having("COUNT(*) > 1").
+				Pluck("LOWER("+column+")", &duplicates).
+				Error
+			if err != nil {
+				return err
+			}
+
+			if len(duplicates) > 0 {
+				return fmt.Errorf("users share the same %s, ignoring case: %s", column, strings.Join(duplicates, ", "))
+			}
+		}
+
+		err := db.Exec(`
+			CREATE UNIQUE INDEX idx_users_username_lower
+			ON users (LOWER(username))
+			WHERE deleted_at IS NULL
+		`).Error
+		if err != nil {
+			return err
+		}
+
+		return db.Exec(`
+			CREATE UNIQUE INDEX idx_users_email_lower
+			ON users (LOWER(email))
+			WHERE deleted_at IS NULL
+		`).Error
+	},
+	Rollback: func(db *gorm.DB) error {
+		err := db.Exec("DROP INDEX idx_users_username_lower").Error
+		if err != nil {
+			return err
+		}
+
+		return db.Exec("DROP INDEX idx_users_email_lower").Error
+	},
+}
+
+var contributorDirectory = gormigrate.Migration{
+	ID: "17",
+	Migrate: func(db *gorm.DB) error {
+		type User struct {
+			DirectoryListedAt *time.Time
+			Availability      string         `gorm:"type: VARCHAR(8); not null; default: 'busy'"`
+			WeeklyHours       int            `gorm:"not null; default: 0"`
+			PublicFields      pq.StringArray `gorm:"type: TEXT[]"`
+		}
+
+		type RoleSuggestion struct {
+			gorm.Model
+
+			ProjectId  uint   `gorm:"not null; index"`
+			RoleId     uint   `gorm:"not null"`
+			FromUserId uint   `gorm:"not null; index"`
+			ToUserId   uint   `gorm:"not null; index"`
+			Message    string `gorm:"not null; default: ''"`
+			Status     string `gorm:"type: VARCHAR(16); not null"`
+		}
+
+		err := db.AutoMigrate(&User{}, &RoleSuggestion{})
+		if err != nil {
+			return err
+		}
+
+		// Matches the order in which the directory is listed
+		return db.Exec(`
+			CREATE INDEX idx_users_directory
+			ON users (directory_listed_at DESC, id DESC)
+			WHERE directory_listed_at IS NOT NULL
+		`).Error
+	},
+	Rollback: func(db *gorm.DB) error {
+		err := db.Migrator().DropTable("role_suggestions")
+		if err != nil {
+			return err
+		}
+
+		return db.Exec(`
+			ALTER TABLE users
+			DROP COLUMN directory_listed_at,
+			DROP COLUMN availability,
+			DROP COLUMN weekly_hours,
+			DROP COLUMN public_fields
+		`).Error
+	},
+}
+
+var workingHours = gormigrate.Migration{
+	ID: "18",
+	Migrate: func(db *gorm.DB) error {
+		type User struct {
+			WorkStart *int
+			WorkEnd   *int
+		}
+
+		type Project struct {
+			WeeklyHours     int    `gorm:"not null; default: 0"`
+			Timezone        string `gorm:"not null; default: ''"`
+			WorkStart       *int
+			WorkEnd         *int
+			MinOverlapHours int `gorm:"not null; default: 0"`
+		}
+
+		err := db.AutoMigrate(&User{}, &Project{})
+		if err != nil {
+			return err
+		}
+
+		// Minutes of overlap between the working hours of A on a day and
+		// the working hours of B on that day and the days around it. Times
+		// are in minutes after local midnight and windows that end before
+		// they start cross midnight. Local times are converted with the
+		// offsets the time zones have on each day, so daylight saving time
+		// changes are taken into account.
+		return db.Exec(`
+			CREATE FUNCTION working_hours_overlap(
+				timezone_a TEXT, start_a INT, end_a INT,
+				timezone_b TEXT, start_b INT, end_b INT,
+				on_day DATE
+			) RETURNS INT AS $$
+				SELECT (COALESCE(SUM(GREATEST(0, EXTRACT(EPOCH FROM
+					LEAST(a.ends_at, b.ends_at) - GREATEST(a.starts_at, b.starts_at)
+				))), 0) / 60)::INT
+				FROM (
+					SELECT
+						(on_day + make_interval(mins => start_a)) AT TIME ZONE timezone_a AS starts_at,
+						(on_day + make_interval(mins => CASE WHEN end_a > start_a THEN end_a ELSE end_a + 1440 END))
+							AT TIME ZONE timezone_a AS ends_at
+				) AS a
+				CROSS JOIN (
+					SELECT
+						(b_day + make_interval(mins => start_b)) AT TIME ZONE timezone_b AS starts_at,
+						(b_day + make_interval(mins => CASE WHEN end_b > start_b THEN end_b ELSE end_b + 1440 END))
+							AT TIME ZONE timezone_b AS ends_at
+					FROM unnest(ARRAY[on_day - 1, on_day, on_day + 1]) AS b_day
+				) AS b
+			$$ LANGUAGE SQL STABLE STRICT
+		`).Error
+	},
+	Rollback: func(db *gorm.DB) error {
+		err := db.Exec("DROP FUNCTION working_hours_overlap").Error
+		if err != nil {
+			return err
+		}
+
+		err = db.Exec(`
+			ALTER TABLE projects
+			DROP COLUMN weekly_hours,
+			DROP COLUMN timezone,
+			DROP COLUMN work_start,
+			DROP COLUMN work_end,
+			DROP COLUMN min_overlap_hours
+		`).Error
+		if err != nil {
+			return err
+		}
+
+		return db.Exec("ALTER TABLE users DROP COLUMN work_start, DROP COLUMN work_end").Error
+	},
+}
+
+var userBlocks = gormigrate.Migration{
+	ID: "19",
+	Migrate: func(db *gorm.DB) error {
+		type UserBlock struct {
+			gorm.Model
+
+			UserId        uint   `gorm:"not null; index"`
+			BlockedUserId uint   `gorm:"not null; index"`
+			Kind          string `gorm:"type: VARCHAR(8); not null"`
+		}
+
+		err := db.AutoMigrate(&UserBlock{})
+		if err != nil {
+			return err
+		}
+
+		// Users have at most one block per other user
+		return db.Exec(`
+			CREATE UNIQUE INDEX idx_user_blocks_unique
+			ON user_blocks (user_id, blocked_user_id)
+			WHERE deleted_at IS NULL
+		`).Error
+	},
+	Rollback: func(db *gorm.DB) error {
+		return db.Migrator().DropTable("user_blocks")
+	},
+}
+
+var deletionAttempts = gormigrate.Migration{
+	ID: "20",
+	Migrate: func(db *gorm.DB) error {
+		type User struct {
+			DeletionAttemptedAt *time.Time
+		}
+
+		return db.AutoMigrate(&User{})
+	},
+	Rollback: func(db *gorm.DB) error {
+		return db.Exec("ALTER TABLE users DROP COLUMN deletion_attempted_at").Error
+	},
+}
+
+var publicProfileFields = gormigrate.Migration{
+	ID: "21",
+	Migrate: func(db *gorm.DB) error {
+		// Public fields now apply to profiles too, which were fully public
+		// until now. Users who never chose keep them that way.
+		return db.Exec(`
+			UPDATE users
+			SET public_fields = ARRAY['displayName', 'bio', 'location', 'website', 'githubHandle', 'gitlabHandle', 'timezone', 'skills']
+			WHERE public_fields IS NULL
+		`).Error
+	},
+	Rollback: func(db *gorm.DB) error {
+		// Backfilled fields can't be told apart from chosen ones
+		return nil
+	},
+}
+
+var moderationPendingActions = gormigrate.Migration{
+	ID: "22",
+	Migrate: func(db *gorm.DB) error {
+		type ModerationCase struct {
+			ActionsPending bool `gorm:"not null; default: false"`
+		}
+
+		return db.AutoMigrate(&ModerationCase{})
+	},
+	Rollback: func(db *gorm.DB) error {
+		return db.Exec("ALTER TABLE moderation_cases DROP COLUMN actions_pending").Error
+	},
+}
+
+var projectCreators = gormigrate.Migration{
+	ID: "23",
+	Migrate: func(db *gorm.DB) error {
+		type Project struct {
+			CreatedBy uint `gorm:"not null; default: 0; index"`
+		}
+
+		err := db.AutoMigrate(&Project{})
+		if err != nil {
+			return err
+		}
+
+		// Projects that were transferred were created by the sender of their
+		// first accepted transfer, the others by their current owner.
+		return db.Exec(`
+			UPDATE projects
+			SET created_by = COALESCE((
+				SELECT from_user_id
+				FROM project_transfers
+				WHERE project_transfers.project_id = projects.id
+					AND project_transfers.status = 'accepted'
+					AND project_transfers.deleted_at IS NULL
+				ORDER BY project_transfers.created_at ASC
+				LIMIT 1
+			), owner_id)
+		`).Error
+	},
+	Rollback: func(db *gorm.DB) error {
+		return db.Exec("ALTER TABLE projects DROP COLUMN created_by").Error
+	},
+}
+
 func GetMigration(db *gorm.DB) *gormigrate.Gormigrate {
 	return gormigrate.New(db, gormigrate.DefaultOptions, []*gormigrate.Migration{
 		&usersTable,
 		&projectsTable,
+		&moderation,
+		&projectOwnership,
+		&projectQuotas,
+		&projectSlugs,
+		&projectDuplicates,
+		&projectTasks,
+		&projectWebhooks,
+		&projectVisibility,
+		&projectTranslations,
+		&userProfiles,
+		&userSkills,
+		&emailVerification,
+		&securityEvents,
+		&accountDeletion,
+		&uniqueUsers,
+		&contributorDirectory,
+		&workingHours,
+		&userBlocks,
+		&deletionAttempts,
+		&publicProfileFields,
+		&moderationPendingActions,
+		&projectCreators,
 	})
 }
@@ -0,0 +1,73 @@
+package moderation
+
+import "time"
+
+type NewReportDto struct {
+	TargetType string `json:"targetType" validate:"required,oneof=project user"`
+	TargetId   uint   `json:"targetId" validate:"required"`
+	Reason     string `json:"reason" validate:"required,oneof=spam abuse inappropriate misleading other"`
+	Note       string `json:"note" validate:"max=1000"`
+}
+
+type CaseSummaryDto struct {
+	Id          uint      `json:"id"`
+	TargetType  string    `json:"targetType"`
+	TargetId    uint      `json:"targetId"`
+	Status      string    `json:"status"`
+	ReportCount int       `json:"reportCount"`
+	AutoHidden  bool      `json:"autoHidden"`
+	AssigneeId  *uint     `json:"assigneeId"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+
+	// Whether the case was closed but applying its actions failed. Closing
+	// it again applies them.
+	ActionsPending bool `json:"actionsPending"`
+
+	// Amount of reports per reason, e.g. {"spam": 3, "abuse": 1}
+	Reasons map[string]int `json:"reasons"`
+}
+
+type ReportDto struct {
+	Id         uint      `json:"id"`
+	ReporterId uint      `json:"reporterId"`
+	Reason     string    `json:"reason"`
+	Note       string    `json:"note"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type ActionDto struct {
+	Id           uint      `json:"id"`
+	ModeratorId  uint      `json:"moderatorId"`
+	Action       string    `json:"action"`
+	TargetUserId uint      `json:"targetUserId"`
+	Note         string    `json:"note"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+type CaseDto struct {
+	CaseSummaryDto
+
+	Resolution string      `json:"resolution"`
+	ResolvedBy *uint       `json:"resolvedBy"`
+	ResolvedAt *time.Time  `json:"resolvedAt"`
+	Reports    []ReportDto `json:"reports"`
+	Actions    []ActionDto `json:"actions"`
+}
+
+type ResolveCaseDto struct {
+	// Actions to take on the target. Any combination of "hide", "warn"
+	// and "suspend" is allowed, including none.
+	Actions []string `json:"actions" validate:"max=3,dive,oneof=hide warn suspend"`
+	Note    string   `json:"note" validate:"max=1000"`
+}
+
+type DismissCaseDto struct {
+	Note string `json:"note" validate:"max=1000"`
+}
+
+type WarningDto struct {
+	Id        uint      `json:"id"`
+	Note      string    `json:"note"`
+	CreatedAt time.Time `json:"createdAt"`
+}
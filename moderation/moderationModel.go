@@ -0,0 +1,86 @@
+package moderation
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	TargetProject = "project"
+	TargetUser    = "user"
+)
+
+const (
+	ReasonSpam          = "spam"
+	ReasonAbuse         = "abuse"
+	ReasonInappropriate = "inappropriate"
+	ReasonMisleading    = "misleading"
+	ReasonOther         = "other"
+)
+
+const (
+	// The case has reports that haven't been looked at yet.
+	CaseStatusOpen = "open"
+	// A moderator has picked up the case.
+	CaseStatusTriaged   = "triaged"
+	CaseStatusResolved  = "resolved"
+	CaseStatusDismissed = "dismissed"
+)
+
+const (
+	ActionHide    = "hide"
+	ActionUnhide  = "unhide"
+	ActionWarn    = "warn"
+	ActionSuspend = "suspend"
+)
+
+// Cases that still need a moderator's decision.
+var activeCaseStatuses = []string{CaseStatusOpen, CaseStatusTriaged}
+
+// A moderation case aggregates all reports of a target. There can only
+// be one active (open or triaged) case per target at a time. Once a case
+// is resolved or dismissed, new reports of the same target open a new case.
+type ModerationCase struct {
+	gorm.Model
+
+	TargetType  string
+	TargetId    uint
+	Status      string
+	ReportCount int
+
+	// Whether the target was automatically hidden because it
+	// reached the report threshold.
+	AutoHidden bool
+
+	AssigneeId *uint
+	Resolution string
+	ResolvedBy *uint
+	ResolvedAt *time.Time
+
+	// Whether the case was closed but its actions' side effects (hiding,
+	// suspending, etc) haven't all been applied yet.
+	ActionsPending bool
+}
+
+type Report struct {
+	gorm.Model
+
+	CaseId     uint
+	ReporterId uint
+	Reason     string
+	Note       string
+}
+
+// An action taken by a moderator while resolving a case.
+type ModerationAction struct {
+	gorm.Model
+
+	CaseId      uint
+	ModeratorId uint
+	Action      string
+
+	// The user affected by the action, e.g. the owner of a hidden project
+	// or the user that got warned.
+	TargetUserId uint
+	Note         string
+}
@@ -0,0 +1,285 @@
+package moderation
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/open-collaboration/server/auth"
+	"github.com/open-collaboration/server/utils"
+	"net/http"
+)
+
+type listCasesParams struct {
+	Status string `validate:"omitempty,oneof=open triaged resolved dismissed"`
+}
+
+// @Summary Report a project or a user
+// @Tags moderation
+// @Router /reports [post]
+// @Param report body dtos.NewReportDto true "The report"
+// @Success 201
+func RouteCreateReport(
+	writer http.ResponseWriter,
+	request *http.Request,
+	moderationService Service,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	dto := NewReportDto{}
+	err = utils.ReadJson(request.Context(), request, &dto)
+	if err != nil {
+		return err
+	}
+
+	err = moderationService.CreateReport(request.Context(), session.UserId(), dto)
+	if err != nil {
+		return err
+	}
+
+	writer.WriteHeader(http.StatusCreated)
+
+	return nil
+}
+
+// @Summary List the moderation queue
+// @Tags moderation
+// @Router /moderation/cases [get]
+// @Param status query string false "Only list cases with this status (open, triaged, resolved or dismissed). Defaults to open and triaged cases."
+// @Param pageSize query int false "Maximum amount of cases in the response. Default is 20, max is 50."
+// @Param pageOffset query int false "Response page number."
+// @Success 200 {array} dtos.CaseSummaryDto
+func RouteListCases(
+	writer http.ResponseWriter,
+	request *http.Request,
+	moderationService Service,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	err = moderationService.CheckModerator(request.Context(), session.UserId())
+	if err != nil {
+		return err
+	}
+
+	params := listCasesParams{
+		Status: request.URL.Query().Get("status"),
+	}
+	err = validator.New().Struct(params)
+	if err != nil {
+		return err
+	}
+
+	pageSize, _ := utils.IntFromQuery(request, "pageSize", 20)
+	pageOffset, _ := utils.IntFromQuery(request, "pageOffset", 0)
+
+	if pageSize < 1 || pageSize > 50 {
+		pageSize = 20
+	}
+
+	if pageOffset < 1 {
+		pageOffset = 0
+	}
+
+	cases, err := moderationService.ListCases(request.Context(), params.Status, uint(pageSize), uint(pageOffset))
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusOK, cases)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Get a moderation case
+// @Tags moderation
+// @Router /moderation/cases/{caseId} [get]
+// @Param caseId path int true "The case ID"
+// @Success 200 {object} dtos.CaseDto
+func RouteGetCase(
+	writer http.ResponseWriter,
+	request *http.Request,
+	moderationService Service,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	err = moderationService.CheckModerator(request.Context(), session.UserId())
+	if err != nil {
+		return err
+	}
+
+	caseId, err := utils.UintFromVars(request, "caseId")
+	if err != nil {
+		return err
+	}
+
+	dto, err := moderationService.GetCase(request.Context(), caseId)
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusOK, dto)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Assign a moderation case to yourself
+// @Tags moderation
+// @Router /moderation/cases/{caseId}/triage [post]
+// @Param caseId path int true "The case ID"
+// @Success 204
+func RouteTriageCase(
+	writer http.ResponseWriter,
+	request *http.Request,
+	moderationService Service,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	err = moderationService.CheckModerator(request.Context(), session.UserId())
+	if err != nil {
+		return err
+	}
+
+	caseId, err := utils.UintFromVars(request, "caseId")
+	if err != nil {
+		return err
+	}
+
+	err = moderationService.TriageCase(request.Context(), session.UserId(), caseId)
+	if err != nil {
+		return err
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// @Summary Resolve a moderation case
+// @Description If applying the actions fails, the case is resolved with actionsPending set. Resolving it again applies
+// @Description the actions that were recorded.
+// @Tags moderation
+// @Router /moderation/cases/{caseId}/resolve [post]
+// @Param caseId path int true "The case ID"
+// @Param resolution body dtos.ResolveCaseDto true "Actions to take on the reported target"
+// @Success 204
+func RouteResolveCase(
+	writer http.ResponseWriter,
+	request *http.Request,
+	moderationService Service,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	err = moderationService.CheckModerator(request.Context(), session.UserId())
+	if err != nil {
+		return err
+	}
+
+	caseId, err := utils.UintFromVars(request, "caseId")
+	if err != nil {
+		return err
+	}
+
+	dto := ResolveCaseDto{}
+	err = utils.ReadJson(request.Context(), request, &dto)
+	if err != nil {
+		return err
+	}
+
+	err = moderationService.ResolveCase(request.Context(), session.UserId(), caseId, dto)
+	if err != nil {
+		return err
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// @Summary Dismiss a moderation case
+// @Description If showing an automatically hidden target fails, the case is dismissed with actionsPending set.
+// @Description Dismissing it again retries it.
+// @Tags moderation
+// @Router /moderation/cases/{caseId}/dismiss [post]
+// @Param caseId path int true "The case ID"
+// @Param dismissal body dtos.DismissCaseDto true "Why the case was dismissed"
+// @Success 204
+func RouteDismissCase(
+	writer http.ResponseWriter,
+	request *http.Request,
+	moderationService Service,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	err = moderationService.CheckModerator(request.Context(), session.UserId())
+	if err != nil {
+		return err
+	}
+
+	caseId, err := utils.UintFromVars(request, "caseId")
+	if err != nil {
+		return err
+	}
+
+	dto := DismissCaseDto{}
+	err = utils.ReadJson(request.Context(), request, &dto)
+	if err != nil {
+		return err
+	}
+
+	err = moderationService.DismissCase(request.Context(), session.UserId(), caseId, dto)
+	if err != nil {
+		return err
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// @Summary List the warnings you received from moderators
+// @Tags moderation
+// @Router /users/me/warnings [get]
+// @Success 200 {array} dtos.WarningDto
+func RouteListWarnings(
+	writer http.ResponseWriter,
+	request *http.Request,
+	moderationService Service,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	warnings, err := moderationService.ListWarnings(request.Context(), session.UserId())
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusOK, warnings)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,709 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"github.com/apex/log"
+	"github.com/go-playground/validator/v10"
+	"github.com/open-collaboration/server/auth"
+	"github.com/open-collaboration/server/projects"
+	"github.com/open-collaboration/server/users"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+var ErrTargetNotFound = errors.New("report target not found")
+var ErrAlreadyReported = errors.New("target already reported")
+var ErrCaseNotFound = errors.New("moderation case not found")
+var ErrCaseClosed = errors.New("moderation case already closed")
+
+type Service interface {
+	// Report a project or user. All reports of a target are aggregated
+	// into a single active case. When the case reaches the auto hide
+	// threshold, the target is hidden until a moderator reviews the case.
+	// Returns ErrTargetNotFound if the target doesn't exist and ErrAlreadyReported
+	// if the reporter already reported the target in its active case.
+	CreateReport(ctx context.Context, reporterId uint, newReport NewReportDto) error
+
+	// Check whether a user is a moderator.
+	// Returns auth.ErrForbidden if the user is not a moderator.
+	CheckModerator(ctx context.Context, userId uint) error
+
+	// List moderation cases with the given status (or all active cases if
+	// status is empty), most reported first.
+	ListCases(ctx context.Context, status string, pageSize uint, pageOffset uint) ([]CaseSummaryDto, error)
+
+	// Get a case with all of its reports and actions.
+	// Returns ErrCaseNotFound if the case can't be found.
+	GetCase(ctx context.Context, caseId uint) (CaseDto, error)
+
+	// Assign an open case to a moderator.
+	// Returns ErrCaseNotFound if the case can't be found and ErrCaseClosed
+	// if the case was already resolved or dismissed.
+	TriageCase(ctx context.Context, moderatorId uint, caseId uint) error
+
+	// Close a case taking the given actions on the target. If the target was
+	// automatically hidden and the "hide" action isn't taken, the target is
+	// shown again. If applying the actions fails, the case stays resolved
+	// with pending actions and resolving it again applies the actions that
+	// were recorded, ignoring the given ones.
+	// Returns ErrCaseNotFound if the case can't be found and ErrCaseClosed
+	// if the case was already resolved or dismissed and has no pending actions.
+	ResolveCase(ctx context.Context, moderatorId uint, caseId uint, resolution ResolveCaseDto) error
+
+	// Close a case without taking any action. If the target was automatically
+	// hidden, it is shown again. If showing it fails, dismissing the case
+	// again retries it.
+	// Returns ErrCaseNotFound if the case can't be found and ErrCaseClosed
+	// if the case was already resolved or dismissed and has no pending actions.
+	DismissCase(ctx context.Context, moderatorId uint, caseId uint, dismissal DismissCaseDto) error
+
+	// List the warnings moderators gave to a user, newest to oldest.
+	ListWarnings(ctx context.Context, userId uint) ([]WarningDto, error)
+
+	// List the reports a user filed, newest to oldest.
+	ListUserReports(ctx context.Context, reporterId uint) ([]ReportDto, error)
+}
+
+type serviceImpl struct {
+	Db              *gorm.DB
+	UsersService    users.Service
+	ProjectsService projects.Service
+	AuthService     auth.Service
+
+	// Amount of reports after which a target is hidden automatically.
+	AutoHideThreshold int
+}
+
+func NewService(
+	db *gorm.DB,
+	usersService users.Service,
+	projectsService projects.Service,
+	authService auth.Service,
+	autoHideThreshold int,
+) Service {
+	return &serviceImpl{
+		Db:                db,
+		UsersService:      usersService,
+		ProjectsService:   projectsService,
+		AuthService:       authService,
+		AutoHideThreshold: autoHideThreshold,
+	}
+}
+
+func (s *serviceImpl) CreateReport(ctx context.Context, reporterId uint, newReport NewReportDto) error {
+	logger := log.FromContext(ctx).WithFields(log.Fields{
+		"targetType": newReport.TargetType,
+		"targetId":   newReport.TargetId,
+	})
+
+	err := validator.New().Struct(newReport)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.getTargetAuthor(ctx, newReport.TargetType, newReport.TargetId)
+	if err != nil {
+		return err
+	}
+
+	logger.Debug("Reporting target")
+
+	hideTarget := false
+
+	err = s.Db.Transaction(func(tx *gorm.DB) error {
+		modCase := ModerationCase{}
+		result := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("target_type = ? AND target_id = ?", newReport.TargetType, newReport.TargetId).
+			Where("status IN ?", activeCaseStatuses).
+			First(&modCase)
+
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			modCase = ModerationCase{
+				TargetType: newReport.TargetType,
+				TargetId:   newReport.TargetId,
+				Status:     CaseStatusOpen,
+			}
+
+			err := tx.Create(&modCase).Error
+			if err != nil {
+				return err
+			}
+		} else if result.Error != nil {
+			return result.Error
+		}
+
+		var reportCount int64
+		err := tx.
+			Model(&Report{}).
+			Where("case_id = ? AND reporter_id = ?", modCase.ID, reporterId).
+			Count(&reportCount).
+			Error
+		if err != nil {
+			return err
+		}
+
+		if reportCount > 0 {
+			return ErrAlreadyReported
+		}
+
+		err = tx.Create(&Report{
+			CaseId:     modCase.ID,
+			ReporterId: reporterId,
+			Reason:     newReport.Reason,
+			Note:       newReport.Note,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		modCase.ReportCount++
+		if modCase.ReportCount >= s.AutoHideThreshold && !modCase.AutoHidden {
+			modCase.AutoHidden = true
+			hideTarget = true
+		}
+
+		return tx.
+			Model(&modCase).
+			Select("report_count", "auto_hidden").
+			Updates(&modCase).
+			Error
+	})
+	if err != nil {
+		if !errors.Is(err, ErrAlreadyReported) {
+			logger.WithError(err).Error("Failed to report target")
+		}
+
+		return err
+	}
+
+	if hideTarget {
+		logger.Info("Target reached the report threshold, hiding it")
+
+		err = s.setTargetHidden(ctx, newReport.TargetType, newReport.TargetId, true)
+		if err != nil {
+			logger.WithError(err).Error("Failed to hide reported target")
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *serviceImpl) CheckModerator(ctx context.Context, userId uint) error {
+	user, err := s.UsersService.GetUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if !user.IsModerator() {
+		return auth.ErrForbidden
+	}
+
+	return nil
+}
+
+func (s *serviceImpl) ListCases(
+	ctx context.Context,
+	status string,
+	pageSize uint,
+	pageOffset uint,
+) ([]CaseSummaryDto, error) {
+	logger := log.FromContext(ctx)
+
+	statuses := activeCaseStatuses
+	if status != "" {
+		statuses = []string{status}
+	}
+
+	var modCases []ModerationCase
+	result := s.Db.
+		Where("status IN ?", statuses).
+		Order("report_count desc, created_at asc").
+		Limit(int(pageSize)).
+		Offset(int(pageOffset * pageSize)).
+		Find(&modCases)
+	if result.Error != nil {
+		logger.WithError(result.Error).Error("Failed to list moderation cases")
+		return nil, result.Error
+	}
+
+	caseIds := make([]uint, len(modCases))
+	for i, modCase := range modCases {
+		caseIds[i] = modCase.ID
+	}
+
+	reasons, err := s.countReasons(caseIds)
+	if err != nil {
+		logger.WithError(err).Error("Failed to count report reasons")
+		return nil, err
+	}
+
+	summaries := make([]CaseSummaryDto, len(modCases))
+	for i, modCase := range modCases {
+		summaries[i] = caseSummary(modCase, reasons[modCase.ID])
+	}
+
+	return summaries, nil
+}
+
+func (s *serviceImpl) GetCase(ctx context.Context, caseId uint) (CaseDto, error) {
+	logger := log.FromContext(ctx).WithField("caseId", caseId)
+
+	modCase, err := s.findCase(s.Db, caseId)
+	if err != nil {
+		if !errors.Is(err, ErrCaseNotFound) {
+			logger.WithError(err).Error("Failed to query for moderation case")
+		}
+
+		return CaseDto{}, err
+	}
+
+	var reports []Report
+	err = s.Db.Where("case_id = ?", caseId).Order("created_at asc").Find(&reports).Error
+	if err != nil {
+		logger.WithError(err).Error("Failed to query for case reports")
+		return CaseDto{}, err
+	}
+
+	var actions []ModerationAction
+	err = s.Db.Where("case_id = ?", caseId).Order("created_at asc").Find(&actions).Error
+	if err != nil {
+		logger.WithError(err).Error("Failed to query for case actions")
+		return CaseDto{}, err
+	}
+
+	reasons := map[string]int{}
+	reportDtos := make([]ReportDto, len(reports))
+	for i, report := range reports {
+		reasons[report.Reason]++
+		reportDtos[i] = ReportDto{
+			Id:         report.ID,
+			ReporterId: report.ReporterId,
+			Reason:     report.Reason,
+			Note:       report.Note,
+			CreatedAt:  report.CreatedAt,
+		}
+	}
+
+	actionDtos := make([]ActionDto, len(actions))
+	for i, action := range actions {
+		actionDtos[i] = ActionDto{
+			Id:           action.ID,
+			ModeratorId:  action.ModeratorId,
+			Action:       action.Action,
+			TargetUserId: action.TargetUserId,
+			Note:         action.Note,
+			CreatedAt:    action.CreatedAt,
+		}
+	}
+
+	return CaseDto{
+		CaseSummaryDto: caseSummary(*modCase, reasons),
+		Resolution:     modCase.Resolution,
+		ResolvedBy:     modCase.ResolvedBy,
+		ResolvedAt:     modCase.ResolvedAt,
+		Reports:        reportDtos,
+		Actions:        actionDtos,
+	}, nil
+}
+
+func (s *serviceImpl) TriageCase(ctx context.Context, moderatorId uint, caseId uint) error {
+	logger := log.FromContext(ctx).WithFields(log.Fields{
+		"caseId":      caseId,
+		"moderatorId": moderatorId,
+	})
+
+	logger.Debug("Triaging moderation case")
+
+	return s.Db.Transaction(func(tx *gorm.DB) error {
+		modCase, err := s.findActiveCase(tx, caseId)
+		if err != nil {
+			return err
+		}
+
+		return tx.
+			Model(modCase).
+			Updates(map[string]interface{}{
+				"status":      CaseStatusTriaged,
+				"assignee_id": moderatorId,
+			}).
+			Error
+	})
+}
+
+func (s *serviceImpl) ResolveCase(
+	ctx context.Context,
+	moderatorId uint,
+	caseId uint,
+	resolution ResolveCaseDto,
+) error {
+	logger := log.FromContext(ctx).WithFields(log.Fields{
+		"caseId":      caseId,
+		"moderatorId": moderatorId,
+		"actions":     resolution.Actions,
+	})
+
+	err := validator.New().Struct(resolution)
+	if err != nil {
+		return err
+	}
+
+	logger.Debug("Resolving moderation case")
+
+	return s.closeCase(ctx, moderatorId, caseId, CaseStatusResolved, resolution.Actions, resolution.Note)
+}
+
+func (s *serviceImpl) DismissCase(
+	ctx context.Context,
+	moderatorId uint,
+	caseId uint,
+	dismissal DismissCaseDto,
+) error {
+	logger := log.FromContext(ctx).WithFields(log.Fields{
+		"caseId":      caseId,
+		"moderatorId": moderatorId,
+	})
+
+	err := validator.New().Struct(dismissal)
+	if err != nil {
+		return err
+	}
+
+	logger.Debug("Dismissing moderation case")
+
+	return s.closeCase(ctx, moderatorId, caseId, CaseStatusDismissed, []string{}, dismissal.Note)
+}
+
+func (s *serviceImpl) ListWarnings(ctx context.Context, userId uint) ([]WarningDto, error) {
+	logger := log.FromContext(ctx).WithField("userId", userId)
+
+	var actions []ModerationAction
+	err := s.Db.
+		Where("target_user_id = ? AND action = ?", userId, ActionWarn).
+		Order("created_at desc").
+		Find(&actions).
+		Error
+	if err != nil {
+		logger.WithError(err).Error("Failed to list user warnings")
+		return nil, err
+	}
+
+	warnings := make([]WarningDto, len(actions))
+	for i, action := range actions {
+		warnings[i] = WarningDto{
+			Id:        action.ID,
+			Note:      action.Note,
+			CreatedAt: action.CreatedAt,
+		}
+	}
+
+	return warnings, nil
+}
+
+func (s *serviceImpl) ListUserReports(ctx context.Context, reporterId uint) ([]ReportDto, error) {
+	var reports []Report
+	err := s.Db.
+		Where("reporter_id = ?", reporterId).
+		Order("created_at desc").
+		Find(&reports).
+		Error
+	if err != nil {
+		log.FromContext(ctx).WithError(err).WithField("reporterId", reporterId).Error("Failed to list user reports")
+		return nil, err
+	}
+
+	dtos := make([]ReportDto, len(reports))
+	for i, report := range reports {
+		dtos[i] = ReportDto{
+			Id:         report.ID,
+			ReporterId: report.ReporterId,
+			Reason:     report.Reason,
+			Note:       report.Note,
+			CreatedAt:  report.CreatedAt,
+		}
+	}
+
+	return dtos, nil
+}
+
+// Close an active case, recording and applying the given actions. The case
+// status and the actions are stored in a transaction, the actions' side
+// effects (hiding, suspending, etc) are applied after it commits. Until they
+// all succeed the case has pending actions, and closing it again with the
+// same status applies the recorded actions instead of the given ones.
+func (s *serviceImpl) closeCase(
+	ctx context.Context,
+	moderatorId uint,
+	caseId uint,
+	status string,
+	actions []string,
+	note string,
+) error {
+	logger := log.FromContext(ctx).WithField("caseId", caseId)
+
+	hide := false
+	for _, action := range actions {
+		if action == ActionHide {
+			hide = true
+		}
+	}
+
+	var modCase *ModerationCase
+	var authorId uint
+
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		modCase, err = s.findCase(tx.Clauses(clause.Locking{Strength: "UPDATE"}), caseId)
+		if err != nil {
+			return err
+		}
+
+		if modCase.ActionsPending && modCase.Status == status {
+			logger.Info("Retrying the pending actions of a closed case")
+
+			actions, authorId, err = s.findRecordedActions(tx, modCase.ID)
+			return err
+		}
+
+		if !isActiveStatus(modCase.Status) {
+			return ErrCaseClosed
+		}
+
+		authorId, err = s.getTargetAuthor(ctx, modCase.TargetType, modCase.TargetId)
+		if err != nil {
+			return err
+		}
+
+		// An automatically hidden target is shown again unless
+		// the moderator decided to keep it hidden.
+		if modCase.AutoHidden && !hide {
+			actions = append(actions, ActionUnhide)
+		}
+
+		for _, action := range actions {
+			err = tx.Create(&ModerationAction{
+				CaseId:       modCase.ID,
+				ModeratorId:  moderatorId,
+				Action:       action,
+				TargetUserId: authorId,
+				Note:         note,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.
+			Model(modCase).
+			Updates(map[string]interface{}{
+				"status":          status,
+				"resolution":      note,
+				"resolved_by":     moderatorId,
+				"resolved_at":     time.Now(),
+				"actions_pending": len(actions) > 0,
+			}).
+			Error
+	})
+	if err != nil {
+		if !errors.Is(err, ErrCaseNotFound) && !errors.Is(err, ErrCaseClosed) {
+			logger.WithError(err).Error("Failed to close moderation case")
+		}
+
+		return err
+	}
+
+	for _, action := range actions {
+		switch action {
+		case ActionHide:
+			err = s.setTargetHidden(ctx, modCase.TargetType, modCase.TargetId, true)
+		case ActionUnhide:
+			err = s.setTargetHidden(ctx, modCase.TargetType, modCase.TargetId, false)
+		case ActionSuspend:
+			err = s.suspendUser(ctx, authorId)
+		}
+
+		if err != nil {
+			logger.
+				WithError(err).
+				WithField("action", action).
+				Error("Failed to apply moderation action")
+
+			return err
+		}
+	}
+
+	if len(actions) > 0 {
+		err = s.Db.Model(modCase).Update("actions_pending", false).Error
+		if err != nil {
+			logger.WithError(err).Error("Failed to mark moderation actions as applied")
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Find the actions recorded when a case was closed and the user they
+// affect.
+func (s *serviceImpl) findRecordedActions(tx *gorm.DB, caseId uint) ([]string, uint, error) {
+	var recorded []ModerationAction
+	err := tx.Where("case_id = ?", caseId).Order("created_at asc").Find(&recorded).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	actions := make([]string, len(recorded))
+	var authorId uint
+	for i, action := range recorded {
+		actions[i] = action.Action
+		authorId = action.TargetUserId
+	}
+
+	return actions, authorId, nil
+}
+
+func (s *serviceImpl) suspendUser(ctx context.Context, userId uint) error {
+	err := s.UsersService.SuspendUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	return s.AuthService.InvalidateSessions(ctx, userId)
+}
+
+// Get the id of the user responsible for a target, i.e. the owner of
+// a project or the user itself.
+// Returns ErrTargetNotFound if the target doesn't exist.
+func (s *serviceImpl) getTargetAuthor(ctx context.Context, targetType string, targetId uint) (uint, error) {
+	switch targetType {
+	case TargetProject:
+		ownerId, err := s.ProjectsService.GetProjectOwner(ctx, targetId)
+		if errors.Is(err, projects.ErrProjectNotFound) {
+			return 0, ErrTargetNotFound
+		}
+
+		return ownerId, err
+
+	case TargetUser:
+		_, err := s.UsersService.GetUser(ctx, targetId)
+		if errors.Is(err, users.ErrUserNotFound) {
+			return 0, ErrTargetNotFound
+		}
+
+		return targetId, err
+
+	default:
+		return 0, ErrTargetNotFound
+	}
+}
+
+func (s *serviceImpl) setTargetHidden(ctx context.Context, targetType string, targetId uint, hidden bool) error {
+	switch targetType {
+	case TargetProject:
+		return s.ProjectsService.SetProjectHidden(ctx, targetId, hidden)
+	case TargetUser:
+		return s.UsersService.SetUserHidden(ctx, targetId, hidden)
+	default:
+		return ErrTargetNotFound
+	}
+}
+
+// Find a case by id, returns ErrCaseNotFound if it can't be found.
+func (s *serviceImpl) findCase(db *gorm.DB, caseId uint) (*ModerationCase, error) {
+	modCase := &ModerationCase{}
+	err := db.First(modCase, caseId).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCaseNotFound
+		}
+
+		return nil, err
+	}
+
+	return modCase, nil
+}
+
+// Find a case by id and lock it for update. Returns ErrCaseNotFound if the
+// case can't be found and ErrCaseClosed if the case is not active.
+// Must be called inside a transaction.
+func (s *serviceImpl) findActiveCase(tx *gorm.DB, caseId uint) (*ModerationCase, error) {
+	modCase, err := s.findCase(tx.Clauses(clause.Locking{Strength: "UPDATE"}), caseId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isActiveStatus(modCase.Status) {
+		return nil, ErrCaseClosed
+	}
+
+	return modCase, nil
+}
+
+func isActiveStatus(status string) bool {
+	for _, active := range activeCaseStatuses {
+		if status == active {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Count the reports of each case grouped by reason.
+// Returns a map of case id -> reason -> count.
+func (s *serviceImpl) countReasons(caseIds []uint) (map[uint]map[string]int, error) {
+	reasons := map[uint]map[string]int{}
+	if len(caseIds) < 1 {
+		return reasons, nil
+	}
+
+	var rows []struct {
+		CaseId uint
+		Reason string
+		Count  int
+	}
+	err := s.Db.
+		Model(&Report{}).
+		Select("case_id, reason, count(*) as count").
+		Where("case_id IN ?", caseIds).
+		Group("case_id, reason").
+		Scan(&rows).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		if reasons[row.CaseId] == nil {
+			reasons[row.CaseId] = map[string]int{}
+		}
+
+		reasons[row.CaseId][row.Reason] = row.Count
+	}
+
+	return reasons, nil
+}
+
+func caseSummary(modCase ModerationCase, reasons map[string]int) CaseSummaryDto {
+	if reasons == nil {
+		reasons = map[string]int{}
+	}
+
+	return CaseSummaryDto{
+		Id:          modCase.ID,
+		TargetType:  modCase.TargetType,
+		TargetId:    modCase.TargetId,
+		Status:      modCase.Status,
+		ReportCount: modCase.ReportCount,
+		AutoHidden:  modCase.AutoHidden,
+		AssigneeId:  modCase.AssigneeId,
+		CreatedAt:   modCase.CreatedAt,
+		UpdatedAt:   modCase.UpdatedAt,
+		Reasons:     reasons,
+
+		ActionsPending: modCase.ActionsPending,
+	}
+}
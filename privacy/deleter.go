@@ -0,0 +1,49 @@
+package privacy
+
+import (
+	"context"
+	"github.com/apex/log"
+	"time"
+)
+
+// Deletes accounts once their cooling-off period is over.
+type Deleter struct {
+	Service Service
+
+	// How often to look for accounts to delete.
+	PollInterval time.Duration
+}
+
+func NewDeleter(service Service, pollInterval time.Duration) *Deleter {
+	return &Deleter{
+		Service:      service,
+		PollInterval: pollInterval,
+	}
+}
+
+// Delete due accounts until ctx is done.
+func (d *Deleter) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Keep going while there are full batches of due accounts. A
+			// batch with failed deletions ends the pass, the failed
+			// accounts are retried next time after the other due ones.
+			for {
+				deleted, err := d.Service.DeleteDueAccounts(ctx)
+				if err != nil {
+					log.WithError(err).Error("Failed to delete due accounts")
+				}
+
+				if err != nil || deleted < deletionBatchSize {
+					break
+				}
+			}
+		}
+	}
+}
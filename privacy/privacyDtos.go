@@ -0,0 +1,13 @@
+package privacy
+
+import "time"
+
+type DeleteAccountDto struct {
+	Password string `json:"password" validate:"required"`
+}
+
+type DeletionDto struct {
+	// When the account will be deleted. Logging in before then cancels
+	// the deletion.
+	ScheduledAt time.Time `json:"scheduledAt"`
+}
@@ -0,0 +1,90 @@
+package privacy
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/open-collaboration/server/auth"
+	"github.com/open-collaboration/server/utils"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// @Summary Export your data
+// @Description Download a zip archive with everything stored about your account, as JSON files.
+// @Tags users
+// @Router /users/me/export [get]
+// @Produce application/zip
+// @Success 200
+func RouteExportUserData(
+	writer http.ResponseWriter,
+	request *http.Request,
+	privacyService Service,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	// Written to a buffer first so that failures still get an error response
+	archive := bytes.Buffer{}
+	err = privacyService.ExportUserData(request.Context(), session.UserId(), &archive)
+	if err != nil {
+		return err
+	}
+
+	filename := fmt.Sprintf("account-export-%s.zip", time.Now().UTC().Format("2006-01-02"))
+
+	writer.Header().Set("Content-Type", "application/zip")
+	writer.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	writer.Header().Set("Content-Length", strconv.Itoa(archive.Len()))
+	writer.Header().Set("Cache-Control", "no-store")
+	writer.WriteHeader(http.StatusOK)
+
+	_, err = archive.WriteTo(writer)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Delete your account
+// @Description The account is deleted after a cooling-off period and all of its sessions are invalidated. Logging
+// @Description in before the deletion cancels it. Your personal data is deleted, projects and tasks you worked on
+// @Description are kept and attributed to an anonymous user.
+// @Tags users
+// @Router /users/me [delete]
+// @Param password body dtos.DeleteAccountDto true "Your password"
+// @Success 202 {object} dtos.DeletionDto
+// @Failure 403 "Wrong password"
+func RouteDeleteAccount(
+	writer http.ResponseWriter,
+	request *http.Request,
+	privacyService Service,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	dto := DeleteAccountDto{}
+	err = utils.ReadJson(request.Context(), request, &dto)
+	if err != nil {
+		return err
+	}
+
+	deletion, err := privacyService.RequestDeletion(request.Context(), session.UserId(), dto.Password)
+	if err != nil {
+		return err
+	}
+
+	writer.Header().Set("Set-Cookie", "sessionToken=; Max-Age=0")
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusAccepted, deletion)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
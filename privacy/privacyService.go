@@ -0,0 +1,254 @@
+package privacy
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"github.com/apex/log"
+	"github.com/open-collaboration/server/activity"
+	"github.com/open-collaboration/server/auth"
+	"github.com/open-collaboration/server/moderation"
+	"github.com/open-collaboration/server/projects"
+	"github.com/open-collaboration/server/users"
+	"io"
+	"time"
+)
+
+// Maximum amount of accounts deleted by DeleteDueAccounts at a time.
+const deletionBatchSize = 20
+
+// Page size used to read paginated data while exporting it.
+const exportPageSize = 500
+
+const exportReadme = `This archive contains all the data we hold about your account, as JSON:
+
+account.json    Your account, profile, directory settings and security events (password and email changes, deletion requests)
+skills.json     Your skills and the endorsements you gave to other users
+projects.json   The projects you own, your memberships, the tasks assigned to you, your project transfers and role suggestions
+quota.json      Your project quotas
+activity.json   Your activity feed
+reports.json    The reports you filed
+warnings.json   The warnings moderators gave you
+sessions.json   Your active sessions. Session keys are not included.
+`
+
+type Service interface {
+	// Write a zip archive with everything stored about a user.
+	// Returns users.ErrUserNotFound if the user can't be found.
+	ExportUserData(ctx context.Context, userId uint, writer io.Writer) error
+
+	// Schedule the deletion of a user's account after the cooling-off
+	// period and invalidate all of the user's sessions. Logging in before
+	// the deletion cancels it.
+	// Returns auth.ErrWrongPassword if password is not the user's password.
+	RequestDeletion(ctx context.Context, userId uint, password string) (DeletionDto, error)
+
+	// Delete the accounts whose cooling-off period is over. Accounts that
+	// fail to be deleted are logged and retried on a later call, after the
+	// other due accounts. Returns the amount of deleted accounts.
+	DeleteDueAccounts(ctx context.Context) (int, error)
+}
+
+type serviceImpl struct {
+	UsersService      users.Service
+	SkillsService     users.SkillsService
+	ProjectsService   projects.Service
+	ActivityService   activity.Service
+	ModerationService moderation.Service
+	AuthService       auth.Service
+
+	// How long after being requested accounts are deleted.
+	CoolingOff time.Duration
+}
+
+func NewService(
+	usersService users.Service,
+	skillsService users.SkillsService,
+	projectsService projects.Service,
+	activityService activity.Service,
+	moderationService moderation.Service,
+	authService auth.Service,
+	coolingOff time.Duration,
+) Service {
+	return &serviceImpl{
+		UsersService:      usersService,
+		SkillsService:     skillsService,
+		ProjectsService:   projectsService,
+		ActivityService:   activityService,
+		ModerationService: moderationService,
+		AuthService:       authService,
+		CoolingOff:        coolingOff,
+	}
+}
+
+func (s *serviceImpl) ExportUserData(ctx context.Context, userId uint, writer io.Writer) error {
+	logger := log.FromContext(ctx).WithField("userId", userId)
+
+	logger.Debug("Exporting user data")
+
+	// Gather everything before writing, so that errors can still be
+	// reported to the client.
+	account, err := s.UsersService.ExportAccount(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	skills, err := s.SkillsService.ExportSkills(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	userProjects, err := s.ProjectsService.ExportUserData(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	quota, err := s.ProjectsService.GetQuota(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	activities := []activity.ActivityDto{}
+	for page := uint(0); ; page++ {
+		activitiesPage, err := s.ActivityService.ListActivities(ctx, userId, exportPageSize, page)
+		if err != nil {
+			return err
+		}
+
+		activities = append(activities, activitiesPage...)
+		if len(activitiesPage) < exportPageSize {
+			break
+		}
+	}
+
+	reports, err := s.ModerationService.ListUserReports(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	warnings, err := s.ModerationService.ListWarnings(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := s.AuthService.ListSessions(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(writer)
+
+	err = writeFile(archive, "README.txt", []byte(exportReadme))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range []struct {
+		name string
+		data interface{}
+	}{
+		{"account.json", account},
+		{"skills.json", skills},
+		{"projects.json", userProjects},
+		{"quota.json", quota},
+		{"activity.json", activities},
+		{"reports.json", reports},
+		{"warnings.json", warnings},
+		{"sessions.json", sessions},
+	} {
+		content, err := json.MarshalIndent(file.data, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		err = writeFile(archive, file.name, content)
+		if err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+func (s *serviceImpl) RequestDeletion(ctx context.Context, userId uint, password string) (DeletionDto, error) {
+	err := s.AuthService.CheckPassword(ctx, userId, password)
+	if err != nil {
+		return DeletionDto{}, err
+	}
+
+	scheduledAt := time.Now().Add(s.CoolingOff).Truncate(time.Second)
+
+	err = s.UsersService.ScheduleDeletion(ctx, userId, scheduledAt)
+	if err != nil {
+		return DeletionDto{}, err
+	}
+
+	err = s.AuthService.InvalidateSessions(ctx, userId)
+	if err != nil {
+		return DeletionDto{}, err
+	}
+
+	return DeletionDto{ScheduledAt: scheduledAt}, nil
+}
+
+func (s *serviceImpl) DeleteDueAccounts(ctx context.Context) (int, error) {
+	ids, err := s.UsersService.ListDueDeletions(ctx, deletionBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, id := range ids {
+		err = s.deleteAccount(ctx, id)
+		if err != nil {
+			log.FromContext(ctx).WithError(err).WithField("userId", id).Error("Failed to delete user account")
+			continue
+		}
+
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+// Delete a user's personal data and anonymize the user's account. Content
+// shared with other users (projects, memberships, tasks, reports) is kept
+// and stays attributed to the anonymized account. Each step can be run
+// again, the account is only anonymized once all of them succeed.
+func (s *serviceImpl) deleteAccount(ctx context.Context, userId uint) error {
+	logger := log.FromContext(ctx).WithField("userId", userId)
+
+	logger.Info("Deleting user account")
+
+	err := s.AuthService.InvalidateSessions(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	err = s.SkillsService.DeleteUserSkills(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	err = s.ActivityService.DeleteActivities(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	err = s.ProjectsService.ForgetUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	return s.UsersService.AnonymizeUser(ctx, userId)
+}
+
+func writeFile(archive *zip.Writer, name string, content []byte) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(content)
+	return err
+}
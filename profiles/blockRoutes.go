@@ -0,0 +1,98 @@
+package profiles
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/open-collaboration/server/auth"
+	"github.com/open-collaboration/server/users"
+	"github.com/open-collaboration/server/utils"
+	"net/http"
+)
+
+// @Summary List the users you blocked or muted
+// @Tags users
+// @Router /users/me/blocks [get]
+// @Success 200 {array} dtos.BlockDto
+func RouteListBlocks(
+	writer http.ResponseWriter,
+	request *http.Request,
+	blocksService users.BlocksService,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	blocks, err := blocksService.ListBlocks(request.Context(), session.UserId())
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusOK, blocks)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Block or mute a user
+// @Description Blocked users can't invite you to projects, transfer projects to you or endorse your skills. The content of blocked and muted users is hidden from you. They aren't told about it.
+// @Tags users
+// @Router /users/me/blocks [post]
+// @Param block body dtos.NewBlockDto true "The user and whether to block or mute them"
+// @Success 201 {object} dtos.BlockDto
+func RouteBlockUser(
+	writer http.ResponseWriter,
+	request *http.Request,
+	blocksService users.BlocksService,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	dto := users.NewBlockDto{}
+	err = utils.ReadJson(request.Context(), request, &dto)
+	if err != nil {
+		return err
+	}
+
+	block, err := blocksService.BlockUser(request.Context(), session.UserId(), dto)
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusCreated, block)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Unblock or unmute a user
+// @Tags users
+// @Router /users/me/blocks/{username} [delete]
+// @Param username path string true "The user's username"
+// @Success 204
+func RouteUnblockUser(
+	writer http.ResponseWriter,
+	request *http.Request,
+	blocksService users.BlocksService,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	username := mux.Vars(request)["username"]
+
+	err = blocksService.UnblockUser(request.Context(), session.UserId(), username)
+	if err != nil {
+		return err
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
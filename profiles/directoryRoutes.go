@@ -0,0 +1,149 @@
+package profiles
+
+import (
+	"github.com/open-collaboration/server/auth"
+	"github.com/open-collaboration/server/projects"
+	"github.com/open-collaboration/server/users"
+	"github.com/open-collaboration/server/utils"
+	"net/http"
+)
+
+// @Summary Search the contributor directory
+// @Description Only users who chose to be listed are found. Users who didn't make their skills or time zone public never match filters on them.
+// @Tags users
+// @Router /users [get]
+// @Param skills query []string false "Only list users with at least one of these skills"
+// @Param minProficiency query string false "Only match skills at this proficiency or above (learning, comfortable or expert)"
+// @Param availability query string false "Only list users with this availability (open or busy)"
+// @Param minWeeklyHours query int false "Only list users who can give at least this many hours a week"
+// @Param maxWeeklyHours query int false "Only list users who can give at most this many hours a week"
+// @Param minUtcOffset query int false "Only list users whose time zone is currently at this UTC offset or above, in minutes"
+// @Param maxUtcOffset query int false "Only list users whose time zone is currently at this UTC offset or below, in minutes"
+// @Param overlapProjectId query int false "Only list users whose working hours overlap the working hours of this project's team today"
+// @Param minOverlapHours query int false "Least hours of overlap with overlapProjectId. The project's own minimum applies if it's higher."
+// @Param after query string false "The nextCursor of the previous page"
+// @Param pageSize query int false "Maximum amount of users in the response. Default is 20, max is 50."
+// @Success 200 {object} dtos.DirectoryPageDto
+func RouteSearchDirectory(
+	writer http.ResponseWriter,
+	request *http.Request,
+	directoryService users.DirectoryService,
+	projectsService projects.Service,
+) error {
+	pageSize, _ := utils.IntFromQuery(request, "pageSize", 20)
+	if pageSize < 1 || pageSize > 50 {
+		pageSize = 20
+	}
+
+	params := users.DirectorySearchDto{
+		Skills:         utils.ListFromQuery(request, "skills"),
+		MinProficiency: request.URL.Query().Get("minProficiency"),
+		Availability:   request.URL.Query().Get("availability"),
+		After:          request.URL.Query().Get("after"),
+		PageSize:       uint(pageSize),
+	}
+
+	if session, ok := auth.SessionFromContext(request.Context()); ok {
+		params.ViewerId = session.UserId()
+	}
+
+	params.MinWeeklyHours, _ = utils.IntFromQuery(request, "minWeeklyHours", 0)
+	params.MaxWeeklyHours, _ = utils.IntFromQuery(request, "maxWeeklyHours", 0)
+
+	if offset, ok := utils.IntFromQuery(request, "minUtcOffset", 0); ok {
+		params.MinUtcOffset = &offset
+	}
+
+	if offset, ok := utils.IntFromQuery(request, "maxUtcOffset", 0); ok {
+		params.MaxUtcOffset = &offset
+	}
+
+	if projectId, ok := utils.IntFromQuery(request, "overlapProjectId", 0); ok {
+		project, err := projectsService.GetProject(request.Context(), uint(projectId), nil)
+		if err != nil {
+			return err
+		}
+
+		minOverlapHours, _ := utils.IntFromQuery(request, "minOverlapHours", 0)
+
+		params.Overlap, err = project.Collaboration.OverlapFilter(minOverlapHours * 60)
+		if err != nil {
+			return err
+		}
+	}
+
+	page, err := directoryService.SearchDirectory(request.Context(), params)
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusOK, page)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Get your contributor directory settings
+// @Tags users
+// @Router /users/me/directory [get]
+// @Success 200 {object} dtos.DirectorySettingsDto
+func RouteGetDirectorySettings(
+	writer http.ResponseWriter,
+	request *http.Request,
+	directoryService users.DirectoryService,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	settings, err := directoryService.GetDirectorySettings(request.Context(), session.UserId())
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusOK, settings)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Change your contributor directory settings
+// @Description Listing yourself is opt-in. Only your username, availability, weekly hours and the profile fields in publicFields are shown in the directory.
+// @Description publicFields also applies to your public profile, whether or not you're listed.
+// @Tags users
+// @Router /users/me/directory [put]
+// @Param settings body dtos.DirectorySettingsDto true "The new settings"
+// @Success 200 {object} dtos.DirectorySettingsDto
+func RouteUpdateDirectorySettings(
+	writer http.ResponseWriter,
+	request *http.Request,
+	directoryService users.DirectoryService,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	dto := users.DirectorySettingsDto{}
+	err = utils.ReadJson(request.Context(), request, &dto)
+	if err != nil {
+		return err
+	}
+
+	settings, err := directoryService.UpdateDirectorySettings(request.Context(), session.UserId(), dto)
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusOK, settings)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
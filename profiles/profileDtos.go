@@ -0,0 +1,17 @@
+package profiles
+
+import (
+	"github.com/open-collaboration/server/projects"
+	"github.com/open-collaboration/server/users"
+)
+
+type ProfileDto struct {
+	users.PublicProfileDto
+
+	// The user's skills, most endorsed first. Empty if the user didn't make
+	// them public.
+	Skills []users.SkillDto `json:"skills"`
+
+	// Projects the user owns or is a member of.
+	Projects []projects.MemberProjectDto `json:"projects"`
+}
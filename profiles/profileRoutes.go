@@ -0,0 +1,137 @@
+package profiles
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/open-collaboration/server/auth"
+	"github.com/open-collaboration/server/users"
+	"github.com/open-collaboration/server/utils"
+	"net/http"
+)
+
+// @Summary Get a user's public profile
+// @Description Only the profile fields in the user's publicFields directory setting are shown, the others are empty.
+// @Tags users
+// @Router /users/{username} [get]
+// @Param username path string true "The user's username"
+// @Success 200 {object} dtos.ProfileDto
+func RouteGetProfile(
+	writer http.ResponseWriter,
+	request *http.Request,
+	profilesService Service,
+) error {
+	username := mux.Vars(request)["username"]
+
+	profile, err := profilesService.GetProfile(request.Context(), username)
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusOK, profile)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Update your profile
+// @Tags users
+// @Router /users/me [patch]
+// @Param profile body dtos.UpdateProfileDto true "The fields to change. Missing fields are left as they are, empty ones are cleared."
+// @Success 200 {object} dtos.PublicProfileDto
+func RouteUpdateOwnProfile(
+	writer http.ResponseWriter,
+	request *http.Request,
+	usersService users.Service,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	dto := users.UpdateProfileDto{}
+	err = utils.ReadJson(request.Context(), request, &dto)
+	if err != nil {
+		return err
+	}
+
+	profile, err := usersService.UpdateProfile(request.Context(), session.UserId(), dto)
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusOK, profile)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Resend the verification email
+// @Description Only one email is sent every few minutes, see the Retry-After header of 429 responses.
+// @Tags users
+// @Router /users/me/verification-email [post]
+// @Success 204
+// @Failure 409 "The email is already verified"
+// @Failure 429 "An email was sent too recently"
+func RouteResendVerificationEmail(
+	writer http.ResponseWriter,
+	request *http.Request,
+	usersService users.Service,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	err = usersService.SendVerificationEmail(request.Context(), session.UserId())
+	if err != nil {
+		return err
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// @Summary List your security events
+// @Description Password and email changes made to your account, newest to oldest.
+// @Tags users
+// @Router /users/me/security-events [get]
+// @Param pageSize query int false "Maximum amount of events in the response. Default is 20, max is 50."
+// @Param pageOffset query int false "Response page number."
+// @Success 200 {array} dtos.SecurityEventDto
+func RouteListSecurityEvents(
+	writer http.ResponseWriter,
+	request *http.Request,
+	usersService users.Service,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	pageSize, _ := utils.IntFromQuery(request, "pageSize", 20)
+	pageOffset, _ := utils.IntFromQuery(request, "pageOffset", 0)
+
+	if pageSize < 1 || pageSize > 50 {
+		pageSize = 20
+	}
+
+	if pageOffset < 1 {
+		pageOffset = 0
+	}
+
+	events, err := usersService.ListSecurityEvents(request.Context(), session.UserId(), uint(pageSize), uint(pageOffset))
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusOK, events)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
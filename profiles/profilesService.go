@@ -0,0 +1,108 @@
+package profiles
+
+import (
+	"context"
+	"github.com/open-collaboration/server/auth"
+	"github.com/open-collaboration/server/projects"
+	"github.com/open-collaboration/server/users"
+)
+
+type Service interface {
+	// Get a user's public profile along with the user's skills and the
+	// projects the user is part of. Only the projects the user in ctx can
+	// see are listed.
+	// Returns users.ErrUserNotFound if the user can't be found.
+	GetProfile(ctx context.Context, username string) (ProfileDto, error)
+
+	// Endorse a user's skill. Users can only endorse the skills of other
+	// users they have been on a project's team with.
+	// Returns users.ErrUserNotFound if the user can't be found, auth.ErrForbidden
+	// if endorserId can't endorse the user, users.ErrBlocked if the user
+	// blocked endorserId and the errors of users.SkillsService.AddEndorsement.
+	EndorseSkill(ctx context.Context, endorserId uint, username string, skillId uint) error
+
+	// Withdraw an endorsement of a user's skill.
+	// Returns users.ErrUserNotFound if the user can't be found and
+	// users.ErrEndorsementNotFound if endorserId didn't endorse the skill.
+	WithdrawEndorsement(ctx context.Context, endorserId uint, username string, skillId uint) error
+}
+
+type serviceImpl struct {
+	UsersService    users.Service
+	SkillsService   users.SkillsService
+	BlocksService   users.BlocksService
+	ProjectsService projects.Service
+}
+
+func NewService(
+	usersService users.Service,
+	skillsService users.SkillsService,
+	blocksService users.BlocksService,
+	projectsService projects.Service,
+) Service {
+	return &serviceImpl{
+		UsersService:    usersService,
+		SkillsService:   skillsService,
+		BlocksService:   blocksService,
+		ProjectsService: projectsService,
+	}
+}
+
+func (s *serviceImpl) GetProfile(ctx context.Context, username string) (ProfileDto, error) {
+	profile, err := s.UsersService.GetPublicProfile(ctx, username)
+	if err != nil {
+		return ProfileDto{}, err
+	}
+
+	skills, err := s.SkillsService.ListPublicSkills(ctx, profile.Id)
+	if err != nil {
+		return ProfileDto{}, err
+	}
+
+	memberProjects, err := s.ProjectsService.ListMemberProjects(ctx, profile.Id)
+	if err != nil {
+		return ProfileDto{}, err
+	}
+
+	return ProfileDto{
+		PublicProfileDto: profile,
+		Skills:           skills,
+		Projects:         memberProjects,
+	}, nil
+}
+
+func (s *serviceImpl) EndorseSkill(ctx context.Context, endorserId uint, username string, skillId uint) error {
+	profile, err := s.UsersService.GetPublicProfile(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	if profile.Id == endorserId {
+		return auth.ErrForbidden
+	}
+
+	sharesProject, err := s.ProjectsService.SharesProject(ctx, endorserId, profile.Id)
+	if err != nil {
+		return err
+	}
+
+	if !sharesProject {
+		return auth.ErrForbidden
+	}
+
+	err = s.BlocksService.CheckCanInteract(ctx, endorserId, profile.Id)
+	if err != nil {
+		return err
+	}
+
+	return s.SkillsService.AddEndorsement(ctx, profile.Id, skillId, endorserId)
+}
+
+func (s *serviceImpl) WithdrawEndorsement(ctx context.Context, endorserId uint, username string, skillId uint) error {
+	profile, err := s.UsersService.GetPublicProfile(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	return s.SkillsService.RemoveEndorsement(ctx, profile.Id, skillId, endorserId)
+}
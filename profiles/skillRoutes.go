@@ -0,0 +1,207 @@
+package profiles
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/open-collaboration/server/auth"
+	"github.com/open-collaboration/server/users"
+	"github.com/open-collaboration/server/utils"
+	"net/http"
+)
+
+// @Summary List your skills
+// @Tags skills
+// @Router /users/me/skills [get]
+// @Success 200 {array} dtos.SkillDto
+func RouteListOwnSkills(
+	writer http.ResponseWriter,
+	request *http.Request,
+	skillsService users.SkillsService,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	skills, err := skillsService.ListSkills(request.Context(), session.UserId())
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusOK, skills)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Declare a skill
+// @Tags skills
+// @Router /users/me/skills [post]
+// @Param skill body dtos.NewSkillDto true "The skill"
+// @Success 201 {object} dtos.SkillDto
+func RouteAddSkill(
+	writer http.ResponseWriter,
+	request *http.Request,
+	skillsService users.SkillsService,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	dto := users.NewSkillDto{}
+	err = utils.ReadJson(request.Context(), request, &dto)
+	if err != nil {
+		return err
+	}
+
+	skill, err := skillsService.AddSkill(request.Context(), session.UserId(), dto)
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusCreated, skill)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Update a skill
+// @Tags skills
+// @Router /users/me/skills/{skillId} [put]
+// @Param skillId path int true "The skill ID"
+// @Param skill body dtos.NewSkillDto true "The skill"
+// @Success 200 {object} dtos.SkillDto
+func RouteUpdateSkill(
+	writer http.ResponseWriter,
+	request *http.Request,
+	skillsService users.SkillsService,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	skillId, err := utils.UintFromVars(request, "skillId")
+	if err != nil {
+		return err
+	}
+
+	dto := users.NewSkillDto{}
+	err = utils.ReadJson(request.Context(), request, &dto)
+	if err != nil {
+		return err
+	}
+
+	skill, err := skillsService.UpdateSkill(request.Context(), session.UserId(), skillId, dto)
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusOK, skill)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Remove a skill
+// @Tags skills
+// @Router /users/me/skills/{skillId} [delete]
+// @Param skillId path int true "The skill ID"
+// @Success 204
+func RouteDeleteSkill(
+	writer http.ResponseWriter,
+	request *http.Request,
+	skillsService users.SkillsService,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	skillId, err := utils.UintFromVars(request, "skillId")
+	if err != nil {
+		return err
+	}
+
+	err = skillsService.DeleteSkill(request.Context(), session.UserId(), skillId)
+	if err != nil {
+		return err
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// @Summary Endorse a user's skill
+// @Description Only users who have been on a project's team with the user can endorse the user's skills.
+// @Tags skills
+// @Router /users/{username}/skills/{skillId}/endorsements [post]
+// @Param username path string true "The user's username"
+// @Param skillId path int true "The skill ID"
+// @Success 204
+func RouteEndorseSkill(
+	writer http.ResponseWriter,
+	request *http.Request,
+	profilesService Service,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	skillId, err := utils.UintFromVars(request, "skillId")
+	if err != nil {
+		return err
+	}
+
+	username := mux.Vars(request)["username"]
+
+	err = profilesService.EndorseSkill(request.Context(), session.UserId(), username, skillId)
+	if err != nil {
+		return err
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// @Summary Withdraw your endorsement of a user's skill
+// @Tags skills
+// @Router /users/{username}/skills/{skillId}/endorsements [delete]
+// @Param username path string true "The user's username"
+// @Param skillId path int true "The skill ID"
+// @Success 204
+func RouteWithdrawEndorsement(
+	writer http.ResponseWriter,
+	request *http.Request,
+	profilesService Service,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	skillId, err := utils.UintFromVars(request, "skillId")
+	if err != nil {
+		return err
+	}
+
+	username := mux.Vars(request)["username"]
+
+	err = profilesService.WithdrawEndorsement(request.Context(), session.UserId(), username, skillId)
+	if err != nil {
+		return err
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
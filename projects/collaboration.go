@@ -0,0 +1,99 @@
+package projects
+
+import (
+	"context"
+	"github.com/apex/log"
+	"github.com/go-playground/validator/v10"
+	"github.com/open-collaboration/server/users"
+	"gorm.io/gorm"
+)
+
+func (s *serviceImpl) SetCollaboration(
+	ctx context.Context,
+	userId uint,
+	projectId uint,
+	collaboration CollaborationDto,
+) (CollaborationDto, error) {
+	logger := log.FromContext(ctx).WithField("projectId", projectId)
+
+	err := validator.New().Struct(collaboration)
+	if err != nil {
+		return CollaborationDto{}, err
+	}
+
+	if collaboration.WorkingHours != nil && collaboration.Timezone == "" {
+		return CollaborationDto{}, users.ErrMissingTimezone
+	}
+
+	err = s.checkCanManage(ctx, userId, projectId)
+	if err != nil {
+		return CollaborationDto{}, err
+	}
+
+	updates := map[string]interface{}{
+		"weekly_hours":      collaboration.WeeklyHours,
+		"timezone":          collaboration.Timezone,
+		"work_start":        nil,
+		"work_end":          nil,
+		"min_overlap_hours": collaboration.MinOverlapHours,
+	}
+
+	if collaboration.WorkingHours != nil {
+		updates["work_start"], updates["work_end"] = collaboration.WorkingHours.Minutes()
+	}
+
+	result := s.Db.Model(&Project{}).Where("id = ?", projectId).Updates(updates)
+	if result.Error != nil {
+		logger.WithError(result.Error).Error("Failed to update collaboration preferences")
+		return CollaborationDto{}, result.Error
+	}
+
+	if result.RowsAffected < 1 {
+		return CollaborationDto{}, ErrProjectNotFound
+	}
+
+	return collaboration, nil
+}
+
+// Get a filter for the users whose working hours overlap the team's by at
+// least minMinutes, or by the project's minimum overlap if it's higher.
+// Returns users.ErrNoWorkingHours if the project didn't set working hours.
+func (dto CollaborationDto) OverlapFilter(minMinutes int) (*users.OverlapFilter, error) {
+	if dto.WorkingHours == nil {
+		return nil, users.ErrNoWorkingHours
+	}
+
+	if minMinutes < dto.MinOverlapHours*60 {
+		minMinutes = dto.MinOverlapHours * 60
+	}
+
+	start, end := dto.WorkingHours.Minutes()
+
+	return users.NewOverlapFilter(dto.Timezone, &start, &end, minMinutes)
+}
+
+func collaborationDto(project *Project) CollaborationDto {
+	return CollaborationDto{
+		WeeklyHours:     project.WeeklyHours,
+		Timezone:        project.Timezone,
+		WorkingHours:    users.WorkingHoursFromMinutes(project.WorkStart, project.WorkEnd),
+		MinOverlapHours: project.MinOverlapHours,
+	}
+}
+
+// Filter a projects query by the overlap of the projects' working hours with
+// the given ones. Projects need at least the overlap in the filter and their
+// own minimum overlap. Projects without working hours are left out.
+func filterByOverlap(query *gorm.DB, overlap *users.OverlapFilter) *gorm.DB {
+	// The given working day is compared with the project's working hours of
+	// the days around it, at the actual offsets of both time zones on that
+	// day.
+	return query.Where(
+		"working_hours_overlap(?, ?, ?, NULLIF(timezone, ''), work_start, work_end, ?) >= GREATEST(?, min_overlap_hours * 60)",
+		overlap.Timezone,
+		overlap.Start,
+		overlap.End,
+		overlap.Day,
+		overlap.MinMinutes,
+	)
+}
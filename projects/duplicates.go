@@ -0,0 +1,114 @@
+package projects
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+)
+
+// Advisory lock used to serialize duplicate checks of a repository link.
+const duplicateLockNamespace = 3
+
+// Minimum trigram similarity (from 0 to 1) between the names or short
+// descriptions of two projects for them to be considered near-duplicates.
+const nearDuplicateThreshold = 0.6
+
+// Maximum amount of near-duplicates returned in a NearDuplicateError.
+const maxNearDuplicates = 5
+
+type DuplicateCandidateDto struct {
+	Id         uint    `json:"id"`
+	Slug       string  `json:"slug"`
+	Name       string  `json:"name"`
+	Similarity float64 `json:"similarity"`
+}
+
+// Returned when a project's repository link already belongs to
+// another project. ProjectId and Slug are left empty unless the other
+// project is public.
+type DuplicateProjectError struct {
+	ProjectId uint
+	Slug      string
+}
+
+func (e *DuplicateProjectError) Error() string {
+	if e.ProjectId == 0 {
+		return "repository link already used by a project that isn't public"
+	}
+
+	return fmt.Sprintf("repository link already used by project %d", e.ProjectId)
+}
+
+// Returned when a new project looks a lot like existing projects. The
+// project can still be created by setting NewProjectDto.IgnoreNearDuplicates.
+type NearDuplicateError struct {
+	Candidates []DuplicateCandidateDto
+}
+
+func (e *NearDuplicateError) Error() string {
+	return fmt.Sprintf("project has %d near-duplicates", len(e.Candidates))
+}
+
+// Check that no project other than projectId uses the normalized repository
+// link. The link stays locked until the transaction ends, so that two
+// projects with the same link can't be created concurrently.
+// Returns a *DuplicateProjectError if the link is taken.
+// Must be called inside a transaction.
+func checkRepoLinkDuplicate(tx *gorm.DB, normalizedLink string, projectId uint) error {
+	err := tx.Exec("SELECT pg_advisory_xact_lock(?, hashtext(?))", duplicateLockNamespace, normalizedLink).Error
+	if err != nil {
+		return err
+	}
+
+	duplicate := Project{}
+	err = tx.
+		Select("id", "slug", "visibility").
+		Where("github_link_normalized = ? AND id <> ?", normalizedLink, projectId).
+		First(&duplicate).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+
+		return err
+	}
+
+	if duplicate.Visibility != VisibilityPublic {
+		return &DuplicateProjectError{}
+	}
+
+	return &DuplicateProjectError{
+		ProjectId: duplicate.ID,
+		Slug:      duplicate.Slug,
+	}
+}
+
+// Find public projects whose name or short description are similar to the
+// given ones, most similar first.
+func findNearDuplicates(db *gorm.DB, name string, shortDescription string) ([]DuplicateCandidateDto, error) {
+	candidates := []DuplicateCandidateDto{}
+	err := db.
+		Model(&Project{}).
+		Select(
+			"id, slug, name, GREATEST(similarity(name, ?), similarity(short_description, ?)) AS similarity",
+			name,
+			shortDescription,
+		).
+		Where("hidden_at IS NULL AND visibility = ?", VisibilityPublic).
+		// The % operator can use the trigram indexes, the similarity
+		// check below is stricter than it.
+		Where("name % ? OR short_description % ?", name, shortDescription).
+		Where(
+			"GREATEST(similarity(name, ?), similarity(short_description, ?)) >= ?",
+			name,
+			shortDescription,
+			nearDuplicateThreshold,
+		).
+		Order("similarity desc").
+		Limit(maxNearDuplicates).
+		Scan(&candidates).
+		Error
+
+	return candidates, err
+}
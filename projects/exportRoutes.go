@@ -0,0 +1,249 @@
+package projects
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"github.com/apex/log"
+	"github.com/go-playground/validator/v10"
+	"github.com/open-collaboration/server/auth"
+	"github.com/open-collaboration/server/users"
+	"github.com/open-collaboration/server/utils"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Amount of exported projects written between flushes of the response.
+const exportFlushInterval = 100
+
+type exportParams struct {
+	Format string `validate:"oneof=csv json ndjson"`
+}
+
+// Writes exported projects in one of the export formats.
+type exportWriter interface {
+	begin() error
+	write(project ProjectExportDto) error
+	end() error
+}
+
+// @Summary Export projects
+// @Tags projects
+// @Router /projects/export [get]
+// @Param format query string false "csv, json or ndjson. Default is json."
+// @Param tags query []string false "Only export projects with at least one of these tags"
+// @Param mine query bool false "Only export your own projects, including hidden, unlisted and private ones"
+// @Param includeHidden query bool false "Also export hidden, unlisted and private projects. Admins only."
+// @Success 200
+func RouteExportProjects(
+	writer http.ResponseWriter,
+	request *http.Request,
+	projectsService Service,
+	usersService users.Service,
+) error {
+	ctx := request.Context()
+	logger := log.FromContext(ctx)
+
+	query := request.URL.Query()
+
+	params := exportParams{Format: query.Get("format")}
+	if params.Format == "" {
+		params.Format = "json"
+	}
+
+	err := validator.New().Struct(params)
+	if err != nil {
+		return err
+	}
+
+	options := ExportOptions{Tags: utils.ListFromQuery(request, "tags")}
+
+	if query.Get("mine") == "true" || query.Get("includeHidden") == "true" {
+		session, err := auth.CheckSession(request)
+		if err != nil {
+			return err
+		}
+
+		if query.Get("mine") == "true" {
+			options.OwnerId = session.UserId()
+			options.IncludeHidden = true
+		}
+
+		if query.Get("includeHidden") == "true" {
+			user, err := usersService.GetUser(ctx, session.UserId())
+			if err != nil {
+				return err
+			}
+
+			if !user.IsAdmin() {
+				return auth.ErrForbidden
+			}
+
+			options.IncludeHidden = true
+		}
+	}
+
+	var export exportWriter
+	var contentType string
+	switch params.Format {
+	case "csv":
+		export = &csvExportWriter{writer: csv.NewWriter(writer)}
+		contentType = "text/csv"
+	case "ndjson":
+		export = &ndjsonExportWriter{encoder: json.NewEncoder(writer)}
+		contentType = "application/x-ndjson"
+	default:
+		export = &jsonExportWriter{writer: writer}
+		contentType = "application/json"
+	}
+
+	writer.Header().Set("Content-Type", contentType)
+	writer.Header().Set("Content-Disposition", "attachment; filename=\"projects."+params.Format+"\"")
+	writer.WriteHeader(http.StatusOK)
+
+	// From here on the status code has already been sent, errors can
+	// only be logged and the response cut short.
+	err = export.begin()
+	if err != nil {
+		logger.WithError(err).Error("Failed to write export")
+		return nil
+	}
+
+	flusher, canFlush := writer.(http.Flusher)
+	written := 0
+
+	err = projectsService.ExportProjects(ctx, options, func(project ProjectExportDto) error {
+		err := export.write(project)
+		if err != nil {
+			return err
+		}
+
+		written++
+		if canFlush && written%exportFlushInterval == 0 {
+			flusher.Flush()
+		}
+
+		return nil
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to export projects")
+		return nil
+	}
+
+	err = export.end()
+	if err != nil {
+		logger.WithError(err).Error("Failed to write export")
+	}
+
+	return nil
+}
+
+type csvExportWriter struct {
+	writer *csv.Writer
+}
+
+func (w *csvExportWriter) begin() error {
+	return w.writer.Write([]string{
+		"id",
+		"slug",
+		"name",
+		"tags",
+		"shortDescription",
+		"fullDescription",
+		"githubLink",
+		"visibility",
+		"hidden",
+		"createdAt",
+		"updatedAt",
+	})
+}
+
+func (w *csvExportWriter) write(project ProjectExportDto) error {
+	err := w.writer.Write([]string{
+		strconv.Itoa(int(project.Id)),
+		project.Slug,
+		project.Name,
+		strings.Join(project.Tags, ","),
+		project.ShortDescription,
+		project.LongDescription,
+		project.GithubLink,
+		project.Visibility,
+		strconv.FormatBool(project.Hidden),
+		project.CreatedAt.Format(time.RFC3339),
+		project.UpdatedAt.Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Push the row to the response so that rows don't pile up in
+	// the csv writer's buffer.
+	w.writer.Flush()
+
+	return w.writer.Error()
+}
+
+func (w *csvExportWriter) end() error {
+	w.writer.Flush()
+
+	return w.writer.Error()
+}
+
+// Writes a JSON array one element at a time.
+type jsonExportWriter struct {
+	writer io.Writer
+	count  int
+}
+
+func (w *jsonExportWriter) begin() error {
+	_, err := w.writer.Write([]byte("["))
+	return err
+}
+
+func (w *jsonExportWriter) write(project ProjectExportDto) error {
+	if w.count > 0 {
+		_, err := w.writer.Write([]byte(","))
+		if err != nil {
+			return err
+		}
+	}
+
+	bytes, err := json.Marshal(project)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.writer.Write(bytes)
+	if err != nil {
+		return err
+	}
+
+	w.count++
+
+	return nil
+}
+
+func (w *jsonExportWriter) end() error {
+	_, err := w.writer.Write([]byte("]"))
+	return err
+}
+
+// Writes one JSON object per line.
+type ndjsonExportWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *ndjsonExportWriter) begin() error {
+	return nil
+}
+
+func (w *ndjsonExportWriter) write(project ProjectExportDto) error {
+	// Encode already ends each object with a new line
+	return w.encoder.Encode(project)
+}
+
+func (w *ndjsonExportWriter) end() error {
+	return nil
+}
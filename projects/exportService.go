@@ -0,0 +1,76 @@
+package projects
+
+import (
+	"context"
+	"github.com/apex/log"
+)
+
+func (s *serviceImpl) ExportProjects(
+	ctx context.Context,
+	options ExportOptions,
+	each func(ProjectExportDto) error,
+) error {
+	logger := log.FromContext(ctx).WithFields(log.Fields{
+		"tags":          options.Tags,
+		"ownerId":       options.OwnerId,
+		"includeHidden": options.IncludeHidden,
+	})
+
+	logger.Debug("Exporting projects")
+
+	query := filterByTags(s.Db.Model(&Project{}), options.Tags)
+
+	if options.OwnerId != 0 {
+		query = query.Where("owner_id = ?", options.OwnerId)
+	}
+
+	if !options.IncludeHidden {
+		query = query.Where("hidden_at IS NULL AND visibility = ?", VisibilityPublic)
+	}
+
+	rows, err := query.Order("created_at desc").Rows()
+	if err != nil {
+		logger.WithError(err).Error("Failed to query for projects to export")
+		return err
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		project := Project{}
+		err = s.Db.ScanRows(rows, &project)
+		if err != nil {
+			logger.WithError(err).Error("Failed to read exported project")
+			return err
+		}
+
+		err = each(ProjectExportDto{
+			Id:               project.ID,
+			Slug:             project.Slug,
+			Name:             project.Name,
+			Tags:             project.Tags,
+			ShortDescription: project.ShortDescription,
+			LongDescription:  project.LongDescription,
+			GithubLink:       project.GithubLink,
+			Visibility:       project.Visibility,
+			Hidden:           project.HiddenAt != nil,
+			CreatedAt:        project.CreatedAt,
+			UpdatedAt:        project.UpdatedAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		count++
+	}
+
+	err = rows.Err()
+	if err != nil {
+		logger.WithError(err).Error("Failed to read exported projects")
+		return err
+	}
+
+	logger.Debugf("Exported %d projects", count)
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package projects
+
+// Status of a row in an import report.
+const (
+	// The row's project was created.
+	ImportStatusCreated = "created"
+	// The row's project would have been created, used in dry runs.
+	ImportStatusValid = "valid"
+	// The row couldn't be parsed or failed validation.
+	ImportStatusInvalid = "invalid"
+	// The row's project duplicates an existing project or an earlier row.
+	ImportStatusDuplicate = "duplicate"
+	// The owner can't create any more projects.
+	ImportStatusQuotaExceeded = "quota-exceeded"
+	// The row's project was created but then rolled back because another
+	// row failed in an atomic import.
+	ImportStatusRolledBack = "rolled-back"
+)
+
+type ImportOptions struct {
+	// Validate the rows and check for duplicates and quotas without
+	// creating any projects.
+	DryRun bool
+
+	// Create the valid rows in a single transaction, so that either all
+	// of them are created or none are. When false, each row is created and
+	// committed in a transaction of its own, and failing rows don't affect
+	// the others.
+	Atomic bool
+}
+
+type ImportRow struct {
+	// Line of the row in the imported file.
+	Line int
+
+	Project NewProjectDto
+
+	// Errors found while parsing the row, by field. Rows with parse
+	// errors are reported as invalid and never created.
+	ParseErrors map[string]string
+}
+
+type ImportRowResultDto struct {
+	Line      int    `json:"line"`
+	Status    string `json:"status"`
+	ProjectId uint   `json:"projectId,omitempty"`
+	Slug      string `json:"slug,omitempty"`
+
+	// Parse and validation errors, by field.
+	Errors map[string]string `json:"errors,omitempty"`
+
+	// The existing projects that this row duplicates.
+	Duplicates []DuplicateCandidateDto `json:"duplicates,omitempty"`
+
+	// The quota that was exceeded, if the status is quota-exceeded.
+	Quota string `json:"quota,omitempty"`
+}
+
+type ImportReportDto struct {
+	DryRun  bool                 `json:"dryRun"`
+	Atomic  bool                 `json:"atomic"`
+	Created int                  `json:"created"`
+	Failed  int                  `json:"failed"`
+	Rows    []ImportRowResultDto `json:"rows"`
+}
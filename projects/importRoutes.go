@@ -0,0 +1,274 @@
+package projects
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/open-collaboration/server/auth"
+	"github.com/open-collaboration/server/users"
+	"github.com/open-collaboration/server/utils"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Maximum amount of rows in an imported file.
+const maxImportRows = 1000
+
+// Maximum size of an imported file, in bytes.
+const maxImportSize = 10 << 20
+
+// Returned when an imported file can't be read at all, as opposed to
+// single rows with invalid data, which are listed in the import report.
+type ImportFileError struct {
+	Line   int
+	Reason string
+}
+
+func (e *ImportFileError) Error() string {
+	return fmt.Sprintf("invalid import file at line %d: %s", e.Line, e.Reason)
+}
+
+type importParams struct {
+	Format string `validate:"oneof=csv ndjson"`
+	Mode   string `validate:"oneof=atomic per-row"`
+}
+
+// @Summary Import projects from a CSV or NDJSON file
+// @Description CSV files must start with a header row. The name, tags, shortDescription, fullDescription,
+// @Description githubLink, visibility, defaultLocale and ignoreNearDuplicates columns are read, others are ignored.
+// @Description Tags are comma separated.
+// @Description NDJSON files have one project per line, in the same format used to create a project.
+// @Description Only users with a verified email can import projects.
+// @Tags projects
+// @Router /projects/import [post]
+// @Param format query string false "csv or ndjson. Defaults to the format of the request's Content-Type."
+// @Param mode query string false "atomic (default) creates all valid rows or none of them, per-row creates each valid row on its own."
+// @Param dryRun query bool false "Check the rows without creating any projects."
+// @Success 200 {object} dtos.ImportReportDto
+func RouteImportProjects(
+	writer http.ResponseWriter,
+	request *http.Request,
+	projectsService Service,
+	usersService users.Service,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	err = usersService.CheckEmailVerified(request.Context(), session.UserId())
+	if err != nil {
+		return err
+	}
+
+	query := request.URL.Query()
+
+	params := importParams{
+		Format: query.Get("format"),
+		Mode:   query.Get("mode"),
+	}
+
+	if params.Format == "" {
+		switch strings.Split(request.Header.Get("Content-Type"), ";")[0] {
+		case "text/csv":
+			params.Format = "csv"
+		case "application/x-ndjson":
+			params.Format = "ndjson"
+		}
+	}
+
+	if params.Mode == "" {
+		params.Mode = "atomic"
+	}
+
+	err = validator.New().Struct(params)
+	if err != nil {
+		return err
+	}
+
+	body := http.MaxBytesReader(writer, request.Body, maxImportSize)
+
+	var rows []ImportRow
+	if params.Format == "csv" {
+		rows, err = readCsvImport(body)
+	} else {
+		rows, err = readNdjsonImport(body)
+	}
+	if err != nil {
+		return err
+	}
+
+	report, err := projectsService.ImportProjects(request.Context(), session.UserId(), rows, ImportOptions{
+		DryRun: query.Get("dryRun") == "true",
+		Atomic: params.Mode == "atomic",
+	})
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusOK, report)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Read the rows of a CSV import. The first line must be a header naming
+// each column.
+func readCsvImport(reader io.Reader) ([]ImportRow, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, &ImportFileError{Line: 1, Reason: "missing header"}
+		}
+
+		return nil, csvImportError(err)
+	}
+
+	columns := map[string]int{}
+	for i, column := range header {
+		columns[strings.TrimSpace(column)] = i
+	}
+
+	// Exported files name the long description "fullDescription", like
+	// the API does.
+	if _, ok := columns["fullDescription"]; !ok {
+		if i, ok := columns["longDescription"]; ok {
+			columns["fullDescription"] = i
+		}
+	}
+
+	field := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []ImportRow
+	for {
+		record, err := csvReader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return nil, csvImportError(err)
+		}
+
+		if len(rows) == maxImportRows {
+			return nil, &ImportFileError{
+				Line:   len(rows) + 2,
+				Reason: fmt.Sprintf("more than %d rows", maxImportRows),
+			}
+		}
+
+		// Rows are numbered as if each took a single line, which holds
+		// unless a quoted field has line breaks.
+		row := ImportRow{
+			Line: len(rows) + 2,
+			Project: NewProjectDto{
+				Name:             field(record, "name"),
+				Tags:             splitImportTags(field(record, "tags")),
+				ShortDescription: field(record, "shortDescription"),
+				LongDescription:  field(record, "fullDescription"),
+				GithubLink:       field(record, "githubLink"),
+				Visibility:       field(record, "visibility"),
+				DefaultLocale:    field(record, "defaultLocale"),
+			},
+		}
+
+		ignoreNearDuplicates := field(record, "ignoreNearDuplicates")
+		if ignoreNearDuplicates != "" {
+			row.Project.IgnoreNearDuplicates, err = strconv.ParseBool(ignoreNearDuplicates)
+			if err != nil {
+				row.ParseErrors = map[string]string{"IgnoreNearDuplicates": "boolean"}
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// Read the rows of an NDJSON import. Blank lines are skipped.
+func readNdjsonImport(reader io.Reader) ([]ImportRow, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportSize)
+
+	var rows []ImportRow
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		if len(rows) == maxImportRows {
+			return nil, &ImportFileError{
+				Line:   line,
+				Reason: fmt.Sprintf("more than %d rows", maxImportRows),
+			}
+		}
+
+		row := ImportRow{Line: line}
+
+		err := json.Unmarshal([]byte(text), &row.Project)
+		if err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				row.ParseErrors = map[string]string{typeErr.Field: "type"}
+			} else {
+				row.ParseErrors = map[string]string{"json": "syntax"}
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, &ImportFileError{Line: line + 1, Reason: err.Error()}
+	}
+
+	return rows, nil
+}
+
+// Convert an error returned while reading a CSV import (a malformed file or a
+// body over the size limit) to an *ImportFileError.
+func csvImportError(err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return &ImportFileError{Line: parseErr.Line, Reason: parseErr.Err.Error()}
+	}
+
+	return &ImportFileError{Reason: err.Error()}
+}
+
+// Split a comma separated list of tags, dropping empty tags.
+func splitImportTags(tags string) []string {
+	split := []string{}
+	for _, tag := range strings.Split(tags, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" {
+			split = append(split, tag)
+		}
+	}
+
+	return split
+}
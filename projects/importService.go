@@ -0,0 +1,220 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"github.com/apex/log"
+	"github.com/go-playground/validator/v10"
+	"github.com/open-collaboration/server/events"
+	"gorm.io/gorm"
+)
+
+// Returned from an import's transaction to roll it back once all rows have
+// been processed, e.g. in dry runs.
+var errRollbackImport = errors.New("import rolled back")
+
+func (s *serviceImpl) ImportProjects(
+	ctx context.Context,
+	ownerId uint,
+	rows []ImportRow,
+	options ImportOptions,
+) (ImportReportDto, error) {
+	logger := log.FromContext(ctx).WithFields(log.Fields{
+		"ownerId": ownerId,
+		"rows":    len(rows),
+		"dryRun":  options.DryRun,
+		"atomic":  options.Atomic,
+	})
+
+	logger.Debug("Importing projects")
+
+	report := ImportReportDto{
+		DryRun: options.DryRun,
+		Atomic: options.Atomic,
+		Rows:   make([]ImportRowResultDto, len(rows)),
+	}
+
+	validate := validator.New()
+	var valid []int
+	for i, row := range rows {
+		result := &report.Rows[i]
+		result.Line = row.Line
+
+		if len(row.ParseErrors) > 0 {
+			result.Status = ImportStatusInvalid
+			result.Errors = row.ParseErrors
+			continue
+		}
+
+		err := validate.Struct(row.Project)
+		if err != nil {
+			var validationErrs validator.ValidationErrors
+			if !errors.As(err, &validationErrs) {
+				return ImportReportDto{}, err
+			}
+
+			result.Status = ImportStatusInvalid
+			result.Errors = map[string]string{}
+			for _, fieldError := range validationErrs {
+				result.Errors[fieldError.StructField()] = fieldError.Tag()
+			}
+			continue
+		}
+
+		valid = append(valid, i)
+	}
+
+	var err error
+	if options.DryRun || options.Atomic {
+		err = s.importInTransaction(ownerId, rows, valid, &report, options)
+	} else {
+		// Each row gets a transaction of its own, so that rows are committed
+		// as soon as they're created and the locks taken by createProject
+		// aren't held for the whole import.
+		for _, i := range valid {
+			var project *Project
+			err = s.Db.Transaction(func(tx *gorm.DB) error {
+				var err error
+				project, err = s.createProject(tx, ownerId, rows[i].Project)
+				return err
+			})
+
+			err = setImportResult(&report.Rows[i], project, err, false)
+			if err != nil {
+				break
+			}
+		}
+	}
+	if err != nil && !errors.Is(err, errRollbackImport) {
+		logger.WithError(err).Error("Failed to import projects")
+		return ImportReportDto{}, err
+	}
+
+	rolledBack := errors.Is(err, errRollbackImport) && !options.DryRun
+	var created []events.Event
+	for i := range report.Rows {
+		result := &report.Rows[i]
+
+		if rolledBack && result.Status == ImportStatusCreated {
+			result.Status = ImportStatusRolledBack
+			result.ProjectId = 0
+			result.Slug = ""
+		}
+
+		switch result.Status {
+		case ImportStatusCreated:
+			report.Created++
+			created = append(created, events.Event{
+				Type:      events.ProjectCreated,
+				ProjectId: result.ProjectId,
+				ActorId:   ownerId,
+			})
+		case ImportStatusInvalid, ImportStatusDuplicate, ImportStatusQuotaExceeded:
+			report.Failed++
+		}
+	}
+
+	if len(created) > 0 {
+		s.committed(ctx, created...)
+	}
+
+	logger.Debugf("Imported %d projects, %d rows failed", report.Created, report.Failed)
+
+	return report, nil
+}
+
+// Create the valid rows of a dry run or atomic import in one transaction,
+// each row in a nested transaction of its own. A failing row only rolls back
+// its nested transaction, so later rows still get checked (and see the
+// projects created by earlier rows when looking for duplicates). The outer
+// transaction is rolled back in dry runs and in atomic imports with failing
+// rows, in which case errRollbackImport is returned.
+func (s *serviceImpl) importInTransaction(
+	ownerId uint,
+	rows []ImportRow,
+	valid []int,
+	report *ImportReportDto,
+	options ImportOptions,
+) error {
+	return s.Db.Transaction(func(tx *gorm.DB) error {
+		failed := false
+		for _, i := range valid {
+			var project *Project
+			err := tx.Transaction(func(rowTx *gorm.DB) error {
+				var err error
+				project, err = s.createProject(rowTx, ownerId, rows[i].Project)
+				return err
+			})
+
+			err = setImportResult(&report.Rows[i], project, err, options.DryRun)
+			if err != nil {
+				return err
+			}
+
+			if report.Rows[i].Status != ImportStatusCreated && report.Rows[i].Status != ImportStatusValid {
+				failed = true
+			}
+		}
+
+		if options.DryRun || (options.Atomic && failed) {
+			return errRollbackImport
+		}
+
+		return nil
+	})
+}
+
+// Fill in a row's result with the outcome of creating its project.
+// Returns err if it's unexpected, i.e. it should stop the import.
+func setImportResult(result *ImportRowResultDto, project *Project, err error, dryRun bool) error {
+	if err != nil {
+		if !setImportFailure(result, err) {
+			return err
+		}
+
+		return nil
+	}
+
+	if dryRun {
+		result.Status = ImportStatusValid
+	} else {
+		result.Status = ImportStatusCreated
+		result.ProjectId = project.ID
+		result.Slug = project.Slug
+	}
+
+	return nil
+}
+
+// Fill in a row's result with an error returned by createProject.
+// Returns false if the error is unexpected, i.e. it should stop the import.
+func setImportFailure(result *ImportRowResultDto, err error) bool {
+	var quotaErr *QuotaExceededError
+	var duplicateErr *DuplicateProjectError
+	var nearDuplicateErr *NearDuplicateError
+
+	switch {
+	case errors.As(err, &quotaErr):
+		result.Status = ImportStatusQuotaExceeded
+		result.Quota = quotaErr.Quota
+
+	case errors.As(err, &duplicateErr):
+		result.Status = ImportStatusDuplicate
+		if duplicateErr.ProjectId != 0 {
+			result.Duplicates = []DuplicateCandidateDto{{
+				Id:         duplicateErr.ProjectId,
+				Slug:       duplicateErr.Slug,
+				Similarity: 1,
+			}}
+		}
+
+	case errors.As(err, &nearDuplicateErr):
+		result.Status = ImportStatusDuplicate
+		result.Duplicates = nearDuplicateErr.Candidates
+
+	default:
+		return false
+	}
+
+	return true
+}
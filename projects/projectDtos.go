@@ -1,6 +1,10 @@
 package projects
 
-import "github.com/lib/pq"
+import (
+	"github.com/lib/pq"
+	"github.com/open-collaboration/server/users"
+	"time"
+)
 
 type NewProjectDto struct {
 	Name             string   `json:"name" validate:"required,min=4,max=32"`
@@ -8,23 +12,94 @@ type NewProjectDto struct {
 	LongDescription  string   `json:"longDescription" validate:"required,min=200,max=10000"`
 	ShortDescription string   `json:"shortDescription" validate:"required,min=10,max=200"`
 	GithubLink       string   `json:"githubLink" validate:"required"`
+
+	// public, unlisted or private. New projects are public by default and
+	// updates without a visibility keep the current one.
+	Visibility string `json:"visibility" validate:"omitempty,oneof=public unlisted private"`
+
+	// Locale of the name and descriptions, "en" by default. Updates without
+	// a default locale keep the current one.
+	DefaultLocale string `json:"defaultLocale" validate:"omitempty,max=35,bcp47_language_tag"`
+
+	// Create the project even if it looks like existing projects.
+	IgnoreNearDuplicates bool `json:"ignoreNearDuplicates"`
 }
 
 type ProjectSummaryDto struct {
 	Id               uint           `json:"id" validate:""`
+	Slug             string         `json:"slug"`
 	Name             string         `json:"name" validate:"required"`
 	Tags             pq.StringArray `json:"tags" validate:"required" gorm:"type: TEXT[]" swaggertype:"array,string"`
 	ShortDescription string         `json:"shortDescription" validate:"required"`
 	Skills           pq.StringArray `json:"skills" validate:"required" gorm:"type: TEXT[]" swaggertype:"array,string"`
+
+	// Amount of open tasks labelled "good first task".
+	GoodFirstTasks int `json:"goodFirstTasks"`
+
+	// Locale of the name and short description.
+	Locale       string          `json:"locale"`
+	Translations TranslationsDto `json:"translations" gorm:"-"`
 }
 
 type ProjectDto struct {
+	Id               uint             `json:"id"`
+	Slug             string           `json:"slug"`
+	ShortLink        string           `json:"shortLink"`
+	Name             string           `json:"name"`
+	Tags             pq.StringArray   `json:"tags" swaggertype:"array,string"`
+	ShortDescription string           `json:"shortDescription"`
+	LongDescription  string           `json:"fullDescription"`
+	GithubLink       string           `json:"githubLink"`
+	Visibility       string           `json:"visibility"`
+	Roles            []RoleDto        `json:"roles"`
+	Collaboration    CollaborationDto `json:"collaboration"`
+
+	// Locale of the name and descriptions.
+	Locale       string          `json:"locale"`
+	Translations TranslationsDto `json:"translations"`
+}
+
+// The locales a project's name and descriptions are available in.
+type TranslationsDto struct {
+	DefaultLocale string `json:"defaultLocale"`
+
+	// All available locales, starting with the default one.
+	Locales []string `json:"locales"`
+}
+
+type TranslationDto struct {
+	Locale           string `json:"locale"`
+	Name             string `json:"name" validate:"required,min=4,max=32"`
+	ShortDescription string `json:"shortDescription" validate:"required,min=10,max=200"`
+	LongDescription  string `json:"longDescription" validate:"required,min=200,max=10000"`
+}
+
+type RecentProjectDto struct {
 	Id               uint           `json:"id"`
+	Slug             string         `json:"slug"`
 	Name             string         `json:"name"`
-	Tags             pq.StringArray `json:"tags" swaggertype:"array,string"`
+	Tags             pq.StringArray `json:"tags" gorm:"type: TEXT[]" swaggertype:"array,string"`
 	ShortDescription string         `json:"shortDescription"`
-	LongDescription  string         `json:"fullDescription"`
-	GithubLink       string         `json:"githubLink"`
+	CreatedAt        time.Time      `json:"createdAt"`
+	UpdatedAt        time.Time      `json:"updatedAt"`
+}
+
+// A project a user is part of, as listed in the user's profile.
+type MemberProjectDto struct {
+	Id               uint   `json:"id"`
+	Slug             string `json:"slug"`
+	Name             string `json:"name"`
+	ShortDescription string `json:"shortDescription"`
+
+	// The user's role in the project's team, one of the MemberRole constants.
+	Role string `json:"role"`
+}
+
+// Enough of a project to link to it, e.g. from a sitemap.
+type ProjectLinkDto struct {
+	Id        uint      `json:"id"`
+	Slug      string    `json:"slug"`
+	UpdatedAt time.Time `json:"updatedAt"`
 }
 
 type ListProjectsParamsDto struct {
@@ -33,3 +108,58 @@ type ListProjectsParamsDto struct {
 	Tags       []string `form:"tags"`
 	Skills     []string `form:"skills"`
 }
+
+// How a project's team wants to work with its contributors.
+type CollaborationDto struct {
+	// Hours a week the project expects from contributors, 0 if not said.
+	WeeklyHours int `json:"weeklyHours" validate:"min=0,max=168"`
+
+	// IANA time zone of WorkingHours.
+	Timezone string `json:"timezone" validate:"omitempty,timezone,ne=Local"`
+
+	// The team's daily working hours, nil if not said.
+	WorkingHours *users.WorkingHoursDto `json:"workingHours"`
+
+	// Least hours a day contributors should share with WorkingHours.
+	MinOverlapHours int `json:"minOverlapHours" validate:"min=0,max=24"`
+}
+
+type NewRoleDto struct {
+	Title       string   `json:"title" validate:"required,min=2,max=64"`
+	Description string   `json:"description" validate:"max=2000"`
+	Skills      []string `json:"skills" validate:"max=10,dive,min=1,max=40"`
+}
+
+type RoleDto struct {
+	Id          uint           `json:"id"`
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	Skills      pq.StringArray `json:"skills" swaggertype:"array,string"`
+	Open        bool           `json:"open"`
+}
+
+type ExportOptions struct {
+	Tags []string
+
+	// Only export projects owned by this user. 0 exports projects of
+	// all users.
+	OwnerId uint
+
+	// Export projects hidden by moderators, and unlisted and private
+	// projects, too.
+	IncludeHidden bool
+}
+
+type ProjectExportDto struct {
+	Id               uint           `json:"id"`
+	Slug             string         `json:"slug"`
+	Name             string         `json:"name"`
+	Tags             pq.StringArray `json:"tags" swaggertype:"array,string"`
+	ShortDescription string         `json:"shortDescription"`
+	LongDescription  string         `json:"fullDescription"`
+	GithubLink       string         `json:"githubLink"`
+	Visibility       string         `json:"visibility"`
+	Hidden           bool           `json:"hidden"`
+	CreatedAt        time.Time      `json:"createdAt"`
+	UpdatedAt        time.Time      `json:"updatedAt"`
+}
@@ -3,6 +3,7 @@ package projects
 import (
 	"github.com/lib/pq"
 	"gorm.io/gorm"
+	"time"
 )
 
 type Project struct {
@@ -13,4 +14,90 @@ type Project struct {
 	LongDescription  string
 	ShortDescription string
 	GithubLink       string
+
+	// Locale of Name, ShortDescription and LongDescription. Other locales
+	// are kept in ProjectTranslation.
+	DefaultLocale string `gorm:"default: en"`
+
+	// GithubLink normalized with utils.NormalizeRepoLink, used to
+	// find duplicate projects.
+	GithubLinkNormalized string
+
+	// Unique URL friendly identifier derived from the project's name.
+	// Old slugs are kept in ProjectSlugRedirect.
+	Slug string
+
+	// Unique number used to build the project's short link. It's
+	// generated by the database.
+	LinkUid uint `gorm:"autoIncrement"`
+
+	// Id of the user that owns the project. Projects created before
+	// ownership was tracked have an OwnerId of 0.
+	OwnerId uint
+
+	// Id of the user that created the project. Unlike OwnerId it doesn't
+	// change when the project is transferred.
+	CreatedBy uint
+
+	// Who can see the project, one of the Visibility constants.
+	Visibility string `gorm:"default: public"`
+
+	// Set when the project is hidden by the moderation team. Hidden
+	// projects are not listed and can't be fetched.
+	HiddenAt *time.Time
+
+	// Hours a week the project expects from its contributors, 0 if not
+	// said.
+	WeeklyHours int
+
+	// IANA time zone of the team's working hours.
+	Timezone string
+
+	// The team's daily working hours in Timezone, in minutes after
+	// midnight. Nil if not said.
+	WorkStart *int
+	WorkEnd   *int
+
+	// Least hours a day contributors should share with the team's working
+	// hours.
+	MinOverlapHours int
+}
+
+const (
+	// Listed and visible to everyone.
+	VisibilityPublic = "public"
+	// Visible to everyone who has its link, but not listed.
+	VisibilityUnlisted = "unlisted"
+	// Only visible to the project's team.
+	VisibilityPrivate = "private"
+)
+
+const (
+	MemberRoleOwner      = "owner"
+	MemberRoleMaintainer = "maintainer"
+	MemberRoleMember     = "member"
+)
+
+// A user that is part of a project's team. The project's owner is
+// always a member with the MemberRoleOwner role.
+type ProjectMember struct {
+	gorm.Model
+
+	ProjectId uint
+	UserId    uint
+	Role      string
+}
+
+// A position a project is looking to fill. A project with at least one
+// open role is recruiting.
+type ProjectRole struct {
+	gorm.Model
+
+	ProjectId   uint
+	Title       string
+	Description string
+	Skills      pq.StringArray `gorm:"type: TEXT[]"`
+
+	// Set when the role is filled or no longer needed.
+	ClosedAt *time.Time
 }
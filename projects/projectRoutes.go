@@ -2,21 +2,21 @@ package projects
 
 import (
 	"errors"
-	"fmt"
 	"github.com/apex/log"
 	"github.com/gorilla/mux"
 	"github.com/lib/pq"
 	"github.com/open-collaboration/server/auth"
+	"github.com/open-collaboration/server/users"
 	"github.com/open-collaboration/server/utils"
 	"net/http"
 	"strconv"
-	"strings"
 )
 
-var ErrInvalidParam = errors.New("invalid parameter")
-var ErrMissingParam = errors.New("missing parameter")
+var ErrInvalidParam = utils.ErrInvalidParam
+var ErrMissingParam = utils.ErrMissingParam
 
 // @Summary Create a project
+// @Description Only users with a verified email can create projects.
 // @Tags projects
 // @Router /projects [post]
 // @Param project body dtos.NewProjectDto true "Project data"
@@ -25,14 +25,17 @@ func RouteCreateProject(
 	writer http.ResponseWriter,
 	request *http.Request,
 	projectsService Service,
+	usersService users.Service,
 ) error {
-	_, err := auth.CheckSession(request)
+	session, err := auth.CheckSession(request)
 	if err != nil {
 		return err
 	}
 
-	// TODO: check if user already owns a project. If he/she does,
-	//	return an error.
+	err = usersService.CheckEmailVerified(request.Context(), session.UserId())
+	if err != nil {
+		return err
+	}
 
 	dto := NewProjectDto{}
 	err = utils.ReadJson(request.Context(), request, &dto)
@@ -40,7 +43,7 @@ func RouteCreateProject(
 		return err
 	}
 
-	createdProject, err := projectsService.CreateProject(dto)
+	createdProject, err := projectsService.CreateProject(request.Context(), session.UserId(), dto)
 	if err != nil {
 		return err
 	}
@@ -64,7 +67,7 @@ func RouteUpdateProject(
 ) error {
 	logger := log.FromContext(request.Context())
 
-	_, err := auth.CheckSession(request)
+	session, err := auth.CheckSession(request)
 	if err != nil {
 		return err
 	}
@@ -99,7 +102,14 @@ func RouteUpdateProject(
 		return err
 	}
 
-	// TODO: check if the user owns the project
+	role, err := projectsService.GetMemberRole(request.Context(), projectId, session.UserId())
+	if err != nil {
+		return err
+	}
+
+	if role != MemberRoleOwner && role != MemberRoleMaintainer {
+		return auth.ErrForbidden
+	}
 
 	project := NewProjectDto{
 		Name:             dto.Name,
@@ -107,8 +117,8 @@ func RouteUpdateProject(
 		ShortDescription: dto.ShortDescription,
 		LongDescription:  dto.LongDescription,
 		GithubLink:       dto.GithubLink,
+		Visibility:       dto.Visibility,
 	}
-	fmt.Printf("%#v", project)
 
 	err = projectsService.UpdateProject(projectId, project)
 	if err != nil {
@@ -126,19 +136,22 @@ func RouteUpdateProject(
 // @Router /projects [get]
 // @Param pageSize query int false "Maximum amount of projects in the response. Default is 20, max is 20."
 // @Param pageOffset query int false "Response page number. If pageSize is 20 and pageOffset is 2, the first 40 projects will be skipped."
+// @Param overlapWithMe query bool false "Only list projects whose working hours overlap yours today. Needs a session, and working hours in your directory settings."
+// @Param minOverlapHours query int false "Least hours of overlap with overlapWithMe. Projects can ask for more."
+// @Param lang query string false "Preferred locales, e.g. pt-BR,pt. Takes precedence over Accept-Language."
 // @Success 200 {object} dtos.ProjectSummaryDto.
-func RouteListProjects(writer http.ResponseWriter, request *http.Request, projectsService Service) error {
+func RouteListProjects(
+	writer http.ResponseWriter,
+	request *http.Request,
+	projectsService Service,
+	usersService users.Service,
+) error {
 	// TODO: move hardcoded maximum and default page size values to
 	// 	an env variable
 	pageSize, _ := utils.IntFromQuery(request, "pageSize", 20)
 	pageOffset, _ := utils.IntFromQuery(request, "pageOffset", 0)
 
-	var tags []string
-	if len(request.URL.Query()["tags"]) > 0 {
-		println(len(request.URL.Query()["tags"]))
-		tagsRaw := strings.Join(request.URL.Query()["tags"], ",")
-		tags = strings.Split(tagsRaw, ",")
-	}
+	tags := utils.ListFromQuery(request, "tags")
 
 	if pageSize < 1 || pageSize > 20 {
 		pageSize = 20
@@ -148,7 +161,35 @@ func RouteListProjects(writer http.ResponseWriter, request *http.Request, projec
 		pageOffset = 0
 	}
 
-	projectSummaries, err := projectsService.ListProjects(request.Context(), uint(pageSize), uint(pageOffset), tags, []string{})
+	var overlap *users.OverlapFilter
+	if request.URL.Query().Get("overlapWithMe") == "true" {
+		session, err := auth.CheckSession(request)
+		if err != nil {
+			return err
+		}
+
+		user, err := usersService.GetUser(request.Context(), session.UserId())
+		if err != nil {
+			return err
+		}
+
+		minOverlapHours, _ := utils.IntFromQuery(request, "minOverlapHours", 0)
+
+		overlap, err = user.OverlapFilter(minOverlapHours * 60)
+		if err != nil {
+			return err
+		}
+	}
+
+	projectSummaries, err := projectsService.ListProjects(
+		request.Context(),
+		uint(pageSize),
+		uint(pageOffset),
+		tags,
+		[]string{},
+		overlap,
+		utils.LocalesFromRequest(request),
+	)
 	if err != nil {
 		return err
 	}
@@ -169,6 +210,7 @@ func RouteListProjects(writer http.ResponseWriter, request *http.Request, projec
 // @Tags projects
 // @Router /projects/{id} [get]
 // @Param id path int true "The project ID"
+// @Param lang query string false "Preferred locales, e.g. pt-BR,pt. Takes precedence over Accept-Language."
 // @Success 200 {object} dtos.ProjectDto.
 func RouteGetProject(writer http.ResponseWriter, request *http.Request, projectsService Service) error {
 	var projectId uint
@@ -182,7 +224,7 @@ func RouteGetProject(writer http.ResponseWriter, request *http.Request, projects
 		projectId = uint(id)
 	}
 
-	dto, err := projectsService.GetProject(request.Context(), projectId)
+	dto, err := projectsService.GetProject(request.Context(), projectId, utils.LocalesFromRequest(request))
 	if err != nil {
 		if errors.Is(err, ErrProjectNotFound) {
 			writer.WriteHeader(404)
@@ -199,3 +241,185 @@ func RouteGetProject(writer http.ResponseWriter, request *http.Request, projects
 
 	return nil
 }
+
+// @Summary Open a role in a project
+// @Tags projects
+// @Router /projects/{projectId}/roles [post]
+// @Param projectId path int true "The project ID"
+// @Param role body dtos.NewRoleDto true "Role data"
+// @Success 201 {object} dtos.RoleDto
+func RouteCreateRole(
+	writer http.ResponseWriter,
+	request *http.Request,
+	projectsService Service,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	projectId, err := utils.UintFromVars(request, "projectId")
+	if err != nil {
+		return err
+	}
+
+	dto := NewRoleDto{}
+	err = utils.ReadJson(request.Context(), request, &dto)
+	if err != nil {
+		return err
+	}
+
+	role, err := projectsService.CreateRole(request.Context(), session.UserId(), projectId, dto)
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusCreated, role)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Close a role in a project
+// @Tags projects
+// @Router /projects/{projectId}/roles/{roleId}/close [post]
+// @Param projectId path int true "The project ID"
+// @Param roleId path int true "The role ID"
+// @Success 204
+func RouteCloseRole(
+	writer http.ResponseWriter,
+	request *http.Request,
+	projectsService Service,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	projectId, err := utils.UintFromVars(request, "projectId")
+	if err != nil {
+		return err
+	}
+
+	roleId, err := utils.UintFromVars(request, "roleId")
+	if err != nil {
+		return err
+	}
+
+	err = projectsService.CloseRole(request.Context(), session.UserId(), projectId, roleId)
+	if err != nil {
+		return err
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// @Summary Set a project's commitment and working hours
+// @Tags projects
+// @Router /projects/{projectId}/collaboration [put]
+// @Param projectId path int true "The project ID"
+// @Param collaboration body dtos.CollaborationDto true "The project's collaboration preferences"
+// @Success 200 {object} dtos.CollaborationDto
+func RouteSetCollaboration(
+	writer http.ResponseWriter,
+	request *http.Request,
+	projectsService Service,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	projectId, err := utils.UintFromVars(request, "projectId")
+	if err != nil {
+		return err
+	}
+
+	dto := CollaborationDto{}
+	err = utils.ReadJson(request.Context(), request, &dto)
+	if err != nil {
+		return err
+	}
+
+	collaboration, err := projectsService.SetCollaboration(request.Context(), session.UserId(), projectId, dto)
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusOK, collaboration)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Get project by slug
+// @Tags projects
+// @Router /projects/by-slug/{slug} [get]
+// @Param slug path string true "The project slug"
+// @Param lang query string false "Preferred locales, e.g. pt-BR,pt. Takes precedence over Accept-Language."
+// @Success 200 {object} dtos.ProjectDto
+// @Success 301 "The slug is an old slug of the project, Location points to the current one"
+func RouteGetProjectBySlug(writer http.ResponseWriter, request *http.Request, projectsService Service) error {
+	slug := mux.Vars(request)["slug"]
+
+	dto, err := projectsService.GetProjectBySlug(request.Context(), slug, utils.LocalesFromRequest(request))
+	if err != nil {
+		var slugMoved *SlugMovedError
+		if errors.As(err, &slugMoved) {
+			location := "/projects/by-slug/" + slugMoved.Slug
+			if request.URL.RawQuery != "" {
+				location += "?" + request.URL.RawQuery
+			}
+
+			http.Redirect(writer, request, location, http.StatusMovedPermanently)
+			return nil
+		} else if errors.Is(err, ErrProjectNotFound) {
+			writer.WriteHeader(404)
+			return nil
+		} else {
+			return err
+		}
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusOK, dto)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Follow a project's short link
+// @Tags projects
+// @Router /s/{code} [get]
+// @Param code path string true "The short link code"
+// @Success 302 "Location points to the project"
+func RouteFollowShortLink(writer http.ResponseWriter, request *http.Request, projectsService Service) error {
+	linkUid, ok := utils.DecodeBase62(mux.Vars(request)["code"])
+	if !ok {
+		writer.WriteHeader(404)
+		return nil
+	}
+
+	slug, err := projectsService.GetSlugByLinkUid(request.Context(), uint(linkUid))
+	if err != nil {
+		if errors.Is(err, ErrProjectNotFound) {
+			writer.WriteHeader(404)
+			return nil
+		} else {
+			return err
+		}
+	}
+
+	// Short links are stable but the slug they point to isn't, so
+	// the redirect is temporary.
+	http.Redirect(writer, request, "/projects/by-slug/"+slug, http.StatusFound)
+
+	return nil
+}
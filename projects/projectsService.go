@@ -6,21 +6,166 @@ import (
 	"github.com/apex/log"
 	"github.com/go-playground/validator/v10"
 	"github.com/lib/pq"
+	"github.com/open-collaboration/server/auth"
+	"github.com/open-collaboration/server/events"
+	"github.com/open-collaboration/server/users"
+	"github.com/open-collaboration/server/utils"
+	"golang.org/x/text/language"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
 )
 
 type Service interface {
-	CreateProject(newProject NewProjectDto) (*Project, error)
+	// Create a project owned by the user ownerId.
+	// Returns a *QuotaExceededError if the user already owns too many projects
+	// or created too many projects today, a *DuplicateProjectError if another
+	// project has the same repository link and a *NearDuplicateError if the
+	// project's name or short description are too similar to other projects'
+	// (unless newProject.IgnoreNearDuplicates is set).
+	CreateProject(ctx context.Context, ownerId uint, newProject NewProjectDto) (*Project, error)
+
+	// Create many projects owned by the user ownerId. Each row goes through
+	// the same validation, quota and duplicate checks as CreateProject, and
+	// its outcome is listed in the returned report. Rows that fail never
+	// stop the import, only unexpected errors do. Rows created before an
+	// unexpected error stay created unless the import is atomic.
+	ImportProjects(ctx context.Context, ownerId uint, rows []ImportRow, options ImportOptions) (ImportReportDto, error)
+
+	// Update a project's data.
+	// Returns ErrProjectNotFound if the project can't be found and a
+	// *DuplicateProjectError if another project has the same repository link.
 	UpdateProject(projectId uint, projectData NewProjectDto) error
 
 	// Get the given project's summary
 	GetProjectSummary(project *Project) ProjectSummaryDto
 
-	// Get a project by id.
+	// Get a project by its slug. Hidden projects, and private projects of
+	// teams the user in ctx is not part of, are treated as if they didn't
+	// exist. The name and descriptions are in the available locale that
+	// best matches `locales` (most preferred first), or in the project's
+	// default locale if none match.
+	// Returns ErrProjectNotFound if the project can't be found and a
+	// *SlugMovedError if slug is an old slug of the project.
+	GetProjectBySlug(ctx context.Context, slug string, locales []language.Tag) (ProjectDto, error)
+
+	// Get the current slug of the project with the given link uid, used
+	// to resolve short links. Visibility is enforced as in GetProjectBySlug.
 	// Returns ErrProjectNotFound if the project can't be found.
-	GetProject(ctx context.Context, projectId uint) (ProjectDto, error)
+	GetSlugByLinkUid(ctx context.Context, linkUid uint) (string, error)
+
+	// Get a project by id. Visibility and locales work as in GetProjectBySlug.
+	// Returns ErrProjectNotFound if the project can't be found.
+	GetProject(ctx context.Context, projectId uint, locales []language.Tag) (ProjectDto, error)
+
+	// Add or replace the translation of a project's name and descriptions
+	// in a locale. Only the project's owner and maintainers can translate it.
+	// Returns auth.ErrForbidden if userId can't manage the project,
+	// utils.ErrInvalidLocale if the locale is not a BCP 47 tag and
+	// ErrDefaultLocaleTranslation if it's the project's default locale.
+	SetTranslation(ctx context.Context, userId uint, projectId uint, locale string, translation TranslationDto) (TranslationDto, error)
+
+	// Remove a project's translation in a locale.
+	// Returns auth.ErrForbidden if userId can't manage the project and
+	// ErrTranslationNotFound if the project has no translation in the locale.
+	DeleteTranslation(ctx context.Context, userId uint, projectId uint, locale string) error
+
+	// Check whether the user in ctx (if any) can see a project, with the same
+	// rules as GetProject.
+	// Returns ErrProjectNotFound if the project can't be found or the user
+	// can't see it.
+	CheckCanView(ctx context.Context, projectId uint) error
+
+	// Call `each` with every project that matches the export options, newest
+	// to oldest. Projects are read from the database one at a time, so that
+	// large exports don't have to fit in memory. If `each` returns an error
+	// the export stops and the error is returned.
+	ExportProjects(ctx context.Context, options ExportOptions, each func(ProjectExportDto) error) error
+
+	// List the most recently created or updated projects, most recent first.
+	// The projects are filtered in the same way as in ListProjects.
+	ListRecentProjects(ctx context.Context, tags []string, limit uint) ([]RecentProjectDto, error)
+
+	// List the projects that ListProjects would list, ordered by id, in pages
+	// of pageSize projects.
+	ListProjectLinks(ctx context.Context, page uint, pageSize uint) ([]ProjectLinkDto, error)
+
+	// Get the time of the latest update of each page of ListProjectLinks, so
+	// that the amount of pages is the length of the returned slice.
+	ListProjectLinkPages(ctx context.Context, pageSize uint) ([]time.Time, error)
+
+	// Get the projects a user owns, the user's memberships, the tasks
+	// assigned to the user and the user's project transfers and role
+	// suggestions. Hidden and private projects are included.
+	ExportUserData(ctx context.Context, userId uint) (UserProjectsExportDto, error)
+
+	// Cancel a user's pending project transfers, delete the user's role
+	// suggestions and remove the user's quota override, before the user's
+	// account is deleted. The user's projects, memberships and tasks are
+	// kept, they are shared with the projects' teams.
+	ForgetUser(ctx context.Context, userId uint) error
+
+	// Get the id of the user that owns a project. Unlike GetProject, this also
+	// works for hidden projects.
+	// Returns ErrProjectNotFound if the project can't be found.
+	GetProjectOwner(ctx context.Context, projectId uint) (uint, error)
+
+	// List the projects a user owns or is a member of, newest first. Only
+	// public projects and projects the user in ctx is part of are listed.
+	ListMemberProjects(ctx context.Context, userId uint) ([]MemberProjectDto, error)
+
+	// Whether two users are or were on a project's team together. Projects
+	// that were hidden still count.
+	SharesProject(ctx context.Context, userId uint, otherUserId uint) (bool, error)
+
+	// Get the role of a user in a project's team. Returns an empty string
+	// if the user is not a member of the project.
+	GetMemberRole(ctx context.Context, projectId uint, userId uint) (string, error)
+
+	// Hide or unhide a project.
+	// Returns ErrProjectNotFound if the project can't be found.
+	SetProjectHidden(ctx context.Context, projectId uint, hidden bool) error
+
+	// Open a role in a project. Only the project's owner and maintainers can
+	// open roles.
+	// Returns auth.ErrForbidden if userId can't manage the project and a
+	// *QuotaExceededError if the project wasn't recruiting and the owner
+	// already has too many recruiting projects.
+	CreateRole(ctx context.Context, userId uint, projectId uint, newRole NewRoleDto) (RoleDto, error)
+
+	// Close a role, e.g. because it was filled.
+	// Returns auth.ErrForbidden if userId can't manage the project and
+	// ErrRoleNotFound if the role can't be found.
+	CloseRole(ctx context.Context, userId uint, projectId uint, roleId uint) error
 
-	// List all projects ordered by creation date, newest to oldest.
+	// Set the commitment a project expects from its contributors and its
+	// team's working hours. Only the project's owner and maintainers can
+	// change them.
+	// Returns auth.ErrForbidden if userId can't manage the project and
+	// users.ErrMissingTimezone if working hours are set without a time zone.
+	SetCollaboration(ctx context.Context, userId uint, projectId uint, collaboration CollaborationDto) (CollaborationDto, error)
+
+	// Get a user's quota policy (the default policy with the user's overrides
+	// applied) along with the user's current usage.
+	GetQuota(ctx context.Context, userId uint) (QuotaDto, error)
+
+	// Override the default quota policy for a user. Nil limits use the
+	// default policy.
+	SetQuotaOverride(ctx context.Context, userId uint, override QuotaOverrideDto) error
+
+	// Check whether a user has room for one more project in each of the
+	// given quotas (QuotaOwnedProjects, QuotaRecruitingProjects or
+	// QuotaProjectsPerDay). The check runs on db, which allows callers to
+	// pass the transaction that gives the user the project: the user's
+	// quotas stay locked until it ends, so concurrent changes can't both
+	// pass the check. Checks outside of a transaction are only good for
+	// reporting.
+	// Returns a *QuotaExceededError if any of the quotas is exhausted.
+	CheckQuota(ctx context.Context, db *gorm.DB, userId uint, quotas ...string) error
+
+	// List all projects ordered by creation date, newest to oldest. Only
+	// public projects that aren't hidden are listed, and projects owned by
+	// users the user in ctx blocked or muted are left out.
 	//
 	// Results are returned in "pages". A page is determined by the pageSize and
 	// pageOffset parameters. pageSize determines the maximum amount of projects
@@ -28,52 +173,170 @@ type Service interface {
 	// to skip. For example: if pageSize is 20 and pageOffset is 3, a maximum of 20
 	// projects will be returned and 60 (3x20) projects will be skipped.
 	//
+	// Names and short descriptions are translated as in GetProjectBySlug.
+	//
 	// You can also filter the results by tags and skills. If tags is specified
 	// (non-nil and non-empty), any projects that have at least one of the specified
 	// tags will be returned. If skills is specified (non-nil and non-empty), any projects
 	// that have at least one role that require at least one of the specified skills will
-	// be returned.
+	// be returned. If overlap is not nil, only projects whose working hours overlap
+	// the given ones today by the filter's and the project's minimum are returned.
 	ListProjects(
 		ctx context.Context,
 		pageSize uint,
 		pageOffset uint,
 		tags []string,
 		skills []string,
+		overlap *users.OverlapFilter,
+		locales []language.Tag,
 	) ([]ProjectSummaryDto, error)
 }
 
-func NewService(db *gorm.DB) Service {
-	return &serviceImpl{Db: db}
+func NewService(db *gorm.DB, defaultQuota QuotaPolicy, publisher events.Publisher, listener events.Listener) Service {
+	return &serviceImpl{
+		Db:           db,
+		DefaultQuota: defaultQuota,
+		Events:       publisher,
+		Listener:     listener,
+	}
 }
 
 type serviceImpl struct {
 	Db *gorm.DB
+
+	// Quota policy of users without overrides.
+	DefaultQuota QuotaPolicy
+
+	Events events.Publisher
+
+	// Notified of project events once they're committed, nil if nobody
+	// listens.
+	Listener events.Listener
 }
 
 var ErrProjectNotFound = errors.New("project not found")
+var ErrRoleNotFound = errors.New("role not found")
 
-func (s *serviceImpl) CreateProject(newProject NewProjectDto) (*Project, error) {
+func (s *serviceImpl) CreateProject(ctx context.Context, ownerId uint, newProject NewProjectDto) (*Project, error) {
 	err := validator.New().Struct(newProject)
 	if err != nil {
 		return nil, err
 	}
 
+	var project *Project
+	err = s.Db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		project, err = s.createProject(tx, ownerId, newProject)
+		return err
+	})
+	if err != nil {
+		if !isCreateProjectError(err) {
+			log.FromContext(ctx).WithError(err).Error("Failed to create project")
+		}
+
+		return nil, err
+	}
+
+	s.committed(ctx, events.Event{
+		Type:      events.ProjectCreated,
+		ProjectId: project.ID,
+		ActorId:   ownerId,
+	})
+
+	return project, nil
+}
+
+// Tell the listener about events whose changes were committed.
+func (s *serviceImpl) committed(ctx context.Context, committed ...events.Event) {
+	if s.Listener != nil {
+		s.Listener.Committed(ctx, committed...)
+	}
+}
+
+// Create a project after checking the owner's quotas and looking for
+// duplicates. newProject must already be validated.
+// Must be called inside a transaction.
+func (s *serviceImpl) createProject(tx *gorm.DB, ownerId uint, newProject NewProjectDto) (*Project, error) {
+	if newProject.Visibility == "" {
+		newProject.Visibility = VisibilityPublic
+	}
+
+	if newProject.DefaultLocale == "" {
+		newProject.DefaultLocale = DefaultLocale
+	}
+
 	project := Project{
 		Name:             newProject.Name,
 		Tags:             newProject.Tags,
 		LongDescription:  newProject.LongDescription,
 		ShortDescription: newProject.ShortDescription,
 		GithubLink:       newProject.GithubLink,
+		Visibility:       newProject.Visibility,
+		DefaultLocale:    language.Make(newProject.DefaultLocale).String(),
+		OwnerId:          ownerId,
+		CreatedBy:        ownerId,
+
+		GithubLinkNormalized: utils.NormalizeRepoLink(newProject.GithubLink),
 	}
 
-	result := s.Db.Create(&project)
-	if result.Error != nil {
-		return nil, result.Error
+	err := s.checkQuota(tx, ownerId, QuotaOwnedProjects, QuotaProjectsPerDay)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkRepoLinkDuplicate(tx, project.GithubLinkNormalized, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if !newProject.IgnoreNearDuplicates {
+		candidates, err := findNearDuplicates(tx, project.Name, project.ShortDescription)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(candidates) > 0 {
+			return nil, &NearDuplicateError{Candidates: candidates}
+		}
+	}
+
+	project.Slug, err = assignSlug(tx, project.Name, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Create(&project).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = setMemberRole(tx, project.ID, ownerId, MemberRoleOwner)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Events.Publish(context.Background(), tx, events.Event{
+		Type:      events.ProjectCreated,
+		ProjectId: project.ID,
+		ActorId:   ownerId,
+		Data:      s.GetProjectSummary(&project),
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return &project, nil
 }
 
+// Whether err is one of the expected errors returned when creating a project.
+func isCreateProjectError(err error) bool {
+	var quotaErr *QuotaExceededError
+	var duplicateErr *DuplicateProjectError
+	var nearDuplicateErr *NearDuplicateError
+
+	return errors.As(err, &quotaErr) || errors.As(err, &duplicateErr) || errors.As(err, &nearDuplicateErr)
+}
+
 func (s *serviceImpl) UpdateProject(projectId uint, projectData NewProjectDto) error {
 	err := validator.New().Struct(projectData)
 	if err != nil {
@@ -89,36 +352,128 @@ func (s *serviceImpl) UpdateProject(projectId uint, projectData NewProjectDto) e
 		LongDescription:  projectData.LongDescription,
 		ShortDescription: projectData.ShortDescription,
 		GithubLink:       projectData.GithubLink,
+		Visibility:       projectData.Visibility,
+		DefaultLocale:    projectData.DefaultLocale,
+
+		GithubLinkNormalized: utils.NormalizeRepoLink(projectData.GithubLink),
 	}
 
-	result := s.Db.Save(&project)
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return ErrProjectNotFound
+	columns := []string{
+		"name",
+		"tags",
+		"long_description",
+		"short_description",
+		"github_link",
+		"github_link_normalized",
+	}
+
+	// Updates without a visibility keep the current one
+	if projectData.Visibility != "" {
+		columns = append(columns, "visibility")
+	}
+
+	if projectData.DefaultLocale != "" {
+		project.DefaultLocale = language.Make(projectData.DefaultLocale).String()
+		columns = append(columns, "default_locale")
+	}
+
+	event := events.Event{
+		Type:      events.ProjectUpdated,
+		ProjectId: projectId,
+	}
+
+	err = s.Db.Transaction(func(tx *gorm.DB) error {
+		current := Project{}
+		err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "name", "slug").
+			First(&current, projectId).
+			Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrProjectNotFound
+			}
+
+			return err
+		}
+
+		err = checkRepoLinkDuplicate(tx, project.GithubLinkNormalized, projectId)
+		if err != nil {
+			return err
+		}
+
+		// Renaming a project changes its slug, the old slug is
+		// kept as a redirect.
+		if utils.Slugify(current.Name) != utils.Slugify(project.Name) {
+			project.Slug, err = assignSlug(tx, project.Name, projectId)
+			if err != nil {
+				return err
+			}
+
+			if current.Slug != "" && current.Slug != project.Slug {
+				err = tx.Create(&ProjectSlugRedirect{
+					Slug:      current.Slug,
+					ProjectId: projectId,
+				}).Error
+				if err != nil {
+					return err
+				}
+			}
+
+			columns = append(columns, "slug")
 		} else {
-			return result.Error
+			project.Slug = current.Slug
 		}
+
+		err = tx.Select(columns).Updates(&project).Error
+		if err != nil {
+			return err
+		}
+
+		// The project's own text is now in its default locale, so a
+		// translation in that locale would never be used.
+		if project.DefaultLocale != "" {
+			err = tx.
+				Where("project_id = ? AND locale = ?", projectId, project.DefaultLocale).
+				Delete(&ProjectTranslation{}).
+				Error
+			if err != nil {
+				return err
+			}
+		}
+
+		event.Data = s.GetProjectSummary(&project)
+
+		return s.Events.Publish(context.Background(), tx, event)
+	})
+	if err != nil {
+		return err
 	}
 
+	s.committed(context.Background(), event)
+
 	return nil
 }
 
 func (s *serviceImpl) GetProjectSummary(project *Project) ProjectSummaryDto {
 	return ProjectSummaryDto{
 		Id:               project.ID,
+		Slug:             project.Slug,
 		Name:             project.Name,
 		Tags:             project.Tags,
 		ShortDescription: project.ShortDescription,
 	}
 }
 
-func (s *serviceImpl) GetProject(ctx context.Context, projectId uint) (ProjectDto, error) {
+func (s *serviceImpl) GetProject(ctx context.Context, projectId uint, locales []language.Tag) (ProjectDto, error) {
 	logger := log.FromContext(ctx)
 
 	logger.Debugf("Querying for project of id %d", projectId)
 
 	project := Project{}
-	result := s.Db.First(&project, projectId)
+	result := s.Db.
+		Where("hidden_at IS NULL").
+		First(&project, projectId)
 
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
@@ -130,15 +485,165 @@ func (s *serviceImpl) GetProject(ctx context.Context, projectId uint) (ProjectDt
 		}
 	}
 
+	err := s.checkCanView(ctx, &project)
+	if err != nil {
+		return ProjectDto{}, err
+	}
+
 	logger.Debugf("Project of id %d was found", projectId)
 
+	return s.projectDto(ctx, &project, locales)
+}
+
+func (s *serviceImpl) GetProjectBySlug(ctx context.Context, slug string, locales []language.Tag) (ProjectDto, error) {
+	logger := log.FromContext(ctx).WithField("slug", slug)
+
+	logger.Debug("Querying for project by slug")
+
+	project, isOldSlug, err := findProjectBySlug(s.Db, slug)
+	if err != nil {
+		if !errors.Is(err, ErrProjectNotFound) {
+			logger.WithError(err).Error("Failed to query for project by slug")
+		}
+
+		return ProjectDto{}, err
+	}
+
+	err = s.checkCanView(ctx, project)
+	if err != nil {
+		return ProjectDto{}, err
+	}
+
+	if isOldSlug {
+		return ProjectDto{}, &SlugMovedError{Slug: project.Slug}
+	}
+
+	return s.projectDto(ctx, project, locales)
+}
+
+func (s *serviceImpl) GetSlugByLinkUid(ctx context.Context, linkUid uint) (string, error) {
+	project := Project{}
+	err := s.Db.
+		Select("id", "slug", "visibility", "hidden_at").
+		Where("link_uid = ?", linkUid).
+		First(&project).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrProjectNotFound
+		}
+
+		log.FromContext(ctx).WithError(err).Error("Failed to query for project by link uid")
+		return "", err
+	}
+
+	err = s.checkCanView(ctx, &project)
+	if err != nil {
+		return "", err
+	}
+
+	return project.Slug, nil
+}
+
+func (s *serviceImpl) CheckCanView(ctx context.Context, projectId uint) error {
+	project := Project{}
+	err := s.Db.
+		Select("id", "visibility", "hidden_at").
+		First(&project, projectId).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrProjectNotFound
+		}
+
+		log.FromContext(ctx).WithError(err).Error("Failed to query for project")
+		return err
+	}
+
+	return s.checkCanView(ctx, &project)
+}
+
+// Check whether the user in ctx can see a project. Hidden projects can't be
+// seen by anyone and private projects can only be seen by their team.
+// Returns ErrProjectNotFound if the user can't see the project, so that
+// private projects can't be told apart from projects that don't exist.
+func (s *serviceImpl) checkCanView(ctx context.Context, project *Project) error {
+	if project.HiddenAt != nil {
+		return ErrProjectNotFound
+	}
+
+	if project.Visibility != VisibilityPrivate {
+		return nil
+	}
+
+	session, ok := auth.SessionFromContext(ctx)
+	if !ok {
+		return ErrProjectNotFound
+	}
+
+	role, err := s.GetMemberRole(ctx, project.ID, session.UserId())
+	if err != nil {
+		return err
+	}
+
+	if role == "" {
+		return ErrProjectNotFound
+	}
+
+	return nil
+}
+
+// Build the ProjectDto of a project, including its open roles.
+func (s *serviceImpl) projectDto(ctx context.Context, project *Project, locales []language.Tag) (ProjectDto, error) {
+	var roles []ProjectRole
+	err := s.Db.
+		Where("project_id = ? AND closed_at IS NULL", project.ID).
+		Order("created_at asc").
+		Find(&roles).
+		Error
+	if err != nil {
+		log.FromContext(ctx).WithError(err).Errorf("Failed to query for roles of project %d", project.ID)
+		return ProjectDto{}, err
+	}
+
+	roleDtos := make([]RoleDto, len(roles))
+	for i := range roles {
+		roleDtos[i] = roleDto(roles[i])
+	}
+
+	translations, err := findTranslations(s.Db, []uint{project.ID})
+	if err != nil {
+		log.FromContext(ctx).WithError(err).Errorf("Failed to query for translations of project %d", project.ID)
+		return ProjectDto{}, err
+	}
+
+	locale := project.DefaultLocale
+	name := project.Name
+	shortDescription := project.ShortDescription
+	longDescription := project.LongDescription
+
+	translation, available := pickTranslation(project.DefaultLocale, translations[project.ID], locales)
+	if translation != nil {
+		locale = translation.Locale
+		name = translation.Name
+		shortDescription = translation.ShortDescription
+		longDescription = translation.LongDescription
+	}
+
 	return ProjectDto{
 		Id:               project.ID,
-		Name:             project.Name,
+		Slug:             project.Slug,
+		ShortLink:        shortLink(project.LinkUid),
+		Name:             name,
 		Tags:             project.Tags,
-		ShortDescription: project.ShortDescription,
-		LongDescription:  project.LongDescription,
+		ShortDescription: shortDescription,
+		LongDescription:  longDescription,
 		GithubLink:       project.GithubLink,
+		Visibility:       project.Visibility,
+		Roles:            roleDtos,
+		Collaboration:    collaborationDto(project),
+		Locale:           locale,
+		Translations:     available,
 	}, nil
 }
 
@@ -148,6 +653,8 @@ func (s *serviceImpl) ListProjects(
 	pageOffset uint,
 	tags []string,
 	skills []string,
+	overlap *users.OverlapFilter,
+	locales []language.Tag,
 ) ([]ProjectSummaryDto, error) {
 	logger := log.FromContext(ctx)
 
@@ -159,19 +666,30 @@ func (s *serviceImpl) ListProjects(
 	}).
 		Debug("Listing projects")
 
-	if tags == nil {
-		tags = []string{}
-	}
-
 	if skills == nil {
 		skills = []string{}
 	}
 
+	query := s.listQuery(tags)
+	if overlap != nil {
+		query = filterByOverlap(query, overlap)
+	}
+
+	// Projects of users the viewer blocked or muted are hidden from them
+	if session, ok := auth.SessionFromContext(ctx); ok {
+		query = query.Where("owner_id NOT IN (?)", users.HiddenUserIds(s.Db, session.UserId()))
+	}
+
 	projectSummaries := make([]ProjectSummaryDto, pageSize)
-	result := s.Db.
-		Model(&Project{}).
-		Select("name", "tags", "short_description", "id").
-		Where("cardinality(?::TEXT[]) < 1 OR tags && ?", pq.StringArray(tags), pq.StringArray(tags)).
+	result := query.
+		Select(
+			"name, slug, tags, short_description, id, default_locale AS locale, (?) AS good_first_tasks",
+			s.Db.
+				Model(&ProjectTask{}).
+				Select("count(*)").
+				Where("project_tasks.project_id = projects.id").
+				Where("project_tasks.status <> ? AND ? = ANY(project_tasks.labels)", TaskStatusDone, LabelGoodFirstTask),
+		).
 		Order("created_at desc").
 		Limit(int(pageSize)).
 		Offset(int(pageOffset * pageSize)).
@@ -185,5 +703,424 @@ func (s *serviceImpl) ListProjects(
 
 	logger.Debugf("Found %d projects", result.RowsAffected)
 
-	return projectSummaries[:result.RowsAffected], nil
+	projectSummaries = projectSummaries[:result.RowsAffected]
+
+	projectIds := make([]uint, len(projectSummaries))
+	for i := range projectSummaries {
+		projectIds[i] = projectSummaries[i].Id
+	}
+
+	translations, err := findTranslations(s.Db, projectIds)
+	if err != nil {
+		logger.WithError(err).Error("Failed to query for translations of projects")
+		return nil, err
+	}
+
+	for i := range projectSummaries {
+		summary := &projectSummaries[i]
+
+		translation, available := pickTranslation(summary.Locale, translations[summary.Id], locales)
+		if translation != nil {
+			summary.Locale = translation.Locale
+			summary.Name = translation.Name
+			summary.ShortDescription = translation.ShortDescription
+		}
+
+		summary.Translations = available
+	}
+
+	return projectSummaries, nil
+}
+
+func (s *serviceImpl) ListRecentProjects(ctx context.Context, tags []string, limit uint) ([]RecentProjectDto, error) {
+	logger := log.FromContext(ctx).WithField("tags", tags)
+
+	projects := []RecentProjectDto{}
+	err := s.listQuery(tags).
+		Select("id", "slug", "name", "tags", "short_description", "created_at", "updated_at").
+		Order("updated_at desc").
+		Limit(int(limit)).
+		Find(&projects).
+		Error
+	if err != nil {
+		logger.WithError(err).Error("Failed to list recent projects")
+		return nil, err
+	}
+
+	return projects, nil
+}
+
+func (s *serviceImpl) ListProjectLinks(ctx context.Context, page uint, pageSize uint) ([]ProjectLinkDto, error) {
+	links := []ProjectLinkDto{}
+	err := s.listQuery(nil).
+		Select("id", "slug", "updated_at").
+		Order("id").
+		Limit(int(pageSize)).
+		Offset(int(page * pageSize)).
+		Find(&links).
+		Error
+	if err != nil {
+		log.FromContext(ctx).WithError(err).Error("Failed to list project links")
+		return nil, err
+	}
+
+	return links, nil
+}
+
+func (s *serviceImpl) ListProjectLinkPages(ctx context.Context, pageSize uint) ([]time.Time, error) {
+	var pages []struct {
+		Page      int
+		UpdatedAt time.Time
+	}
+
+	numbered := s.listQuery(nil).
+		Select("(row_number() OVER (ORDER BY id) - 1) / ? AS page, updated_at", pageSize)
+
+	err := s.Db.
+		Table("(?) AS numbered", numbered).
+		Select("page, max(updated_at) AS updated_at").
+		Group("page").
+		Order("page").
+		Scan(&pages).
+		Error
+	if err != nil {
+		log.FromContext(ctx).WithError(err).Error("Failed to list project link pages")
+		return nil, err
+	}
+
+	updatedAt := make([]time.Time, len(pages))
+	for i, page := range pages {
+		updatedAt[i] = page.UpdatedAt
+	}
+
+	return updatedAt, nil
+}
+
+// Query for the projects that can be listed (i.e. public and not hidden),
+// optionally filtered by tags.
+func (s *serviceImpl) listQuery(tags []string) *gorm.DB {
+	return filterByTags(s.Db.Model(&Project{}), tags).
+		Where("hidden_at IS NULL AND visibility = ?", VisibilityPublic)
+}
+
+func (s *serviceImpl) GetProjectOwner(ctx context.Context, projectId uint) (uint, error) {
+	logger := log.FromContext(ctx).WithField("projectId", projectId)
+
+	project := Project{}
+	result := s.Db.
+		Select("owner_id").
+		First(&project, projectId)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return 0, ErrProjectNotFound
+		} else {
+			logger.WithError(result.Error).Error("Failed to query for project owner")
+			return 0, result.Error
+		}
+	}
+
+	return project.OwnerId, nil
+}
+
+func (s *serviceImpl) ListMemberProjects(ctx context.Context, userId uint) ([]MemberProjectDto, error) {
+	query := s.Db.
+		Model(&Project{}).
+		Select("projects.id, projects.slug, projects.name, projects.short_description, project_members.role").
+		Joins("JOIN project_members ON project_members.project_id = projects.id AND project_members.deleted_at IS NULL").
+		Where("project_members.user_id = ? AND projects.hidden_at IS NULL", userId)
+
+	// Projects that aren't public are only listed to their team
+	viewerId := uint(0)
+	if session, ok := auth.SessionFromContext(ctx); ok {
+		viewerId = session.UserId()
+	}
+
+	query = query.Where(
+		"projects.visibility = ? OR EXISTS (?)",
+		VisibilityPublic,
+		s.Db.
+			Model(&ProjectMember{}).
+			Select("1").
+			Where("project_members.project_id = projects.id AND project_members.user_id = ?", viewerId),
+	)
+
+	memberProjects := []MemberProjectDto{}
+	err := query.Order("projects.created_at desc").Scan(&memberProjects).Error
+	if err != nil {
+		log.FromContext(ctx).WithError(err).WithField("userId", userId).Error("Failed to list member projects")
+		return nil, err
+	}
+
+	return memberProjects, nil
+}
+
+func (s *serviceImpl) SharesProject(ctx context.Context, userId uint, otherUserId uint) (bool, error) {
+	var count int64
+	err := s.Db.
+		Unscoped().
+		Table("project_members AS a").
+		Joins("JOIN project_members AS b ON b.project_id = a.project_id").
+		Where("a.user_id = ? AND b.user_id = ?", userId, otherUserId).
+		Count(&count).
+		Error
+	if err != nil {
+		log.FromContext(ctx).WithError(err).Error("Failed to query for shared projects")
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (s *serviceImpl) GetMemberRole(ctx context.Context, projectId uint, userId uint) (string, error) {
+	member := ProjectMember{}
+	result := s.Db.
+		Where("project_id = ? AND user_id = ?", projectId, userId).
+		Limit(1).
+		Find(&member)
+	if result.Error != nil {
+		log.FromContext(ctx).WithError(result.Error).Error("Failed to query for project member")
+		return "", result.Error
+	}
+
+	return member.Role, nil
+}
+
+func (s *serviceImpl) SetProjectHidden(ctx context.Context, projectId uint, hidden bool) error {
+	logger := log.FromContext(ctx).WithFields(log.Fields{
+		"projectId": projectId,
+		"hidden":    hidden,
+	})
+
+	logger.Debug("Changing project visibility")
+
+	var hiddenAt *time.Time
+	if hidden {
+		now := time.Now()
+		hiddenAt = &now
+	}
+
+	event := events.Event{
+		Type:      events.ProjectUnhidden,
+		ProjectId: projectId,
+	}
+	if hidden {
+		event.Type = events.ProjectHidden
+	}
+
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		result := tx.
+			Model(&Project{}).
+			Where("id = ?", projectId).
+			Update("hidden_at", hiddenAt)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected < 1 {
+			return ErrProjectNotFound
+		}
+
+		return s.Events.Publish(ctx, tx, event)
+	})
+	if err != nil {
+		if !errors.Is(err, ErrProjectNotFound) {
+			logger.WithError(err).Error("Failed to change project visibility")
+		}
+
+		return err
+	}
+
+	s.committed(ctx, event)
+
+	return nil
+}
+
+func (s *serviceImpl) CreateRole(
+	ctx context.Context,
+	userId uint,
+	projectId uint,
+	newRole NewRoleDto,
+) (RoleDto, error) {
+	logger := log.FromContext(ctx).WithField("projectId", projectId)
+
+	err := validator.New().Struct(newRole)
+	if err != nil {
+		return RoleDto{}, err
+	}
+
+	err = s.checkCanManage(ctx, userId, projectId)
+	if err != nil {
+		return RoleDto{}, err
+	}
+
+	if newRole.Skills == nil {
+		newRole.Skills = []string{}
+	}
+
+	role := ProjectRole{
+		ProjectId:   projectId,
+		Title:       newRole.Title,
+		Description: newRole.Description,
+		Skills:      newRole.Skills,
+	}
+
+	err = s.Db.Transaction(func(tx *gorm.DB) error {
+		project := Project{}
+		err := tx.Select("id", "owner_id").First(&project, projectId).Error
+		if err != nil {
+			return err
+		}
+
+		var openRoles int64
+		err = tx.
+			Model(&ProjectRole{}).
+			Where("project_id = ? AND closed_at IS NULL", projectId).
+			Count(&openRoles).
+			Error
+		if err != nil {
+			return err
+		}
+
+		// Opening the first role makes the project a recruiting project
+		if openRoles < 1 {
+			err = s.checkQuota(tx, project.OwnerId, QuotaRecruitingProjects)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = tx.Create(&role).Error
+		if err != nil {
+			return err
+		}
+
+		return s.Events.Publish(ctx, tx, events.Event{
+			Type:      events.RoleOpened,
+			ProjectId: projectId,
+			ActorId:   userId,
+			Data:      roleDto(role),
+		})
+	})
+	if err != nil {
+		var quotaErr *QuotaExceededError
+		if !errors.As(err, &quotaErr) {
+			logger.WithError(err).Error("Failed to create role")
+		}
+
+		return RoleDto{}, err
+	}
+
+	return roleDto(role), nil
+}
+
+func (s *serviceImpl) CloseRole(ctx context.Context, userId uint, projectId uint, roleId uint) error {
+	logger := log.FromContext(ctx).WithFields(log.Fields{
+		"projectId": projectId,
+		"roleId":    roleId,
+	})
+
+	err := s.checkCanManage(ctx, userId, projectId)
+	if err != nil {
+		return err
+	}
+
+	err = s.Db.Transaction(func(tx *gorm.DB) error {
+		role := ProjectRole{}
+		err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("project_id = ? AND closed_at IS NULL", projectId).
+			First(&role, roleId).
+			Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRoleNotFound
+			}
+
+			return err
+		}
+
+		now := time.Now()
+		role.ClosedAt = &now
+
+		err = tx.Model(&role).Update("closed_at", now).Error
+		if err != nil {
+			return err
+		}
+
+		return s.Events.Publish(ctx, tx, events.Event{
+			Type:      events.RoleClosed,
+			ProjectId: projectId,
+			ActorId:   userId,
+			Data:      roleDto(role),
+		})
+	})
+	if err != nil {
+		if !errors.Is(err, ErrRoleNotFound) {
+			logger.WithError(err).Error("Failed to close role")
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// Check whether a user can manage a project, i.e. is the project's
+// owner or one of its maintainers.
+// Returns auth.ErrForbidden if the user can't manage the project.
+func (s *serviceImpl) checkCanManage(ctx context.Context, userId uint, projectId uint) error {
+	role, err := s.GetMemberRole(ctx, projectId, userId)
+	if err != nil {
+		return err
+	}
+
+	if role != MemberRoleOwner && role != MemberRoleMaintainer {
+		return auth.ErrForbidden
+	}
+
+	return nil
+}
+
+func roleDto(role ProjectRole) RoleDto {
+	return RoleDto{
+		Id:          role.ID,
+		Title:       role.Title,
+		Description: role.Description,
+		Skills:      role.Skills,
+		Open:        role.ClosedAt == nil,
+	}
+}
+
+// Set the role of a user in a project's team, adding the user to the
+// team if needed. Callers should hold a lock on the project to avoid
+// adding the same user twice.
+func setMemberRole(tx *gorm.DB, projectId uint, userId uint, role string) error {
+	result := tx.
+		Model(&ProjectMember{}).
+		Where("project_id = ? AND user_id = ?", projectId, userId).
+		Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	return tx.Create(&ProjectMember{
+		ProjectId: projectId,
+		UserId:    userId,
+		Role:      role,
+	}).Error
+}
+
+// Filter a projects query by tags, keeping projects that have at least one
+// of the given tags. Nil or empty tags don't filter anything.
+func filterByTags(query *gorm.DB, tags []string) *gorm.DB {
+	if tags == nil {
+		tags = []string{}
+	}
+
+	return query.Where("cardinality(?::TEXT[]) < 1 OR tags && ?", pq.StringArray(tags), pq.StringArray(tags))
 }
@@ -0,0 +1,22 @@
+package projects
+
+type QuotaOverrideDto struct {
+	MaxOwnedProjects      *int `json:"maxOwnedProjects" validate:"omitempty,min=-1"`
+	MaxRecruitingProjects *int `json:"maxRecruitingProjects" validate:"omitempty,min=-1"`
+	MaxProjectsPerDay     *int `json:"maxProjectsPerDay" validate:"omitempty,min=-1"`
+}
+
+type QuotaUsageDto struct {
+	OwnedProjects      int `json:"ownedProjects"`
+	RecruitingProjects int `json:"recruitingProjects"`
+	// Projects the user created in the last 24 hours, including ones that
+	// were transferred to someone else since.
+	ProjectsToday int `json:"projectsToday"`
+}
+
+type QuotaDto struct {
+	// The limits in effect for the user. A limit of -1 means unlimited.
+	Limits   QuotaPolicy      `json:"limits"`
+	Usage    QuotaUsageDto    `json:"usage"`
+	Override QuotaOverrideDto `json:"override"`
+}
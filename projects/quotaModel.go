@@ -0,0 +1,50 @@
+package projects
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+)
+
+const (
+	QuotaOwnedProjects      = "owned-projects"
+	QuotaRecruitingProjects = "recruiting-projects"
+	QuotaProjectsPerDay     = "projects-per-day"
+)
+
+// A limit set to QuotaUnlimited is never enforced.
+const QuotaUnlimited = -1
+
+// Limits on how many projects a user can have.
+type QuotaPolicy struct {
+	// Maximum amount of projects a user can own at once.
+	MaxOwnedProjects int `json:"maxOwnedProjects"`
+
+	// Maximum amount of owned projects with open roles.
+	MaxRecruitingProjects int `json:"maxRecruitingProjects"`
+
+	// Maximum amount of projects a user can create in 24 hours.
+	MaxProjectsPerDay int `json:"maxProjectsPerDay"`
+}
+
+// Per user overrides of the default QuotaPolicy, set by admins.
+// Nil limits fall back to the default policy.
+type ProjectQuotaOverride struct {
+	gorm.Model
+
+	UserId                uint
+	MaxOwnedProjects      *int
+	MaxRecruitingProjects *int
+	MaxProjectsPerDay     *int
+}
+
+// Returned when an action would make a user go over one of its quotas.
+type QuotaExceededError struct {
+	// One of QuotaOwnedProjects, QuotaRecruitingProjects or QuotaProjectsPerDay.
+	Quota string
+	Limit int
+	Usage int
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%s quota exceeded: using %d out of %d", e.Quota, e.Usage, e.Limit)
+}
@@ -0,0 +1,128 @@
+package projects
+
+import (
+	"github.com/open-collaboration/server/auth"
+	"github.com/open-collaboration/server/users"
+	"github.com/open-collaboration/server/utils"
+	"net/http"
+)
+
+// @Summary Get your project quota and usage
+// @Tags projects
+// @Router /users/me/project-quota [get]
+// @Success 200 {object} dtos.QuotaDto
+func RouteGetOwnQuota(
+	writer http.ResponseWriter,
+	request *http.Request,
+	projectsService Service,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	quota, err := projectsService.GetQuota(request.Context(), session.UserId())
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusOK, quota)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Get a user's project quota and usage
+// @Tags admin
+// @Router /admin/users/{userId}/project-quota [get]
+// @Param userId path int true "The user ID"
+// @Success 200 {object} dtos.QuotaDto
+func RouteGetUserQuota(
+	writer http.ResponseWriter,
+	request *http.Request,
+	projectsService Service,
+	usersService users.Service,
+) error {
+	userId, err := adminTargetUser(request, usersService)
+	if err != nil {
+		return err
+	}
+
+	quota, err := projectsService.GetQuota(request.Context(), userId)
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusOK, quota)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Override a user's project quota
+// @Tags admin
+// @Router /admin/users/{userId}/project-quota [put]
+// @Param userId path int true "The user ID"
+// @Param override body dtos.QuotaOverrideDto true "Limits to override, null limits use the default policy and -1 means unlimited"
+// @Success 204
+func RouteSetUserQuota(
+	writer http.ResponseWriter,
+	request *http.Request,
+	projectsService Service,
+	usersService users.Service,
+) error {
+	userId, err := adminTargetUser(request, usersService)
+	if err != nil {
+		return err
+	}
+
+	dto := QuotaOverrideDto{}
+	err = utils.ReadJson(request.Context(), request, &dto)
+	if err != nil {
+		return err
+	}
+
+	err = projectsService.SetQuotaOverride(request.Context(), userId, dto)
+	if err != nil {
+		return err
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// Check that the request was made by an admin and return the id of
+// the user in the route's userId parameter.
+// Returns auth.ErrForbidden if the session's user is not an admin.
+func adminTargetUser(request *http.Request, usersService users.Service) (uint, error) {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return 0, err
+	}
+
+	admin, err := usersService.GetUser(request.Context(), session.UserId())
+	if err != nil {
+		return 0, err
+	}
+
+	if !admin.IsAdmin() {
+		return 0, auth.ErrForbidden
+	}
+
+	userId, err := utils.UintFromVars(request, "userId")
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = usersService.GetUser(request.Context(), userId)
+	if err != nil {
+		return 0, err
+	}
+
+	return userId, nil
+}
@@ -0,0 +1,204 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"github.com/apex/log"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Advisory lock namespace used to serialize quota checks of a user, so that
+// concurrent requests can't both pass a check and go over the quota.
+const quotaLockNamespace = 1
+
+func (s *serviceImpl) GetQuota(ctx context.Context, userId uint) (QuotaDto, error) {
+	logger := log.FromContext(ctx).WithField("userId", userId)
+
+	override, err := s.findQuotaOverride(s.Db, userId)
+	if err != nil {
+		logger.WithError(err).Error("Failed to query for quota override")
+		return QuotaDto{}, err
+	}
+
+	usage, err := s.getQuotaUsage(s.Db, userId)
+	if err != nil {
+		logger.WithError(err).Error("Failed to query for quota usage")
+		return QuotaDto{}, err
+	}
+
+	return QuotaDto{
+		Limits: s.applyQuotaOverride(override),
+		Usage:  usage,
+		Override: QuotaOverrideDto{
+			MaxOwnedProjects:      override.MaxOwnedProjects,
+			MaxRecruitingProjects: override.MaxRecruitingProjects,
+			MaxProjectsPerDay:     override.MaxProjectsPerDay,
+		},
+	}, nil
+}
+
+func (s *serviceImpl) SetQuotaOverride(ctx context.Context, userId uint, override QuotaOverrideDto) error {
+	logger := log.FromContext(ctx).WithField("userId", userId)
+
+	err := validator.New().Struct(override)
+	if err != nil {
+		return err
+	}
+
+	logger.Debug("Setting project quota override")
+
+	err = s.Db.Transaction(func(tx *gorm.DB) error {
+		existing, err := s.findQuotaOverride(tx, userId)
+		if err != nil {
+			return err
+		}
+
+		existing.UserId = userId
+		existing.MaxOwnedProjects = override.MaxOwnedProjects
+		existing.MaxRecruitingProjects = override.MaxRecruitingProjects
+		existing.MaxProjectsPerDay = override.MaxProjectsPerDay
+
+		return tx.Save(&existing).Error
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to set project quota override")
+		return err
+	}
+
+	return nil
+}
+
+func (s *serviceImpl) CheckQuota(ctx context.Context, db *gorm.DB, userId uint, quotas ...string) error {
+	err := s.checkQuota(db, userId, quotas...)
+	if err != nil {
+		var quotaErr *QuotaExceededError
+		if !errors.As(err, &quotaErr) {
+			log.FromContext(ctx).WithError(err).Error("Failed to check quota")
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// Check whether a user has room for one more project in each of the given
+// quotas. When called inside a transaction, the user's quotas stay locked until
+// the transaction ends.
+// Returns a *QuotaExceededError if any of the quotas is exhausted.
+func (s *serviceImpl) checkQuota(db *gorm.DB, userId uint, quotas ...string) error {
+	err := db.Exec("SELECT pg_advisory_xact_lock(?, ?)", quotaLockNamespace, userId).Error
+	if err != nil {
+		return err
+	}
+
+	override, err := s.findQuotaOverride(db, userId)
+	if err != nil {
+		return err
+	}
+
+	policy := s.applyQuotaOverride(override)
+
+	usage, err := s.getQuotaUsage(db, userId)
+	if err != nil {
+		return err
+	}
+
+	for _, quota := range quotas {
+		var limit, used int
+		switch quota {
+		case QuotaOwnedProjects:
+			limit, used = policy.MaxOwnedProjects, usage.OwnedProjects
+		case QuotaRecruitingProjects:
+			limit, used = policy.MaxRecruitingProjects, usage.RecruitingProjects
+		case QuotaProjectsPerDay:
+			limit, used = policy.MaxProjectsPerDay, usage.ProjectsToday
+		default:
+			continue
+		}
+
+		if limit != QuotaUnlimited && used >= limit {
+			return &QuotaExceededError{
+				Quota: quota,
+				Limit: limit,
+				Usage: used,
+			}
+		}
+	}
+
+	return nil
+}
+
+// Find a user's quota override. If the user has no override, an empty
+// override (i.e. one that uses the default limits) is returned.
+func (s *serviceImpl) findQuotaOverride(db *gorm.DB, userId uint) (ProjectQuotaOverride, error) {
+	override := ProjectQuotaOverride{}
+	err := db.
+		Where("user_id = ?", userId).
+		Limit(1).
+		Find(&override).
+		Error
+
+	return override, err
+}
+
+func (s *serviceImpl) applyQuotaOverride(override ProjectQuotaOverride) QuotaPolicy {
+	policy := s.DefaultQuota
+
+	if override.MaxOwnedProjects != nil {
+		policy.MaxOwnedProjects = *override.MaxOwnedProjects
+	}
+
+	if override.MaxRecruitingProjects != nil {
+		policy.MaxRecruitingProjects = *override.MaxRecruitingProjects
+	}
+
+	if override.MaxProjectsPerDay != nil {
+		policy.MaxProjectsPerDay = *override.MaxProjectsPerDay
+	}
+
+	return policy
+}
+
+func (s *serviceImpl) getQuotaUsage(db *gorm.DB, userId uint) (QuotaUsageDto, error) {
+	var owned, recruiting, today int64
+
+	err := db.
+		Model(&Project{}).
+		Where("owner_id = ?", userId).
+		Count(&owned).
+		Error
+	if err != nil {
+		return QuotaUsageDto{}, err
+	}
+
+	err = db.
+		Model(&Project{}).
+		Where("owner_id = ?", userId).
+		Where("EXISTS (?)", db.
+			Model(&ProjectRole{}).
+			Select("1").
+			Where("project_roles.project_id = projects.id AND project_roles.closed_at IS NULL")).
+		Count(&recruiting).
+		Error
+	if err != nil {
+		return QuotaUsageDto{}, err
+	}
+
+	err = db.
+		Model(&Project{}).
+		Where("created_by = ? AND created_at > ?", userId, time.Now().Add(-24*time.Hour)).
+		Count(&today).
+		Error
+	if err != nil {
+		return QuotaUsageDto{}, err
+	}
+
+	return QuotaUsageDto{
+		OwnedProjects:      int(owned),
+		RecruitingProjects: int(recruiting),
+		ProjectsToday:      int(today),
+	}, nil
+}
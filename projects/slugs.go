@@ -0,0 +1,140 @@
+package projects
+
+import (
+	"errors"
+	"fmt"
+	"github.com/open-collaboration/server/utils"
+	"gorm.io/gorm"
+)
+
+// Advisory lock used to serialize slug assignments, so that two projects
+// can't end up with the same slug.
+const slugLockNamespace = 2
+
+// Slug used when a project's name has no characters usable in a slug.
+const defaultSlug = "project"
+
+// An old slug of a project, kept so that links using it keep working
+// after the project is renamed.
+type ProjectSlugRedirect struct {
+	gorm.Model
+
+	Slug      string
+	ProjectId uint
+}
+
+// Returned when a project is looked up by an old slug. Slug is the
+// project's current slug.
+type SlugMovedError struct {
+	Slug string
+}
+
+func (e *SlugMovedError) Error() string {
+	return fmt.Sprintf("project slug moved to %s", e.Slug)
+}
+
+// Find a free slug for a project with the given name. If the name's slug is
+// taken, a numeric suffix is added to it (e.g. "my-project-2"). Old slugs of
+// other projects are considered taken, old slugs of the project itself can
+// be reclaimed. Pass 0 as projectId for new projects.
+// Must be called inside a transaction.
+func assignSlug(tx *gorm.DB, name string, projectId uint) (string, error) {
+	base := utils.Slugify(name)
+	if base == "" {
+		base = defaultSlug
+	}
+
+	err := tx.Exec("SELECT pg_advisory_xact_lock(?, 0)", slugLockNamespace).Error
+	if err != nil {
+		return "", err
+	}
+
+	for suffix := 1; ; suffix++ {
+		slug := base
+		if suffix > 1 {
+			slug = fmt.Sprintf("%s-%d", base, suffix)
+		}
+
+		taken, err := isSlugTaken(tx, slug, projectId)
+		if err != nil {
+			return "", err
+		}
+
+		if taken {
+			continue
+		}
+
+		// If the slug is an old slug of this project, it's not
+		// a redirect anymore.
+		err = tx.
+			Unscoped().
+			Where("slug = ? AND project_id = ?", slug, projectId).
+			Delete(&ProjectSlugRedirect{}).
+			Error
+		if err != nil {
+			return "", err
+		}
+
+		return slug, nil
+	}
+}
+
+// Whether slug is in use by a project other than projectId, either as
+// its current slug or as an old one.
+func isSlugTaken(tx *gorm.DB, slug string, projectId uint) (bool, error) {
+	var count int64
+	err := tx.
+		Model(&Project{}).
+		Where("slug = ? AND id <> ?", slug, projectId).
+		Count(&count).
+		Error
+	if err != nil || count > 0 {
+		return count > 0, err
+	}
+
+	err = tx.
+		Model(&ProjectSlugRedirect{}).
+		Where("slug = ? AND project_id <> ?", slug, projectId).
+		Count(&count).
+		Error
+
+	return count > 0, err
+}
+
+// Find a project by its current slug, or by an old slug.
+// Returns the project and whether slug is an old slug.
+func findProjectBySlug(db *gorm.DB, slug string) (*Project, bool, error) {
+	project := &Project{}
+	err := db.Where("slug = ?", slug).First(project).Error
+	if err == nil {
+		return project, false, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, err
+	}
+
+	redirect := ProjectSlugRedirect{}
+	err = db.Where("slug = ?", slug).First(&redirect).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, ErrProjectNotFound
+		}
+
+		return nil, false, err
+	}
+
+	err = db.First(project, redirect.ProjectId).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, ErrProjectNotFound
+		}
+
+		return nil, false, err
+	}
+
+	return project, true, nil
+}
+
+// Path of a project's short link, e.g. "/s/1c".
+func shortLink(linkUid uint) string {
+	return "/s/" + utils.EncodeBase62(uint64(linkUid))
+}
@@ -0,0 +1,19 @@
+package projects
+
+import "time"
+
+type NewRoleSuggestionDto struct {
+	RecipientId uint   `json:"recipientId" validate:"required"`
+	Message     string `json:"message" validate:"max=1000"`
+}
+
+type RoleSuggestionDto struct {
+	Id         uint      `json:"id"`
+	ProjectId  uint      `json:"projectId"`
+	RoleId     uint      `json:"roleId"`
+	FromUserId uint      `json:"fromUserId"`
+	ToUserId   uint      `json:"toUserId"`
+	Message    string    `json:"message"`
+	Status     string    `json:"status"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
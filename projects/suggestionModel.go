@@ -0,0 +1,23 @@
+package projects
+
+import "gorm.io/gorm"
+
+const (
+	SuggestionStatusPending  = "pending"
+	SuggestionStatusAccepted = "accepted"
+	SuggestionStatusDeclined = "declined"
+)
+
+// An invite to take one of a project's open roles, sent by the project's
+// owner or one of its maintainers to a user found in the contributor
+// directory.
+type RoleSuggestion struct {
+	gorm.Model
+
+	ProjectId  uint
+	RoleId     uint
+	FromUserId uint
+	ToUserId   uint
+	Message    string
+	Status     string
+}
@@ -0,0 +1,147 @@
+package projects
+
+import (
+	"context"
+	"github.com/open-collaboration/server/auth"
+	"github.com/open-collaboration/server/users"
+	"github.com/open-collaboration/server/utils"
+	"net/http"
+)
+
+// @Summary Suggest an open role to a user from the contributor directory
+// @Tags projects
+// @Router /projects/{projectId}/roles/{roleId}/suggestions [post]
+// @Param projectId path int true "The project ID"
+// @Param roleId path int true "The role ID"
+// @Param suggestion body dtos.NewRoleSuggestionDto true "The suggestion's recipient and message"
+// @Success 201 {object} dtos.RoleSuggestionDto
+func RouteSuggestRole(
+	writer http.ResponseWriter,
+	request *http.Request,
+	suggestionsService SuggestionsService,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	projectId, err := utils.UintFromVars(request, "projectId")
+	if err != nil {
+		return err
+	}
+
+	roleId, err := utils.UintFromVars(request, "roleId")
+	if err != nil {
+		return err
+	}
+
+	dto := NewRoleSuggestionDto{}
+	err = utils.ReadJson(request.Context(), request, &dto)
+	if err != nil {
+		return err
+	}
+
+	suggestion, err := suggestionsService.SuggestRole(request.Context(), session.UserId(), projectId, roleId, dto)
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusCreated, suggestion)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary List the pending role suggestions you received
+// @Tags projects
+// @Router /users/me/role-suggestions [get]
+// @Success 200 {array} dtos.RoleSuggestionDto
+func RouteListRoleSuggestions(
+	writer http.ResponseWriter,
+	request *http.Request,
+	suggestionsService SuggestionsService,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	suggestions, err := suggestionsService.ListRoleSuggestions(request.Context(), session.UserId())
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusOK, suggestions)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Accept a role suggested to you and join the project's team
+// @Description Only users with a verified email can accept suggestions.
+// @Tags projects
+// @Router /role-suggestions/{suggestionId}/accept [post]
+// @Param suggestionId path int true "The suggestion ID"
+// @Success 204
+func RouteAcceptRoleSuggestion(
+	writer http.ResponseWriter,
+	request *http.Request,
+	suggestionsService SuggestionsService,
+	usersService users.Service,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	err = usersService.CheckEmailVerified(request.Context(), session.UserId())
+	if err != nil {
+		return err
+	}
+
+	return answerSuggestion(writer, request, suggestionsService.AcceptRoleSuggestion)
+}
+
+// @Summary Decline a role suggested to you
+// @Tags projects
+// @Router /role-suggestions/{suggestionId}/decline [post]
+// @Param suggestionId path int true "The suggestion ID"
+// @Success 204
+func RouteDeclineRoleSuggestion(
+	writer http.ResponseWriter,
+	request *http.Request,
+	suggestionsService SuggestionsService,
+) error {
+	return answerSuggestion(writer, request, suggestionsService.DeclineRoleSuggestion)
+}
+
+// Call `answer` with the session's user and the suggestion id in the
+// route and respond with a 204.
+func answerSuggestion(
+	writer http.ResponseWriter,
+	request *http.Request,
+	answer func(ctx context.Context, userId uint, suggestionId uint) error,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	suggestionId, err := utils.UintFromVars(request, "suggestionId")
+	if err != nil {
+		return err
+	}
+
+	err = answer(request.Context(), session.UserId(), suggestionId)
+	if err != nil {
+		return err
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
@@ -0,0 +1,377 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"github.com/apex/log"
+	"github.com/go-playground/validator/v10"
+	"github.com/open-collaboration/server/activity"
+	"github.com/open-collaboration/server/auth"
+	"github.com/open-collaboration/server/users"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+)
+
+var ErrSuggestionNotFound = errors.New("role suggestion not found")
+var ErrSuggestionNotPending = errors.New("role suggestion is no longer pending")
+var ErrSuggestionAlreadySent = errors.New("role was already suggested to the user")
+var ErrRoleClosed = errors.New("role is closed")
+var ErrAlreadyMember = errors.New("user is already a member of the project")
+
+type SuggestionsService interface {
+	// Suggest one of a project's open roles to a user listed in the
+	// contributor directory. Only the project's owner and maintainers can
+	// suggest roles. The recipient gets an activity record.
+	// Returns auth.ErrForbidden if userId can't manage the project,
+	// ErrRoleNotFound if the role isn't one of the project's, ErrRoleClosed if
+	// the role is closed, ErrInvalidRecipient if the recipient is userId or
+	// isn't listed in the directory, users.ErrBlocked if the recipient blocked
+	// userId, ErrAlreadyMember if the recipient is in the project's team and
+	// ErrSuggestionAlreadySent if the role was already suggested to the
+	// recipient and is still pending.
+	SuggestRole(
+		ctx context.Context,
+		userId uint,
+		projectId uint,
+		roleId uint,
+		suggestion NewRoleSuggestionDto,
+	) (RoleSuggestionDto, error)
+
+	// List the pending role suggestions a user received, newest first.
+	// Suggestions sent by users the user blocked or muted are left out.
+	ListRoleSuggestions(ctx context.Context, userId uint) ([]RoleSuggestionDto, error)
+
+	// Accept a role suggestion, joining the project's team as a member. The
+	// sender gets an activity record.
+	// Returns ErrSuggestionNotFound if the suggestion doesn't exist or wasn't
+	// sent to userId, ErrSuggestionNotPending if it was already answered and
+	// ErrRoleClosed if the role was closed since it was suggested.
+	AcceptRoleSuggestion(ctx context.Context, userId uint, suggestionId uint) error
+
+	// Decline a role suggestion sent to userId.
+	// Returns ErrSuggestionNotFound if the suggestion doesn't exist or wasn't
+	// sent to userId and ErrSuggestionNotPending if it was already answered.
+	DeclineRoleSuggestion(ctx context.Context, userId uint, suggestionId uint) error
+}
+
+type suggestionsServiceImpl struct {
+	Db               *gorm.DB
+	ProjectsService  Service
+	DirectoryService users.DirectoryService
+	BlocksService    users.BlocksService
+	ActivityService  activity.Service
+}
+
+func NewSuggestionsService(
+	db *gorm.DB,
+	projectsService Service,
+	directoryService users.DirectoryService,
+	blocksService users.BlocksService,
+	activityService activity.Service,
+) SuggestionsService {
+	return &suggestionsServiceImpl{
+		Db:               db,
+		ProjectsService:  projectsService,
+		DirectoryService: directoryService,
+		BlocksService:    blocksService,
+		ActivityService:  activityService,
+	}
+}
+
+func (s *suggestionsServiceImpl) SuggestRole(
+	ctx context.Context,
+	userId uint,
+	projectId uint,
+	roleId uint,
+	newSuggestion NewRoleSuggestionDto,
+) (RoleSuggestionDto, error) {
+	logger := log.FromContext(ctx).WithFields(log.Fields{
+		"projectId":   projectId,
+		"roleId":      roleId,
+		"recipientId": newSuggestion.RecipientId,
+	})
+
+	err := validator.New().Struct(newSuggestion)
+	if err != nil {
+		return RoleSuggestionDto{}, err
+	}
+
+	role, err := s.ProjectsService.GetMemberRole(ctx, projectId, userId)
+	if err != nil {
+		return RoleSuggestionDto{}, err
+	}
+
+	if role != MemberRoleOwner && role != MemberRoleMaintainer {
+		return RoleSuggestionDto{}, auth.ErrForbidden
+	}
+
+	if newSuggestion.RecipientId == userId {
+		return RoleSuggestionDto{}, ErrInvalidRecipient
+	}
+
+	listed, err := s.DirectoryService.IsListed(ctx, newSuggestion.RecipientId)
+	if err != nil {
+		return RoleSuggestionDto{}, err
+	}
+
+	if !listed {
+		return RoleSuggestionDto{}, ErrInvalidRecipient
+	}
+
+	err = s.BlocksService.CheckCanInteract(ctx, userId, newSuggestion.RecipientId)
+	if err != nil {
+		return RoleSuggestionDto{}, err
+	}
+
+	recipientRole, err := s.ProjectsService.GetMemberRole(ctx, projectId, newSuggestion.RecipientId)
+	if err != nil {
+		return RoleSuggestionDto{}, err
+	}
+
+	if recipientRole != "" {
+		return RoleSuggestionDto{}, ErrAlreadyMember
+	}
+
+	logger.Debug("Suggesting role")
+
+	suggestion := RoleSuggestion{}
+	err = s.Db.Transaction(func(tx *gorm.DB) error {
+		_, err := findOpenRole(tx, projectId, roleId)
+		if err != nil {
+			return err
+		}
+
+		var pendingCount int64
+		err = tx.
+			Model(&RoleSuggestion{}).
+			Where("role_id = ? AND to_user_id = ? AND status = ?", roleId, newSuggestion.RecipientId, SuggestionStatusPending).
+			Count(&pendingCount).
+			Error
+		if err != nil {
+			return err
+		}
+
+		if pendingCount > 0 {
+			return ErrSuggestionAlreadySent
+		}
+
+		suggestion = RoleSuggestion{
+			ProjectId:  projectId,
+			RoleId:     roleId,
+			FromUserId: userId,
+			ToUserId:   newSuggestion.RecipientId,
+			Message:    strings.TrimSpace(newSuggestion.Message),
+			Status:     SuggestionStatusPending,
+		}
+
+		err = tx.Create(&suggestion).Error
+		if err != nil {
+			return err
+		}
+
+		return s.ActivityService.RecordActivities(ctx, tx, activity.Activity{
+			UserId:        suggestion.ToUserId,
+			Kind:          activity.KindRoleSuggested,
+			ProjectId:     projectId,
+			RelatedUserId: userId,
+		})
+	})
+	if err != nil {
+		if !isSuggestionError(err) {
+			logger.WithError(err).Error("Failed to suggest role")
+		}
+
+		return RoleSuggestionDto{}, err
+	}
+
+	return roleSuggestionDto(suggestion), nil
+}
+
+func (s *suggestionsServiceImpl) ListRoleSuggestions(ctx context.Context, userId uint) ([]RoleSuggestionDto, error) {
+	var suggestions []RoleSuggestion
+	err := s.Db.
+		Where("to_user_id = ? AND status = ?", userId, SuggestionStatusPending).
+		Where("from_user_id NOT IN (?)", users.HiddenUserIds(s.Db, userId)).
+		Order("created_at desc").
+		Find(&suggestions).
+		Error
+	if err != nil {
+		log.FromContext(ctx).WithError(err).Error("Failed to list role suggestions")
+		return nil, err
+	}
+
+	dtos := make([]RoleSuggestionDto, len(suggestions))
+	for i, suggestion := range suggestions {
+		dtos[i] = roleSuggestionDto(suggestion)
+	}
+
+	return dtos, nil
+}
+
+func (s *suggestionsServiceImpl) AcceptRoleSuggestion(ctx context.Context, userId uint, suggestionId uint) error {
+	logger := log.FromContext(ctx).WithField("suggestionId", suggestionId)
+
+	logger.Debug("Accepting role suggestion")
+
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		suggestion, err := findPendingSuggestion(tx, suggestionId, userId)
+		if err != nil {
+			return err
+		}
+
+		// Lock the project while its team changes
+		err = tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").
+			First(&Project{}, suggestion.ProjectId).
+			Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrSuggestionNotFound
+			}
+
+			return err
+		}
+
+		_, err = findOpenRole(tx, suggestion.ProjectId, suggestion.RoleId)
+		if err != nil {
+			return err
+		}
+
+		var memberCount int64
+		err = tx.
+			Model(&ProjectMember{}).
+			Where("project_id = ? AND user_id = ?", suggestion.ProjectId, userId).
+			Count(&memberCount).
+			Error
+		if err != nil {
+			return err
+		}
+
+		// Users who joined the team some other way keep their role
+		if memberCount < 1 {
+			err = setMemberRole(tx, suggestion.ProjectId, userId, MemberRoleMember)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = tx.Model(suggestion).Update("status", SuggestionStatusAccepted).Error
+		if err != nil {
+			return err
+		}
+
+		return s.ActivityService.RecordActivities(ctx, tx, activity.Activity{
+			UserId:        suggestion.FromUserId,
+			Kind:          activity.KindRoleSuggestionAccepted,
+			ProjectId:     suggestion.ProjectId,
+			RelatedUserId: userId,
+		})
+	})
+	if err != nil {
+		if !isSuggestionError(err) {
+			logger.WithError(err).Error("Failed to accept role suggestion")
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (s *suggestionsServiceImpl) DeclineRoleSuggestion(ctx context.Context, userId uint, suggestionId uint) error {
+	logger := log.FromContext(ctx).WithField("suggestionId", suggestionId)
+
+	logger.Debug("Declining role suggestion")
+
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		suggestion, err := findPendingSuggestion(tx, suggestionId, userId)
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(suggestion).Update("status", SuggestionStatusDeclined).Error
+	})
+	if err != nil {
+		if !isSuggestionError(err) {
+			logger.WithError(err).Error("Failed to decline role suggestion")
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// Find one of a project's roles and lock it for update.
+// Returns ErrRoleNotFound if the project has no such role and ErrRoleClosed
+// if the role is closed.
+func findOpenRole(tx *gorm.DB, projectId uint, roleId uint) (*ProjectRole, error) {
+	role := &ProjectRole{}
+	err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("project_id = ?", projectId).
+		First(role, roleId).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRoleNotFound
+		}
+
+		return nil, err
+	}
+
+	if role.ClosedAt != nil {
+		return nil, ErrRoleClosed
+	}
+
+	return role, nil
+}
+
+// Find a suggestion sent to userId and lock it for update.
+// Returns ErrSuggestionNotFound if the suggestion can't be found and
+// ErrSuggestionNotPending if it was already answered.
+func findPendingSuggestion(tx *gorm.DB, suggestionId uint, userId uint) (*RoleSuggestion, error) {
+	suggestion := &RoleSuggestion{}
+	err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("to_user_id = ?", userId).
+		First(suggestion, suggestionId).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSuggestionNotFound
+		}
+
+		return nil, err
+	}
+
+	if suggestion.Status != SuggestionStatusPending {
+		return nil, ErrSuggestionNotPending
+	}
+
+	return suggestion, nil
+}
+
+// Whether err is one of the expected errors returned by the suggestions service.
+func isSuggestionError(err error) bool {
+	return errors.Is(err, ErrSuggestionNotFound) ||
+		errors.Is(err, ErrSuggestionNotPending) ||
+		errors.Is(err, ErrSuggestionAlreadySent) ||
+		errors.Is(err, ErrRoleNotFound) ||
+		errors.Is(err, ErrRoleClosed)
+}
+
+func roleSuggestionDto(suggestion RoleSuggestion) RoleSuggestionDto {
+	return RoleSuggestionDto{
+		Id:         suggestion.ID,
+		ProjectId:  suggestion.ProjectId,
+		RoleId:     suggestion.RoleId,
+		FromUserId: suggestion.FromUserId,
+		ToUserId:   suggestion.ToUserId,
+		Message:    suggestion.Message,
+		Status:     suggestion.Status,
+		CreatedAt:  suggestion.CreatedAt,
+	}
+}
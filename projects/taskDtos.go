@@ -0,0 +1,77 @@
+package projects
+
+import (
+	"github.com/lib/pq"
+	"time"
+)
+
+type NewMilestoneDto struct {
+	Title       string     `json:"title" validate:"required,min=2,max=100"`
+	Description string     `json:"description" validate:"max=2000"`
+	DueAt       *time.Time `json:"dueAt"`
+}
+
+type UpdateMilestoneDto struct {
+	Title       string     `json:"title" validate:"required,min=2,max=100"`
+	Description string     `json:"description" validate:"max=2000"`
+	DueAt       *time.Time `json:"dueAt"`
+	Closed      bool       `json:"closed"`
+}
+
+type MilestoneDto struct {
+	Id          uint       `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	DueAt       *time.Time `json:"dueAt"`
+	Closed      bool       `json:"closed"`
+	OpenTasks   int        `json:"openTasks"`
+	DoneTasks   int        `json:"doneTasks"`
+}
+
+type NewTaskDto struct {
+	Title       string     `json:"title" validate:"required,min=2,max=200"`
+	Description string     `json:"description" validate:"max=5000"`
+	Status      string     `json:"status" validate:"omitempty,oneof=todo in-progress done"`
+	AssigneeId  *uint      `json:"assigneeId"`
+	MilestoneId *uint      `json:"milestoneId"`
+	Labels      []string   `json:"labels" validate:"max=10,dive,min=1,max=40"`
+	DueAt       *time.Time `json:"dueAt"`
+}
+
+type UpdateTaskDto struct {
+	Title       string     `json:"title" validate:"required,min=2,max=200"`
+	Description string     `json:"description" validate:"max=5000"`
+	AssigneeId  *uint      `json:"assigneeId"`
+	MilestoneId *uint      `json:"milestoneId"`
+	Labels      []string   `json:"labels" validate:"max=10,dive,min=1,max=40"`
+	DueAt       *time.Time `json:"dueAt"`
+}
+
+// Move a task to a position in a status column, e.g. after dragging it
+// on the board. Positions past the end of the column move the task to the
+// end of it.
+type MoveTaskDto struct {
+	Status   string `json:"status" validate:"required,oneof=todo in-progress done"`
+	Position int    `json:"position" validate:"min=0"`
+}
+
+type TaskFilter struct {
+	Status      string
+	MilestoneId uint
+	AssigneeId  uint
+	Label       string
+}
+
+type TaskDto struct {
+	Id          uint           `json:"id"`
+	MilestoneId *uint          `json:"milestoneId"`
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	Status      string         `json:"status"`
+	AssigneeId  *uint          `json:"assigneeId"`
+	Labels      pq.StringArray `json:"labels" swaggertype:"array,string"`
+	DueAt       *time.Time     `json:"dueAt"`
+	Position    int            `json:"position"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+}
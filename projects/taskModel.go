@@ -0,0 +1,48 @@
+package projects
+
+import (
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Columns of a project's task board.
+const (
+	TaskStatusTodo       = "todo"
+	TaskStatusInProgress = "in-progress"
+	TaskStatusDone       = "done"
+)
+
+// Tasks with this label are listed on the project's summary, to point
+// newcomers to a place to start.
+const LabelGoodFirstTask = "good first task"
+
+type ProjectMilestone struct {
+	gorm.Model
+
+	ProjectId   uint
+	Title       string
+	Description string
+	DueAt       *time.Time
+	ClosedAt    *time.Time
+}
+
+type ProjectTask struct {
+	gorm.Model
+
+	ProjectId   uint
+	MilestoneId *uint
+	Title       string
+	Description string
+	Status      string
+
+	// Member of the project's team the task is assigned to.
+	AssigneeId *uint
+
+	Labels pq.StringArray `gorm:"type: TEXT[]"`
+	DueAt  *time.Time
+
+	// Position of the task in its status column, starting at 0. Positions
+	// in a column are always contiguous.
+	Position int
+}
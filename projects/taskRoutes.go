@@ -0,0 +1,350 @@
+package projects
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/open-collaboration/server/auth"
+	"github.com/open-collaboration/server/utils"
+	"net/http"
+)
+
+type listTasksParams struct {
+	Status string `validate:"omitempty,oneof=todo in-progress done"`
+}
+
+// @Summary List a project's milestones
+// @Tags tasks
+// @Router /projects/{projectId}/milestones [get]
+// @Param projectId path int true "The project ID"
+// @Success 200 {array} dtos.MilestoneDto
+func RouteListMilestones(
+	writer http.ResponseWriter,
+	request *http.Request,
+	tasksService TasksService,
+) error {
+	projectId, err := utils.UintFromVars(request, "projectId")
+	if err != nil {
+		return err
+	}
+
+	milestones, err := tasksService.ListMilestones(request.Context(), projectId)
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusOK, milestones)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Create a milestone
+// @Tags tasks
+// @Router /projects/{projectId}/milestones [post]
+// @Param projectId path int true "The project ID"
+// @Param milestone body dtos.NewMilestoneDto true "The milestone"
+// @Success 201 {object} dtos.MilestoneDto
+func RouteCreateMilestone(
+	writer http.ResponseWriter,
+	request *http.Request,
+	tasksService TasksService,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	projectId, err := utils.UintFromVars(request, "projectId")
+	if err != nil {
+		return err
+	}
+
+	dto := NewMilestoneDto{}
+	err = utils.ReadJson(request.Context(), request, &dto)
+	if err != nil {
+		return err
+	}
+
+	milestone, err := tasksService.CreateMilestone(request.Context(), session.UserId(), projectId, dto)
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusCreated, milestone)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Update or close a milestone
+// @Tags tasks
+// @Router /projects/{projectId}/milestones/{milestoneId} [put]
+// @Param projectId path int true "The project ID"
+// @Param milestoneId path int true "The milestone ID"
+// @Param milestone body dtos.UpdateMilestoneDto true "The milestone"
+// @Success 200 {object} dtos.MilestoneDto
+func RouteUpdateMilestone(
+	writer http.ResponseWriter,
+	request *http.Request,
+	tasksService TasksService,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	projectId, err := utils.UintFromVars(request, "projectId")
+	if err != nil {
+		return err
+	}
+
+	milestoneId, err := utils.UintFromVars(request, "milestoneId")
+	if err != nil {
+		return err
+	}
+
+	dto := UpdateMilestoneDto{}
+	err = utils.ReadJson(request.Context(), request, &dto)
+	if err != nil {
+		return err
+	}
+
+	milestone, err := tasksService.UpdateMilestone(request.Context(), session.UserId(), projectId, milestoneId, dto)
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusOK, milestone)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary List a project's tasks
+// @Tags tasks
+// @Router /projects/{projectId}/tasks [get]
+// @Param projectId path int true "The project ID"
+// @Param status query string false "Only list tasks with this status (todo, in-progress or done)"
+// @Param milestoneId query int false "Only list tasks of this milestone"
+// @Param assigneeId query int false "Only list tasks assigned to this user"
+// @Param label query string false "Only list tasks with this label, e.g. \"good first task\""
+// @Success 200 {array} dtos.TaskDto
+func RouteListTasks(
+	writer http.ResponseWriter,
+	request *http.Request,
+	tasksService TasksService,
+) error {
+	projectId, err := utils.UintFromVars(request, "projectId")
+	if err != nil {
+		return err
+	}
+
+	query := request.URL.Query()
+
+	params := listTasksParams{Status: query.Get("status")}
+	err = validator.New().Struct(params)
+	if err != nil {
+		return err
+	}
+
+	milestoneId, _ := utils.IntFromQuery(request, "milestoneId", 0)
+	assigneeId, _ := utils.IntFromQuery(request, "assigneeId", 0)
+
+	if milestoneId < 0 {
+		milestoneId = 0
+	}
+
+	if assigneeId < 0 {
+		assigneeId = 0
+	}
+
+	tasks, err := tasksService.ListTasks(request.Context(), projectId, TaskFilter{
+		Status:      params.Status,
+		MilestoneId: uint(milestoneId),
+		AssigneeId:  uint(assigneeId),
+		Label:       query.Get("label"),
+	})
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusOK, tasks)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Create a task
+// @Tags tasks
+// @Router /projects/{projectId}/tasks [post]
+// @Param projectId path int true "The project ID"
+// @Param task body dtos.NewTaskDto true "The task"
+// @Success 201 {object} dtos.TaskDto
+func RouteCreateTask(
+	writer http.ResponseWriter,
+	request *http.Request,
+	tasksService TasksService,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	projectId, err := utils.UintFromVars(request, "projectId")
+	if err != nil {
+		return err
+	}
+
+	dto := NewTaskDto{}
+	err = utils.ReadJson(request.Context(), request, &dto)
+	if err != nil {
+		return err
+	}
+
+	task, err := tasksService.CreateTask(request.Context(), session.UserId(), projectId, dto)
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusCreated, task)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Update a task
+// @Tags tasks
+// @Router /projects/{projectId}/tasks/{taskId} [put]
+// @Param projectId path int true "The project ID"
+// @Param taskId path int true "The task ID"
+// @Param task body dtos.UpdateTaskDto true "The task"
+// @Success 200 {object} dtos.TaskDto
+func RouteUpdateTask(
+	writer http.ResponseWriter,
+	request *http.Request,
+	tasksService TasksService,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	projectId, err := utils.UintFromVars(request, "projectId")
+	if err != nil {
+		return err
+	}
+
+	taskId, err := utils.UintFromVars(request, "taskId")
+	if err != nil {
+		return err
+	}
+
+	dto := UpdateTaskDto{}
+	err = utils.ReadJson(request.Context(), request, &dto)
+	if err != nil {
+		return err
+	}
+
+	task, err := tasksService.UpdateTask(request.Context(), session.UserId(), projectId, taskId, dto)
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusOK, task)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Move a task to another position or status column
+// @Tags tasks
+// @Router /projects/{projectId}/tasks/{taskId}/move [post]
+// @Param projectId path int true "The project ID"
+// @Param taskId path int true "The task ID"
+// @Param move body dtos.MoveTaskDto true "Where to move the task"
+// @Success 200 {object} dtos.TaskDto
+func RouteMoveTask(
+	writer http.ResponseWriter,
+	request *http.Request,
+	tasksService TasksService,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	projectId, err := utils.UintFromVars(request, "projectId")
+	if err != nil {
+		return err
+	}
+
+	taskId, err := utils.UintFromVars(request, "taskId")
+	if err != nil {
+		return err
+	}
+
+	dto := MoveTaskDto{}
+	err = utils.ReadJson(request.Context(), request, &dto)
+	if err != nil {
+		return err
+	}
+
+	task, err := tasksService.MoveTask(request.Context(), session.UserId(), projectId, taskId, dto)
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusOK, task)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Delete a task
+// @Tags tasks
+// @Router /projects/{projectId}/tasks/{taskId} [delete]
+// @Param projectId path int true "The project ID"
+// @Param taskId path int true "The task ID"
+// @Success 204
+func RouteDeleteTask(
+	writer http.ResponseWriter,
+	request *http.Request,
+	tasksService TasksService,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	projectId, err := utils.UintFromVars(request, "projectId")
+	if err != nil {
+		return err
+	}
+
+	taskId, err := utils.UintFromVars(request, "taskId")
+	if err != nil {
+		return err
+	}
+
+	err = tasksService.DeleteTask(request.Context(), session.UserId(), projectId, taskId)
+	if err != nil {
+		return err
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
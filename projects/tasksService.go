@@ -0,0 +1,747 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"github.com/apex/log"
+	"github.com/go-playground/validator/v10"
+	"github.com/open-collaboration/server/auth"
+	"github.com/open-collaboration/server/events"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+var ErrMilestoneNotFound = errors.New("milestone not found")
+var ErrTaskNotFound = errors.New("task not found")
+var ErrInvalidAssignee = errors.New("task assignee is not a member of the project")
+
+// Orders tasks by board column and then by their position in the column.
+const taskBoardOrder = "CASE status WHEN '" + TaskStatusTodo + "' THEN 0 " +
+	"WHEN '" + TaskStatusInProgress + "' THEN 1 ELSE 2 END, position"
+
+type TasksService interface {
+	// List a project's milestones, with their amount of open and done tasks.
+	// Returns ErrProjectNotFound if the project can't be found or is hidden.
+	ListMilestones(ctx context.Context, projectId uint) ([]MilestoneDto, error)
+
+	// Create a milestone. Only the project's owner and maintainers can
+	// manage milestones.
+	// Returns ErrProjectNotFound if the project can't be found or is hidden
+	// and auth.ErrForbidden if userId can't manage the project.
+	CreateMilestone(ctx context.Context, userId uint, projectId uint, newMilestone NewMilestoneDto) (MilestoneDto, error)
+
+	// Update or close a milestone.
+	// Returns the same errors as CreateMilestone and ErrMilestoneNotFound if
+	// the milestone isn't one of the project's.
+	UpdateMilestone(
+		ctx context.Context,
+		userId uint,
+		projectId uint,
+		milestoneId uint,
+		milestone UpdateMilestoneDto,
+	) (MilestoneDto, error)
+
+	// List a project's tasks, ordered by status column (todo, in-progress
+	// and done) and by position inside each column. Zero values in the
+	// filter are ignored.
+	// Returns ErrProjectNotFound if the project can't be found or is hidden.
+	ListTasks(ctx context.Context, projectId uint, filter TaskFilter) ([]TaskDto, error)
+
+	// Create a task at the end of its status column. Any member of the
+	// project's team can manage tasks.
+	// Returns ErrProjectNotFound if the project can't be found or is hidden,
+	// auth.ErrForbidden if userId is not a member of the project,
+	// ErrInvalidAssignee if the assignee is not a member of the project and
+	// ErrMilestoneNotFound if the milestone isn't one of the project's.
+	CreateTask(ctx context.Context, userId uint, projectId uint, newTask NewTaskDto) (TaskDto, error)
+
+	// Update a task's details. Use MoveTask to change its status or position.
+	// Returns the same errors as CreateTask and ErrTaskNotFound if the task
+	// isn't one of the project's.
+	UpdateTask(ctx context.Context, userId uint, projectId uint, taskId uint, task UpdateTaskDto) (TaskDto, error)
+
+	// Move a task to a position in a status column. The other tasks of the
+	// affected columns are shifted to keep positions contiguous.
+	// Returns ErrProjectNotFound if the project can't be found or is hidden,
+	// auth.ErrForbidden if userId is not a member of the project and
+	// ErrTaskNotFound if the task isn't one of the project's.
+	MoveTask(ctx context.Context, userId uint, projectId uint, taskId uint, move MoveTaskDto) (TaskDto, error)
+
+	// Delete a task.
+	// Returns the same errors as MoveTask.
+	DeleteTask(ctx context.Context, userId uint, projectId uint, taskId uint) error
+}
+
+type tasksServiceImpl struct {
+	Db              *gorm.DB
+	ProjectsService Service
+	Events          events.Publisher
+}
+
+func NewTasksService(db *gorm.DB, projectsService Service, publisher events.Publisher) TasksService {
+	return &tasksServiceImpl{
+		Db:              db,
+		ProjectsService: projectsService,
+		Events:          publisher,
+	}
+}
+
+func (s *tasksServiceImpl) ListMilestones(ctx context.Context, projectId uint) ([]MilestoneDto, error) {
+	logger := log.FromContext(ctx).WithField("projectId", projectId)
+
+	err := s.ProjectsService.CheckCanView(ctx, projectId)
+	if err != nil {
+		return nil, err
+	}
+
+	var milestones []ProjectMilestone
+	err = s.Db.
+		Where("project_id = ?", projectId).
+		Order("closed_at IS NOT NULL, due_at IS NULL, due_at, id").
+		Find(&milestones).
+		Error
+	if err != nil {
+		logger.WithError(err).Error("Failed to list milestones")
+		return nil, err
+	}
+
+	var counts []struct {
+		MilestoneId uint
+		Done        bool
+		Count       int
+	}
+	err = s.Db.
+		Model(&ProjectTask{}).
+		Select("milestone_id, status = ? AS done, count(*) AS count", TaskStatusDone).
+		Where("project_id = ? AND milestone_id IS NOT NULL", projectId).
+		Group("milestone_id, done").
+		Scan(&counts).
+		Error
+	if err != nil {
+		logger.WithError(err).Error("Failed to count milestone tasks")
+		return nil, err
+	}
+
+	dtos := make([]MilestoneDto, len(milestones))
+	indexes := map[uint]int{}
+	for i, milestone := range milestones {
+		dtos[i] = milestoneDto(milestone)
+		indexes[milestone.ID] = i
+	}
+
+	for _, count := range counts {
+		i, ok := indexes[count.MilestoneId]
+		if !ok {
+			continue
+		}
+
+		if count.Done {
+			dtos[i].DoneTasks = count.Count
+		} else {
+			dtos[i].OpenTasks = count.Count
+		}
+	}
+
+	return dtos, nil
+}
+
+func (s *tasksServiceImpl) CreateMilestone(
+	ctx context.Context,
+	userId uint,
+	projectId uint,
+	newMilestone NewMilestoneDto,
+) (MilestoneDto, error) {
+	logger := log.FromContext(ctx).WithField("projectId", projectId)
+
+	err := validator.New().Struct(newMilestone)
+	if err != nil {
+		return MilestoneDto{}, err
+	}
+
+	err = s.checkMember(ctx, userId, projectId, true)
+	if err != nil {
+		return MilestoneDto{}, err
+	}
+
+	milestone := ProjectMilestone{
+		ProjectId:   projectId,
+		Title:       newMilestone.Title,
+		Description: newMilestone.Description,
+		DueAt:       newMilestone.DueAt,
+	}
+
+	err = s.Db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(&milestone).Error
+		if err != nil {
+			return err
+		}
+
+		return s.Events.Publish(ctx, tx, events.Event{
+			Type:      events.MilestoneCreated,
+			ProjectId: projectId,
+			ActorId:   userId,
+			Data:      milestoneDto(milestone),
+		})
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to create milestone")
+		return MilestoneDto{}, err
+	}
+
+	return milestoneDto(milestone), nil
+}
+
+func (s *tasksServiceImpl) UpdateMilestone(
+	ctx context.Context,
+	userId uint,
+	projectId uint,
+	milestoneId uint,
+	milestoneData UpdateMilestoneDto,
+) (MilestoneDto, error) {
+	logger := log.FromContext(ctx).WithFields(log.Fields{
+		"projectId":   projectId,
+		"milestoneId": milestoneId,
+	})
+
+	err := validator.New().Struct(milestoneData)
+	if err != nil {
+		return MilestoneDto{}, err
+	}
+
+	err = s.checkMember(ctx, userId, projectId, true)
+	if err != nil {
+		return MilestoneDto{}, err
+	}
+
+	milestone := ProjectMilestone{}
+	err = s.Db.Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("project_id = ?", projectId).
+			First(&milestone, milestoneId).
+			Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrMilestoneNotFound
+			}
+
+			return err
+		}
+
+		milestone.Title = milestoneData.Title
+		milestone.Description = milestoneData.Description
+		milestone.DueAt = milestoneData.DueAt
+
+		if !milestoneData.Closed {
+			milestone.ClosedAt = nil
+		} else if milestone.ClosedAt == nil {
+			now := time.Now()
+			milestone.ClosedAt = &now
+		}
+
+		err = tx.
+			Select("title", "description", "due_at", "closed_at").
+			Updates(&milestone).
+			Error
+		if err != nil {
+			return err
+		}
+
+		return s.Events.Publish(ctx, tx, events.Event{
+			Type:      events.MilestoneUpdated,
+			ProjectId: projectId,
+			ActorId:   userId,
+			Data:      milestoneDto(milestone),
+		})
+	})
+	if err != nil {
+		if !errors.Is(err, ErrMilestoneNotFound) {
+			logger.WithError(err).Error("Failed to update milestone")
+		}
+
+		return MilestoneDto{}, err
+	}
+
+	return milestoneDto(milestone), nil
+}
+
+func (s *tasksServiceImpl) ListTasks(ctx context.Context, projectId uint, filter TaskFilter) ([]TaskDto, error) {
+	logger := log.FromContext(ctx).WithField("projectId", projectId)
+
+	err := s.ProjectsService.CheckCanView(ctx, projectId)
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.Db.Where("project_id = ?", projectId)
+
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	if filter.MilestoneId != 0 {
+		query = query.Where("milestone_id = ?", filter.MilestoneId)
+	}
+
+	if filter.AssigneeId != 0 {
+		query = query.Where("assignee_id = ?", filter.AssigneeId)
+	}
+
+	if filter.Label != "" {
+		query = query.Where("? = ANY(labels)", filter.Label)
+	}
+
+	var tasks []ProjectTask
+	err = query.Order(taskBoardOrder).Find(&tasks).Error
+	if err != nil {
+		logger.WithError(err).Error("Failed to list tasks")
+		return nil, err
+	}
+
+	dtos := make([]TaskDto, len(tasks))
+	for i, task := range tasks {
+		dtos[i] = taskDto(task)
+	}
+
+	return dtos, nil
+}
+
+func (s *tasksServiceImpl) CreateTask(ctx context.Context, userId uint, projectId uint, newTask NewTaskDto) (TaskDto, error) {
+	logger := log.FromContext(ctx).WithField("projectId", projectId)
+
+	err := validator.New().Struct(newTask)
+	if err != nil {
+		return TaskDto{}, err
+	}
+
+	err = s.checkMember(ctx, userId, projectId, false)
+	if err != nil {
+		return TaskDto{}, err
+	}
+
+	if newTask.Status == "" {
+		newTask.Status = TaskStatusTodo
+	}
+
+	if newTask.Labels == nil {
+		newTask.Labels = []string{}
+	}
+
+	task := ProjectTask{
+		ProjectId:   projectId,
+		MilestoneId: newTask.MilestoneId,
+		Title:       newTask.Title,
+		Description: newTask.Description,
+		Status:      newTask.Status,
+		AssigneeId:  newTask.AssigneeId,
+		Labels:      newTask.Labels,
+		DueAt:       newTask.DueAt,
+	}
+
+	err = s.Db.Transaction(func(tx *gorm.DB) error {
+		err := checkBoardProject(tx, projectId, true)
+		if err != nil {
+			return err
+		}
+
+		err = checkTaskReferences(tx, projectId, task.AssigneeId, task.MilestoneId)
+		if err != nil {
+			return err
+		}
+
+		task.Position, err = columnSize(tx, projectId, task.Status, 0)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Create(&task).Error
+		if err != nil {
+			return err
+		}
+
+		return s.Events.Publish(ctx, tx, events.Event{
+			Type:      events.TaskCreated,
+			ProjectId: projectId,
+			ActorId:   userId,
+			Data:      taskDto(task),
+		})
+	})
+	if err != nil {
+		if !isTaskError(err) {
+			logger.WithError(err).Error("Failed to create task")
+		}
+
+		return TaskDto{}, err
+	}
+
+	return taskDto(task), nil
+}
+
+func (s *tasksServiceImpl) UpdateTask(
+	ctx context.Context,
+	userId uint,
+	projectId uint,
+	taskId uint,
+	taskData UpdateTaskDto,
+) (TaskDto, error) {
+	logger := log.FromContext(ctx).WithFields(log.Fields{
+		"projectId": projectId,
+		"taskId":    taskId,
+	})
+
+	err := validator.New().Struct(taskData)
+	if err != nil {
+		return TaskDto{}, err
+	}
+
+	err = s.checkMember(ctx, userId, projectId, false)
+	if err != nil {
+		return TaskDto{}, err
+	}
+
+	if taskData.Labels == nil {
+		taskData.Labels = []string{}
+	}
+
+	var task *ProjectTask
+	err = s.Db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		task, err = findTask(tx, projectId, taskId)
+		if err != nil {
+			return err
+		}
+
+		err = checkTaskReferences(tx, projectId, taskData.AssigneeId, taskData.MilestoneId)
+		if err != nil {
+			return err
+		}
+
+		task.Title = taskData.Title
+		task.Description = taskData.Description
+		task.AssigneeId = taskData.AssigneeId
+		task.MilestoneId = taskData.MilestoneId
+		task.Labels = taskData.Labels
+		task.DueAt = taskData.DueAt
+
+		err = tx.
+			Select("title", "description", "assignee_id", "milestone_id", "labels", "due_at").
+			Updates(task).
+			Error
+		if err != nil {
+			return err
+		}
+
+		return s.Events.Publish(ctx, tx, events.Event{
+			Type:      events.TaskUpdated,
+			ProjectId: projectId,
+			ActorId:   userId,
+			Data:      taskDto(*task),
+		})
+	})
+	if err != nil {
+		if !isTaskError(err) {
+			logger.WithError(err).Error("Failed to update task")
+		}
+
+		return TaskDto{}, err
+	}
+
+	return taskDto(*task), nil
+}
+
+func (s *tasksServiceImpl) MoveTask(
+	ctx context.Context,
+	userId uint,
+	projectId uint,
+	taskId uint,
+	move MoveTaskDto,
+) (TaskDto, error) {
+	logger := log.FromContext(ctx).WithFields(log.Fields{
+		"projectId": projectId,
+		"taskId":    taskId,
+		"status":    move.Status,
+		"position":  move.Position,
+	})
+
+	err := validator.New().Struct(move)
+	if err != nil {
+		return TaskDto{}, err
+	}
+
+	err = s.checkMember(ctx, userId, projectId, false)
+	if err != nil {
+		return TaskDto{}, err
+	}
+
+	logger.Debug("Moving task")
+
+	var task *ProjectTask
+	err = s.Db.Transaction(func(tx *gorm.DB) error {
+		err := checkBoardProject(tx, projectId, true)
+		if err != nil {
+			return err
+		}
+
+		task, err = findTask(tx, projectId, taskId)
+		if err != nil {
+			return err
+		}
+
+		// Close the gap the task leaves in its current column...
+		err = shiftColumn(tx, projectId, task.Status, task.Position+1, taskId, -1)
+		if err != nil {
+			return err
+		}
+
+		size, err := columnSize(tx, projectId, move.Status, taskId)
+		if err != nil {
+			return err
+		}
+
+		position := move.Position
+		if position > size {
+			position = size
+		}
+
+		// ...and open one where it's dropped.
+		err = shiftColumn(tx, projectId, move.Status, position, taskId, 1)
+		if err != nil {
+			return err
+		}
+
+		task.Status = move.Status
+		task.Position = position
+
+		err = tx.Select("status", "position").Updates(task).Error
+		if err != nil {
+			return err
+		}
+
+		return s.Events.Publish(ctx, tx, events.Event{
+			Type:      events.TaskMoved,
+			ProjectId: projectId,
+			ActorId:   userId,
+			Data:      taskDto(*task),
+		})
+	})
+	if err != nil {
+		if !isTaskError(err) {
+			logger.WithError(err).Error("Failed to move task")
+		}
+
+		return TaskDto{}, err
+	}
+
+	return taskDto(*task), nil
+}
+
+func (s *tasksServiceImpl) DeleteTask(ctx context.Context, userId uint, projectId uint, taskId uint) error {
+	logger := log.FromContext(ctx).WithFields(log.Fields{
+		"projectId": projectId,
+		"taskId":    taskId,
+	})
+
+	err := s.checkMember(ctx, userId, projectId, false)
+	if err != nil {
+		return err
+	}
+
+	err = s.Db.Transaction(func(tx *gorm.DB) error {
+		err := checkBoardProject(tx, projectId, true)
+		if err != nil {
+			return err
+		}
+
+		task, err := findTask(tx, projectId, taskId)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Delete(task).Error
+		if err != nil {
+			return err
+		}
+
+		err = shiftColumn(tx, projectId, task.Status, task.Position+1, taskId, -1)
+		if err != nil {
+			return err
+		}
+
+		return s.Events.Publish(ctx, tx, events.Event{
+			Type:      events.TaskDeleted,
+			ProjectId: projectId,
+			ActorId:   userId,
+			Data:      taskDto(*task),
+		})
+	})
+	if err != nil {
+		if !isTaskError(err) {
+			logger.WithError(err).Error("Failed to delete task")
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// Check that a visible project exists and that userId is a member of its
+// team. If manage is set, userId must also be an owner or a maintainer.
+// Returns ErrProjectNotFound or auth.ErrForbidden if not.
+func (s *tasksServiceImpl) checkMember(ctx context.Context, userId uint, projectId uint, manage bool) error {
+	err := checkBoardProject(s.Db, projectId, false)
+	if err != nil {
+		if !errors.Is(err, ErrProjectNotFound) {
+			log.FromContext(ctx).WithError(err).Error("Failed to find project")
+		}
+
+		return err
+	}
+
+	role, err := s.ProjectsService.GetMemberRole(ctx, projectId, userId)
+	if err != nil {
+		return err
+	}
+
+	if role == "" || (manage && role != MemberRoleOwner && role != MemberRoleMaintainer) {
+		return auth.ErrForbidden
+	}
+
+	return nil
+}
+
+// Check that a project exists and isn't hidden. If lock is set, the project
+// is locked until the transaction ends, so that concurrent changes to its
+// board can't mix up task positions.
+// Returns ErrProjectNotFound if the project can't be found or is hidden.
+func checkBoardProject(db *gorm.DB, projectId uint, lock bool) error {
+	query := db.Select("id").Where("hidden_at IS NULL")
+	if lock {
+		query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+
+	err := query.First(&Project{}, projectId).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrProjectNotFound
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// Find one of a project's tasks.
+// Returns ErrTaskNotFound if the task can't be found.
+func findTask(tx *gorm.DB, projectId uint, taskId uint) (*ProjectTask, error) {
+	task := &ProjectTask{}
+	err := tx.
+		Where("project_id = ?", projectId).
+		First(task, taskId).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTaskNotFound
+		}
+
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// Check that a task's assignee is a member of the project and that its
+// milestone is one of the project's. Nil ids are not checked.
+func checkTaskReferences(tx *gorm.DB, projectId uint, assigneeId *uint, milestoneId *uint) error {
+	if assigneeId != nil {
+		var count int64
+		err := tx.
+			Model(&ProjectMember{}).
+			Where("project_id = ? AND user_id = ?", projectId, *assigneeId).
+			Count(&count).
+			Error
+		if err != nil {
+			return err
+		}
+
+		if count < 1 {
+			return ErrInvalidAssignee
+		}
+	}
+
+	if milestoneId != nil {
+		var count int64
+		err := tx.
+			Model(&ProjectMilestone{}).
+			Where("project_id = ? AND id = ?", projectId, *milestoneId).
+			Count(&count).
+			Error
+		if err != nil {
+			return err
+		}
+
+		if count < 1 {
+			return ErrMilestoneNotFound
+		}
+	}
+
+	return nil
+}
+
+// Count the tasks in a status column, leaving out excludedTaskId.
+func columnSize(tx *gorm.DB, projectId uint, status string, excludedTaskId uint) (int, error) {
+	var size int64
+	err := tx.
+		Model(&ProjectTask{}).
+		Where("project_id = ? AND status = ? AND id <> ?", projectId, status, excludedTaskId).
+		Count(&size).
+		Error
+
+	return int(size), err
+}
+
+// Add delta to the position of the tasks in a status column that are at
+// fromPosition or after it, leaving out excludedTaskId.
+func shiftColumn(tx *gorm.DB, projectId uint, status string, fromPosition int, excludedTaskId uint, delta int) error {
+	return tx.
+		Model(&ProjectTask{}).
+		Where("project_id = ? AND status = ? AND position >= ? AND id <> ?", projectId, status, fromPosition, excludedTaskId).
+		UpdateColumn("position", gorm.Expr("position + ?", delta)).
+		Error
+}
+
+// Whether err is one of the expected errors returned by the tasks service.
+func isTaskError(err error) bool {
+	return errors.Is(err, ErrTaskNotFound) ||
+		errors.Is(err, ErrMilestoneNotFound) ||
+		errors.Is(err, ErrInvalidAssignee) ||
+		errors.Is(err, ErrProjectNotFound)
+}
+
+func milestoneDto(milestone ProjectMilestone) MilestoneDto {
+	return MilestoneDto{
+		Id:          milestone.ID,
+		Title:       milestone.Title,
+		Description: milestone.Description,
+		DueAt:       milestone.DueAt,
+		Closed:      milestone.ClosedAt != nil,
+	}
+}
+
+func taskDto(task ProjectTask) TaskDto {
+	return TaskDto{
+		Id:          task.ID,
+		MilestoneId: task.MilestoneId,
+		Title:       task.Title,
+		Description: task.Description,
+		Status:      task.Status,
+		AssigneeId:  task.AssigneeId,
+		Labels:      task.Labels,
+		DueAt:       task.DueAt,
+		Position:    task.Position,
+		CreatedAt:   task.CreatedAt,
+		UpdatedAt:   task.UpdatedAt,
+	}
+}
@@ -0,0 +1,17 @@
+package projects
+
+import "time"
+
+type NewTransferDto struct {
+	RecipientId uint `json:"recipientId" validate:"required"`
+}
+
+type TransferDto struct {
+	Id         uint      `json:"id"`
+	ProjectId  uint      `json:"projectId"`
+	FromUserId uint      `json:"fromUserId"`
+	ToUserId   uint      `json:"toUserId"`
+	Status     string    `json:"status"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
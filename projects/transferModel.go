@@ -0,0 +1,31 @@
+package projects
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	TransferStatusPending   = "pending"
+	TransferStatusAccepted  = "accepted"
+	TransferStatusDeclined  = "declined"
+	TransferStatusCancelled = "cancelled"
+	TransferStatusExpired   = "expired"
+)
+
+// A request from a project's owner to hand the project over to another
+// user. The recipient has until ExpiresAt to accept it.
+type ProjectTransfer struct {
+	gorm.Model
+
+	ProjectId  uint
+	FromUserId uint
+	ToUserId   uint
+	Status     string
+	ExpiresAt  time.Time
+}
+
+// Whether the transfer can still be accepted, declined or cancelled.
+func (transfer *ProjectTransfer) IsPending() bool {
+	return transfer.Status == TransferStatusPending && time.Now().Before(transfer.ExpiresAt)
+}
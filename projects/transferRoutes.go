@@ -0,0 +1,157 @@
+package projects
+
+import (
+	"context"
+	"github.com/open-collaboration/server/auth"
+	"github.com/open-collaboration/server/users"
+	"github.com/open-collaboration/server/utils"
+	"net/http"
+	"strconv"
+)
+
+// @Summary Start transferring a project to another user
+// @Tags projects
+// @Router /projects/{projectId}/transfers [post]
+// @Param projectId path int true "The project ID"
+// @Param transfer body dtos.NewTransferDto true "The transfer recipient"
+// @Success 201 {object} dtos.TransferDto
+func RouteStartTransfer(
+	writer http.ResponseWriter,
+	request *http.Request,
+	transfersService TransfersService,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	projectId, err := utils.UintFromVars(request, "projectId")
+	if err != nil {
+		return err
+	}
+
+	dto := NewTransferDto{}
+	err = utils.ReadJson(request.Context(), request, &dto)
+	if err != nil {
+		return err
+	}
+
+	transfer, err := transfersService.StartTransfer(request.Context(), session.UserId(), projectId, dto)
+	if err != nil {
+		return err
+	}
+
+	writer.Header().Set("Location", "/project-transfers/"+strconv.Itoa(int(transfer.Id)))
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusCreated, transfer)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary List your pending project transfers, sent and received
+// @Tags projects
+// @Router /users/me/project-transfers [get]
+// @Success 200 {array} dtos.TransferDto
+func RouteListPendingTransfers(
+	writer http.ResponseWriter,
+	request *http.Request,
+	transfersService TransfersService,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	transfers, err := transfersService.ListPendingTransfers(request.Context(), session.UserId())
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusOK, transfers)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Accept a project transfer sent to you
+// @Description Only users with a verified email can accept transfers.
+// @Tags projects
+// @Router /project-transfers/{transferId}/accept [post]
+// @Param transferId path int true "The transfer ID"
+// @Success 204
+func RouteAcceptTransfer(
+	writer http.ResponseWriter,
+	request *http.Request,
+	transfersService TransfersService,
+	usersService users.Service,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	err = usersService.CheckEmailVerified(request.Context(), session.UserId())
+	if err != nil {
+		return err
+	}
+
+	return answerTransfer(writer, request, transfersService.AcceptTransfer)
+}
+
+// @Summary Decline a project transfer sent to you
+// @Tags projects
+// @Router /project-transfers/{transferId}/decline [post]
+// @Param transferId path int true "The transfer ID"
+// @Success 204
+func RouteDeclineTransfer(
+	writer http.ResponseWriter,
+	request *http.Request,
+	transfersService TransfersService,
+) error {
+	return answerTransfer(writer, request, transfersService.DeclineTransfer)
+}
+
+// @Summary Cancel a project transfer you started
+// @Tags projects
+// @Router /project-transfers/{transferId}/cancel [post]
+// @Param transferId path int true "The transfer ID"
+// @Success 204
+func RouteCancelTransfer(
+	writer http.ResponseWriter,
+	request *http.Request,
+	transfersService TransfersService,
+) error {
+	return answerTransfer(writer, request, transfersService.CancelTransfer)
+}
+
+// Call `answer` with the session's user and the transfer id in the
+// route and respond with a 204.
+func answerTransfer(
+	writer http.ResponseWriter,
+	request *http.Request,
+	answer func(ctx context.Context, userId uint, transferId uint) error,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	transferId, err := utils.UintFromVars(request, "transferId")
+	if err != nil {
+		return err
+	}
+
+	err = answer(request.Context(), session.UserId(), transferId)
+	if err != nil {
+		return err
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
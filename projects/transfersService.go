@@ -0,0 +1,408 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"github.com/apex/log"
+	"github.com/go-playground/validator/v10"
+	"github.com/open-collaboration/server/activity"
+	"github.com/open-collaboration/server/auth"
+	"github.com/open-collaboration/server/events"
+	"github.com/open-collaboration/server/users"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+var ErrTransferNotFound = errors.New("project transfer not found")
+var ErrTransferNotPending = errors.New("project transfer is no longer pending")
+var ErrTransferAlreadyPending = errors.New("project already has a pending transfer")
+var ErrInvalidRecipient = errors.New("invalid recipient")
+
+type TransfersService interface {
+	// Start transferring a project to another user. Only the project's owner
+	// can start a transfer and there can only be one pending transfer per
+	// project at a time.
+	// Returns auth.ErrForbidden if userId is not the project's owner,
+	// ErrInvalidRecipient if the recipient doesn't exist or is the owner,
+	// users.ErrBlocked if the recipient blocked the owner and
+	// ErrTransferAlreadyPending if the owner already started a transfer that
+	// hasn't been answered yet.
+	StartTransfer(ctx context.Context, userId uint, projectId uint, newTransfer NewTransferDto) (TransferDto, error)
+
+	// List the pending transfers sent or received by a user. Transfers
+	// received from users the user blocked or muted are left out.
+	ListPendingTransfers(ctx context.Context, userId uint) ([]TransferDto, error)
+
+	// Accept a transfer. The project's ownership is moved to the recipient and
+	// the previous owner stays in the team as a maintainer. Both users get an
+	// activity record.
+	// Returns ErrTransferNotFound if the transfer doesn't exist or wasn't sent to
+	// userId, ErrTransferNotPending if it was already answered, cancelled or
+	// has expired and a *QuotaExceededError if the recipient can't own any
+	// more projects.
+	AcceptTransfer(ctx context.Context, userId uint, transferId uint) error
+
+	// Decline a transfer sent to userId.
+	// Returns ErrTransferNotFound if the transfer doesn't exist or wasn't sent to
+	// userId and ErrTransferNotPending if it's not pending anymore.
+	DeclineTransfer(ctx context.Context, userId uint, transferId uint) error
+
+	// Cancel a transfer sent by userId.
+	// Returns ErrTransferNotFound if the transfer doesn't exist or wasn't sent by
+	// userId and ErrTransferNotPending if it's not pending anymore.
+	CancelTransfer(ctx context.Context, userId uint, transferId uint) error
+}
+
+type transfersServiceImpl struct {
+	Db              *gorm.DB
+	UsersService    users.Service
+	BlocksService   users.BlocksService
+	ProjectsService Service
+	ActivityService activity.Service
+	Events          events.Publisher
+
+	// How long a recipient has to accept a transfer.
+	Expiry time.Duration
+}
+
+func NewTransfersService(
+	db *gorm.DB,
+	usersService users.Service,
+	blocksService users.BlocksService,
+	projectsService Service,
+	activityService activity.Service,
+	publisher events.Publisher,
+	expiry time.Duration,
+) TransfersService {
+	return &transfersServiceImpl{
+		Db:              db,
+		UsersService:    usersService,
+		BlocksService:   blocksService,
+		ProjectsService: projectsService,
+		ActivityService: activityService,
+		Events:          publisher,
+		Expiry:          expiry,
+	}
+}
+
+func (s *transfersServiceImpl) StartTransfer(
+	ctx context.Context,
+	userId uint,
+	projectId uint,
+	newTransfer NewTransferDto,
+) (TransferDto, error) {
+	logger := log.FromContext(ctx).WithFields(log.Fields{
+		"projectId":   projectId,
+		"recipientId": newTransfer.RecipientId,
+	})
+
+	err := validator.New().Struct(newTransfer)
+	if err != nil {
+		return TransferDto{}, err
+	}
+
+	if newTransfer.RecipientId == userId {
+		return TransferDto{}, ErrInvalidRecipient
+	}
+
+	_, err = s.UsersService.GetUser(ctx, newTransfer.RecipientId)
+	if err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			return TransferDto{}, ErrInvalidRecipient
+		}
+
+		return TransferDto{}, err
+	}
+
+	err = s.BlocksService.CheckCanInteract(ctx, userId, newTransfer.RecipientId)
+	if err != nil {
+		return TransferDto{}, err
+	}
+
+	logger.Debug("Starting project transfer")
+
+	transfer := ProjectTransfer{}
+	err = s.Db.Transaction(func(tx *gorm.DB) error {
+		project := Project{}
+		err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "owner_id").
+			First(&project, projectId).
+			Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrProjectNotFound
+			}
+
+			return err
+		}
+
+		if project.OwnerId != userId {
+			return auth.ErrForbidden
+		}
+
+		// Pending transfers that expired still hold the project's pending
+		// transfer slot, release it.
+		err = tx.
+			Model(&ProjectTransfer{}).
+			Where("project_id = ? AND status = ? AND expires_at <= ?", projectId, TransferStatusPending, time.Now()).
+			Update("status", TransferStatusExpired).
+			Error
+		if err != nil {
+			return err
+		}
+
+		var pendingCount int64
+		err = tx.
+			Model(&ProjectTransfer{}).
+			Where("project_id = ? AND from_user_id = ? AND status = ?", projectId, userId, TransferStatusPending).
+			Count(&pendingCount).
+			Error
+		if err != nil {
+			return err
+		}
+
+		if pendingCount > 0 {
+			return ErrTransferAlreadyPending
+		}
+
+		transfer = ProjectTransfer{
+			ProjectId:  projectId,
+			FromUserId: userId,
+			ToUserId:   newTransfer.RecipientId,
+			Status:     TransferStatusPending,
+			ExpiresAt:  time.Now().Add(s.Expiry),
+		}
+
+		return tx.Create(&transfer).Error
+	})
+	if err != nil {
+		if !isTransferError(err) {
+			logger.WithError(err).Error("Failed to start project transfer")
+		}
+
+		return TransferDto{}, err
+	}
+
+	return transferDto(transfer), nil
+}
+
+func (s *transfersServiceImpl) ListPendingTransfers(ctx context.Context, userId uint) ([]TransferDto, error) {
+	var transfers []ProjectTransfer
+	err := s.Db.
+		Where("from_user_id = ? OR to_user_id = ?", userId, userId).
+		Where("status = ? AND expires_at > ?", TransferStatusPending, time.Now()).
+		Where("from_user_id NOT IN (?)", users.HiddenUserIds(s.Db, userId)).
+		Order("created_at desc").
+		Find(&transfers).
+		Error
+	if err != nil {
+		log.FromContext(ctx).WithError(err).Error("Failed to list pending transfers")
+		return nil, err
+	}
+
+	dtos := make([]TransferDto, len(transfers))
+	for i, transfer := range transfers {
+		dtos[i] = transferDto(transfer)
+	}
+
+	return dtos, nil
+}
+
+func (s *transfersServiceImpl) AcceptTransfer(ctx context.Context, userId uint, transferId uint) error {
+	logger := log.FromContext(ctx).WithField("transferId", transferId)
+
+	logger.Debug("Accepting project transfer")
+
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		transfer, err := findPendingTransfer(tx, transferId, "to_user_id", userId)
+		if err != nil {
+			return err
+		}
+
+		// Checked in the transaction so that the recipient's quota stays
+		// locked until the project is theirs
+		err = s.ProjectsService.CheckQuota(ctx, tx, userId, QuotaOwnedProjects)
+		if err != nil {
+			return err
+		}
+
+		project := Project{}
+		err = tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "owner_id").
+			First(&project, transfer.ProjectId).
+			Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTransferNotFound
+			}
+
+			return err
+		}
+
+		// The project changed hands since the transfer was started
+		if project.OwnerId != transfer.FromUserId {
+			return ErrTransferNotPending
+		}
+
+		err = tx.Model(&project).Update("owner_id", userId).Error
+		if err != nil {
+			return err
+		}
+
+		err = setMemberRole(tx, project.ID, transfer.FromUserId, MemberRoleMaintainer)
+		if err != nil {
+			return err
+		}
+
+		err = setMemberRole(tx, project.ID, userId, MemberRoleOwner)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(transfer).Update("status", TransferStatusAccepted).Error
+		if err != nil {
+			return err
+		}
+
+		err = s.ActivityService.RecordActivities(
+			ctx,
+			tx,
+			activity.Activity{
+				UserId:        transfer.FromUserId,
+				Kind:          activity.KindProjectOwnershipGiven,
+				ProjectId:     project.ID,
+				RelatedUserId: userId,
+			},
+			activity.Activity{
+				UserId:        userId,
+				Kind:          activity.KindProjectOwnershipReceived,
+				ProjectId:     project.ID,
+				RelatedUserId: transfer.FromUserId,
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		transfer.Status = TransferStatusAccepted
+
+		return s.Events.Publish(ctx, tx, events.Event{
+			Type:      events.ProjectTransferred,
+			ProjectId: project.ID,
+			ActorId:   userId,
+			Data:      transferDto(*transfer),
+		})
+	})
+	if err != nil {
+		if !isTransferError(err) {
+			logger.WithError(err).Error("Failed to accept project transfer")
+		}
+
+		return err
+	}
+
+	logger.Debug("Project transfer accepted")
+
+	return nil
+}
+
+func (s *transfersServiceImpl) DeclineTransfer(ctx context.Context, userId uint, transferId uint) error {
+	return s.closeTransfer(ctx, transferId, "to_user_id", userId, TransferStatusDeclined)
+}
+
+func (s *transfersServiceImpl) CancelTransfer(ctx context.Context, userId uint, transferId uint) error {
+	return s.closeTransfer(ctx, transferId, "from_user_id", userId, TransferStatusCancelled)
+}
+
+// Set the status of a pending transfer in which userId is the user in
+// userColumn (either "from_user_id" or "to_user_id").
+func (s *transfersServiceImpl) closeTransfer(
+	ctx context.Context,
+	transferId uint,
+	userColumn string,
+	userId uint,
+	status string,
+) error {
+	logger := log.FromContext(ctx).WithFields(log.Fields{
+		"transferId": transferId,
+		"status":     status,
+	})
+
+	logger.Debug("Closing project transfer")
+
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		transfer, err := findPendingTransfer(tx, transferId, userColumn, userId)
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(transfer).Update("status", status).Error
+	})
+	if err != nil {
+		if !isTransferError(err) {
+			logger.WithError(err).Error("Failed to close project transfer")
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// Find a transfer and lock it for update. userColumn and userId restrict the
+// search to transfers sent ("from_user_id") or received ("to_user_id") by a user.
+// Returns ErrTransferNotFound if the transfer can't be found and
+// ErrTransferNotPending if it isn't pending.
+func findPendingTransfer(tx *gorm.DB, transferId uint, userColumn string, userId uint) (*ProjectTransfer, error) {
+	transfer := &ProjectTransfer{}
+	err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where(userColumn+" = ?", userId).
+		First(transfer, transferId).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTransferNotFound
+		}
+
+		return nil, err
+	}
+
+	if !transfer.IsPending() {
+		return nil, ErrTransferNotPending
+	}
+
+	return transfer, nil
+}
+
+// Whether err is one of the expected errors returned by the transfers service.
+func isTransferError(err error) bool {
+	var quotaErr *QuotaExceededError
+
+	return errors.As(err, &quotaErr) ||
+		errors.Is(err, ErrTransferNotFound) ||
+		errors.Is(err, ErrTransferNotPending) ||
+		errors.Is(err, ErrTransferAlreadyPending) ||
+		errors.Is(err, ErrProjectNotFound) ||
+		errors.Is(err, auth.ErrForbidden)
+}
+
+func transferDto(transfer ProjectTransfer) TransferDto {
+	status := transfer.Status
+	if status == TransferStatusPending && !transfer.IsPending() {
+		status = TransferStatusExpired
+	}
+
+	return TransferDto{
+		Id:         transfer.ID,
+		ProjectId:  transfer.ProjectId,
+		FromUserId: transfer.FromUserId,
+		ToUserId:   transfer.ToUserId,
+		Status:     status,
+		ExpiresAt:  transfer.ExpiresAt,
+		CreatedAt:  transfer.CreatedAt,
+	}
+}
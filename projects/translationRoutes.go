@@ -0,0 +1,84 @@
+package projects
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/open-collaboration/server/auth"
+	"github.com/open-collaboration/server/utils"
+	"net/http"
+)
+
+// @Summary Add or replace a translation of a project
+// @Tags projects
+// @Router /projects/{projectId}/translations/{locale} [put]
+// @Param projectId path int true "The project ID"
+// @Param locale path string true "A BCP 47 locale other than the project's default one, e.g. pt-BR"
+// @Param translation body dtos.TranslationDto true "The translated name and descriptions"
+// @Success 200 {object} dtos.TranslationDto
+func RouteSetTranslation(
+	writer http.ResponseWriter,
+	request *http.Request,
+	projectsService Service,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	projectId, err := utils.UintFromVars(request, "projectId")
+	if err != nil {
+		return err
+	}
+
+	dto := TranslationDto{}
+	err = utils.ReadJson(request.Context(), request, &dto)
+	if err != nil {
+		return err
+	}
+
+	locale := mux.Vars(request)["locale"]
+
+	translation, err := projectsService.SetTranslation(request.Context(), session.UserId(), projectId, locale, dto)
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusOK, translation)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Remove a translation of a project
+// @Tags projects
+// @Router /projects/{projectId}/translations/{locale} [delete]
+// @Param projectId path int true "The project ID"
+// @Param locale path string true "The translation's locale"
+// @Success 204
+func RouteDeleteTranslation(
+	writer http.ResponseWriter,
+	request *http.Request,
+	projectsService Service,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	projectId, err := utils.UintFromVars(request, "projectId")
+	if err != nil {
+		return err
+	}
+
+	locale := mux.Vars(request)["locale"]
+
+	err = projectsService.DeleteTranslation(request.Context(), session.UserId(), projectId, locale)
+	if err != nil {
+		return err
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
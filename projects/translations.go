@@ -0,0 +1,194 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"github.com/apex/log"
+	"github.com/go-playground/validator/v10"
+	"github.com/open-collaboration/server/utils"
+	"golang.org/x/text/language"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Locale of projects created without a default locale.
+const DefaultLocale = "en"
+
+var ErrTranslationNotFound = errors.New("translation not found")
+
+// Returned when adding a translation in the project's default locale, whose
+// text is the project's own name and descriptions.
+var ErrDefaultLocaleTranslation = errors.New("translation in the project's default locale")
+
+// A project's name and descriptions in a locale other than its default one.
+type ProjectTranslation struct {
+	gorm.Model
+
+	ProjectId        uint
+	Locale           string
+	Name             string
+	ShortDescription string
+	LongDescription  string
+}
+
+func (s *serviceImpl) SetTranslation(
+	ctx context.Context,
+	userId uint,
+	projectId uint,
+	locale string,
+	translation TranslationDto,
+) (TranslationDto, error) {
+	logger := log.FromContext(ctx).WithFields(log.Fields{
+		"projectId": projectId,
+		"locale":    locale,
+	})
+
+	err := validator.New().Struct(translation)
+	if err != nil {
+		return TranslationDto{}, err
+	}
+
+	locale, err = utils.CanonicalLocale(locale)
+	if err != nil {
+		return TranslationDto{}, err
+	}
+
+	err = s.checkCanManage(ctx, userId, projectId)
+	if err != nil {
+		return TranslationDto{}, err
+	}
+
+	err = s.Db.Transaction(func(tx *gorm.DB) error {
+		project := Project{}
+		err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "default_locale").
+			First(&project, projectId).
+			Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrProjectNotFound
+			}
+
+			return err
+		}
+
+		if project.DefaultLocale == locale {
+			return ErrDefaultLocaleTranslation
+		}
+
+		existing := ProjectTranslation{}
+		err = tx.
+			Where("project_id = ? AND locale = ?", projectId, locale).
+			Limit(1).
+			Find(&existing).
+			Error
+		if err != nil {
+			return err
+		}
+
+		existing.ProjectId = projectId
+		existing.Locale = locale
+		existing.Name = translation.Name
+		existing.ShortDescription = translation.ShortDescription
+		existing.LongDescription = translation.LongDescription
+
+		return tx.Save(&existing).Error
+	})
+	if err != nil {
+		if !isTranslationError(err) {
+			logger.WithError(err).Error("Failed to save translation")
+		}
+
+		return TranslationDto{}, err
+	}
+
+	translation.Locale = locale
+
+	return translation, nil
+}
+
+func (s *serviceImpl) DeleteTranslation(ctx context.Context, userId uint, projectId uint, locale string) error {
+	logger := log.FromContext(ctx).WithFields(log.Fields{
+		"projectId": projectId,
+		"locale":    locale,
+	})
+
+	locale, err := utils.CanonicalLocale(locale)
+	if err != nil {
+		return ErrTranslationNotFound
+	}
+
+	err = s.checkCanManage(ctx, userId, projectId)
+	if err != nil {
+		return err
+	}
+
+	result := s.Db.
+		Where("project_id = ? AND locale = ?", projectId, locale).
+		Delete(&ProjectTranslation{})
+	if result.Error != nil {
+		logger.WithError(result.Error).Error("Failed to delete translation")
+		return result.Error
+	}
+
+	if result.RowsAffected < 1 {
+		return ErrTranslationNotFound
+	}
+
+	return nil
+}
+
+// Find the translations of the given projects, grouped by project id.
+func findTranslations(db *gorm.DB, projectIds []uint) (map[uint][]ProjectTranslation, error) {
+	var translations []ProjectTranslation
+	err := db.
+		Where("project_id IN ?", projectIds).
+		Order("locale").
+		Find(&translations).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	byProject := map[uint][]ProjectTranslation{}
+	for _, translation := range translations {
+		byProject[translation.ProjectId] = append(byProject[translation.ProjectId], translation)
+	}
+
+	return byProject, nil
+}
+
+// Pick the translation of a project that best matches the preferred locales.
+// Returns nil if the project's own text (in its default locale) should be
+// used, along with the locales the project is available in.
+func pickTranslation(
+	defaultLocale string,
+	translations []ProjectTranslation,
+	preferred []language.Tag,
+) (*ProjectTranslation, TranslationsDto) {
+	locales := make([]string, len(translations)+1)
+	locales[0] = defaultLocale
+	for i, translation := range translations {
+		locales[i+1] = translation.Locale
+	}
+
+	available := TranslationsDto{
+		DefaultLocale: defaultLocale,
+		Locales:       locales,
+	}
+
+	index := utils.MatchLocale(locales, preferred)
+	if index == 0 {
+		return nil, available
+	}
+
+	return &translations[index-1], available
+}
+
+// Whether err is one of the expected errors returned when translating a project.
+func isTranslationError(err error) bool {
+	return errors.Is(err, ErrProjectNotFound) ||
+		errors.Is(err, ErrDefaultLocaleTranslation) ||
+		errors.Is(err, ErrTranslationNotFound)
+}
@@ -0,0 +1,142 @@
+package projects
+
+import (
+	"context"
+	"github.com/apex/log"
+	"gorm.io/gorm"
+	"time"
+)
+
+// A user's projects and everything else stored about the user in them.
+type UserProjectsExportDto struct {
+	OwnedProjects []ProjectExportDto    `json:"ownedProjects"`
+	Memberships   []MembershipExportDto `json:"memberships"`
+	AssignedTasks []TaskExportDto       `json:"assignedTasks"`
+	Transfers     []TransferDto         `json:"transfers"`
+
+	// Role suggestions the user sent or received.
+	RoleSuggestions []RoleSuggestionDto `json:"roleSuggestions"`
+}
+
+type MembershipExportDto struct {
+	ProjectId   uint      `json:"projectId"`
+	ProjectName string    `json:"projectName"`
+	Role        string    `json:"role"`
+	JoinedAt    time.Time `json:"joinedAt"`
+}
+
+type TaskExportDto struct {
+	Id        uint      `json:"id"`
+	ProjectId uint      `json:"projectId"`
+	Title     string    `json:"title"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (s *serviceImpl) ExportUserData(ctx context.Context, userId uint) (UserProjectsExportDto, error) {
+	logger := log.FromContext(ctx).WithField("userId", userId)
+
+	data := UserProjectsExportDto{
+		OwnedProjects: []ProjectExportDto{},
+		Memberships:   []MembershipExportDto{},
+		AssignedTasks: []TaskExportDto{},
+	}
+
+	err := s.ExportProjects(ctx, ExportOptions{OwnerId: userId, IncludeHidden: true}, func(project ProjectExportDto) error {
+		data.OwnedProjects = append(data.OwnedProjects, project)
+		return nil
+	})
+	if err != nil {
+		return UserProjectsExportDto{}, err
+	}
+
+	err = s.Db.
+		Model(&ProjectMember{}).
+		Select("project_members.project_id, projects.name AS project_name, project_members.role, project_members.created_at AS joined_at").
+		Joins("JOIN projects ON projects.id = project_members.project_id").
+		Where("project_members.user_id = ?", userId).
+		Order("project_members.created_at desc").
+		Scan(&data.Memberships).
+		Error
+	if err != nil {
+		logger.WithError(err).Error("Failed to export memberships")
+		return UserProjectsExportDto{}, err
+	}
+
+	err = s.Db.
+		Model(&ProjectTask{}).
+		Select("id, project_id, title, status, created_at").
+		Where("assignee_id = ?", userId).
+		Order("created_at desc").
+		Scan(&data.AssignedTasks).
+		Error
+	if err != nil {
+		logger.WithError(err).Error("Failed to export assigned tasks")
+		return UserProjectsExportDto{}, err
+	}
+
+	var transfers []ProjectTransfer
+	err = s.Db.
+		Where("from_user_id = ? OR to_user_id = ?", userId, userId).
+		Order("created_at desc").
+		Find(&transfers).
+		Error
+	if err != nil {
+		logger.WithError(err).Error("Failed to export transfers")
+		return UserProjectsExportDto{}, err
+	}
+
+	data.Transfers = make([]TransferDto, len(transfers))
+	for i, transfer := range transfers {
+		data.Transfers[i] = transferDto(transfer)
+	}
+
+	var suggestions []RoleSuggestion
+	err = s.Db.
+		Where("from_user_id = ? OR to_user_id = ?", userId, userId).
+		Order("created_at desc").
+		Find(&suggestions).
+		Error
+	if err != nil {
+		logger.WithError(err).Error("Failed to export role suggestions")
+		return UserProjectsExportDto{}, err
+	}
+
+	data.RoleSuggestions = make([]RoleSuggestionDto, len(suggestions))
+	for i, suggestion := range suggestions {
+		data.RoleSuggestions[i] = roleSuggestionDto(suggestion)
+	}
+
+	return data, nil
+}
+
+func (s *serviceImpl) ForgetUser(ctx context.Context, userId uint) error {
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Model(&ProjectTransfer{}).
+			Where("(from_user_id = ? OR to_user_id = ?) AND status = ?", userId, userId, TransferStatusPending).
+			Update("status", TransferStatusCancelled).
+			Error
+		if err != nil {
+			return err
+		}
+
+		// Suggestions carry a personal message
+		err = tx.
+			Unscoped().
+			Where("from_user_id = ? OR to_user_id = ?", userId, userId).
+			Delete(&RoleSuggestion{}).
+			Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Unscoped().Where("user_id = ?", userId).Delete(&ProjectQuotaOverride{}).Error
+	})
+	if err != nil {
+		log.FromContext(ctx).WithError(err).WithField("userId", userId).Error("Failed to forget user")
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,16 @@
+package middleware
+
+import (
+	"github.com/open-collaboration/server/utils"
+	"net/http"
+)
+
+// Adds the request's client (ip and user agent) to the request's context,
+// see utils.ClientFromContext.
+func ClientMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := utils.WithClient(r.Context(), utils.ClientFromRequest(r))
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
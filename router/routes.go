@@ -9,13 +9,22 @@ import (
 	"github.com/apex/log"
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
+	"github.com/open-collaboration/server/activity"
 	"github.com/open-collaboration/server/auth"
+	"github.com/open-collaboration/server/embeds"
+	"github.com/open-collaboration/server/feeds"
+	"github.com/open-collaboration/server/moderation"
+	"github.com/open-collaboration/server/privacy"
+	"github.com/open-collaboration/server/profiles"
 	"github.com/open-collaboration/server/projects"
 	"github.com/open-collaboration/server/router/middleware"
+	"github.com/open-collaboration/server/sitemap"
 	"github.com/open-collaboration/server/users"
 	"github.com/open-collaboration/server/utils"
+	"github.com/open-collaboration/server/webhooks"
 	"net/http"
 	"reflect"
+	"strconv"
 )
 
 type RouteResponse struct {
@@ -29,17 +38,94 @@ func SetupRoutes(providers []interface{}) *mux.Router {
 
 	rootRouter.Use(middleware.LoggingMiddleware)
 	rootRouter.Use(middleware.CorsMiddleware)
+	rootRouter.Use(middleware.ClientMiddleware)
 
 	authService := getProvider(providers, (*auth.Service)(nil)).(auth.Service)
 	rootRouter.Use(auth.SessionMiddleware(authService))
 
 	// Setup routes
 	rootRouter.HandleFunc("/users", createRouteHandler(users.RouteRegisterUser, providers)).Methods("POST")
+	rootRouter.HandleFunc("/users", createRouteHandler(profiles.RouteSearchDirectory, providers)).Methods("GET")
+	rootRouter.HandleFunc("/users/availability", createRouteHandler(users.RouteCheckAvailability, providers)).Methods("GET")
+	rootRouter.HandleFunc("/users/verify-email", createRouteHandler(users.RouteVerifyEmail, providers)).Methods("POST")
+	rootRouter.HandleFunc("/users/me", createRouteHandler(profiles.RouteUpdateOwnProfile, providers)).Methods("PATCH")
+	rootRouter.HandleFunc("/users/me", createRouteHandler(privacy.RouteDeleteAccount, providers)).Methods("DELETE")
+	rootRouter.HandleFunc("/users/me/export", createRouteHandler(privacy.RouteExportUserData, providers)).Methods("GET")
+	rootRouter.HandleFunc("/users/me/verification-email", createRouteHandler(profiles.RouteResendVerificationEmail, providers)).Methods("POST")
+	rootRouter.HandleFunc("/users/me/password", createRouteHandler(auth.RouteChangePassword, providers)).Methods("POST")
+	rootRouter.HandleFunc("/users/me/email", createRouteHandler(auth.RouteChangeEmail, providers)).Methods("POST")
+	rootRouter.HandleFunc("/users/confirm-email", createRouteHandler(users.RouteConfirmEmailChange, providers)).Methods("POST")
+	rootRouter.HandleFunc("/users/me/directory", createRouteHandler(profiles.RouteGetDirectorySettings, providers)).Methods("GET")
+	rootRouter.HandleFunc("/users/me/directory", createRouteHandler(profiles.RouteUpdateDirectorySettings, providers)).Methods("PUT")
+	rootRouter.HandleFunc("/users/me/blocks", createRouteHandler(profiles.RouteListBlocks, providers)).Methods("GET")
+	rootRouter.HandleFunc("/users/me/blocks", createRouteHandler(profiles.RouteBlockUser, providers)).Methods("POST")
+	rootRouter.HandleFunc("/users/me/blocks/{username}", createRouteHandler(profiles.RouteUnblockUser, providers)).Methods("DELETE")
+	rootRouter.HandleFunc("/users/me/security-events", createRouteHandler(profiles.RouteListSecurityEvents, providers)).Methods("GET")
+	rootRouter.HandleFunc("/users/me/skills", createRouteHandler(profiles.RouteListOwnSkills, providers)).Methods("GET")
+	rootRouter.HandleFunc("/users/me/skills", createRouteHandler(profiles.RouteAddSkill, providers)).Methods("POST")
+	rootRouter.HandleFunc("/users/me/skills/{skillId}", createRouteHandler(profiles.RouteUpdateSkill, providers)).Methods("PUT")
+	rootRouter.HandleFunc("/users/me/skills/{skillId}", createRouteHandler(profiles.RouteDeleteSkill, providers)).Methods("DELETE")
+	rootRouter.HandleFunc("/users/{username}/skills/{skillId}/endorsements", createRouteHandler(profiles.RouteEndorseSkill, providers)).Methods("POST")
+	rootRouter.HandleFunc("/users/{username}/skills/{skillId}/endorsements", createRouteHandler(profiles.RouteWithdrawEndorsement, providers)).Methods("DELETE")
+	rootRouter.HandleFunc("/users/{username}", createRouteHandler(profiles.RouteGetProfile, providers)).Methods("GET")
 	rootRouter.HandleFunc("/login", createRouteHandler(auth.RouteAuthenticateUser, providers)).Methods("POST")
+	rootRouter.HandleFunc("/password-reset/request", createRouteHandler(auth.RouteRequestPasswordReset, providers)).Methods("POST")
+	rootRouter.HandleFunc("/password-reset/confirm", createRouteHandler(auth.RouteConfirmPasswordReset, providers)).Methods("POST")
 	rootRouter.HandleFunc("/projects", createRouteHandler(projects.RouteListProjects, providers)).Methods("GET")
 	rootRouter.HandleFunc("/projects", createRouteHandler(projects.RouteCreateProject, providers)).Methods("POST")
+	rootRouter.HandleFunc("/projects/export", createRouteHandler(projects.RouteExportProjects, providers)).Methods("GET")
+	rootRouter.HandleFunc("/projects/import", createRouteHandler(projects.RouteImportProjects, providers)).Methods("POST")
 	rootRouter.HandleFunc("/projects/{projectId}", createRouteHandler(projects.RouteUpdateProject, providers)).Methods("POST")
 	rootRouter.HandleFunc("/projects/{projectId}", createRouteHandler(projects.RouteGetProject, providers)).Methods("GET")
+	rootRouter.HandleFunc("/projects/by-slug/{slug}", createRouteHandler(projects.RouteGetProjectBySlug, providers)).Methods("GET")
+	rootRouter.HandleFunc("/s/{code}", createRouteHandler(projects.RouteFollowShortLink, providers)).Methods("GET")
+	rootRouter.HandleFunc("/projects/{projectId}/roles", createRouteHandler(projects.RouteCreateRole, providers)).Methods("POST")
+	rootRouter.HandleFunc("/projects/{projectId}/collaboration", createRouteHandler(projects.RouteSetCollaboration, providers)).Methods("PUT")
+	rootRouter.HandleFunc("/projects/{projectId}/roles/{roleId}/close", createRouteHandler(projects.RouteCloseRole, providers)).Methods("POST")
+	rootRouter.HandleFunc("/projects/{projectId}/roles/{roleId}/suggestions", createRouteHandler(projects.RouteSuggestRole, providers)).Methods("POST")
+	rootRouter.HandleFunc("/projects/{projectId}/translations/{locale}", createRouteHandler(projects.RouteSetTranslation, providers)).Methods("PUT")
+	rootRouter.HandleFunc("/projects/{projectId}/translations/{locale}", createRouteHandler(projects.RouteDeleteTranslation, providers)).Methods("DELETE")
+	rootRouter.HandleFunc("/projects/{projectId}/milestones", createRouteHandler(projects.RouteListMilestones, providers)).Methods("GET")
+	rootRouter.HandleFunc("/projects/{projectId}/milestones", createRouteHandler(projects.RouteCreateMilestone, providers)).Methods("POST")
+	rootRouter.HandleFunc("/projects/{projectId}/milestones/{milestoneId}", createRouteHandler(projects.RouteUpdateMilestone, providers)).Methods("PUT")
+	rootRouter.HandleFunc("/projects/{projectId}/tasks", createRouteHandler(projects.RouteListTasks, providers)).Methods("GET")
+	rootRouter.HandleFunc("/projects/{projectId}/tasks", createRouteHandler(projects.RouteCreateTask, providers)).Methods("POST")
+	rootRouter.HandleFunc("/projects/{projectId}/tasks/{taskId}", createRouteHandler(projects.RouteUpdateTask, providers)).Methods("PUT")
+	rootRouter.HandleFunc("/projects/{projectId}/tasks/{taskId}", createRouteHandler(projects.RouteDeleteTask, providers)).Methods("DELETE")
+	rootRouter.HandleFunc("/projects/{projectId}/tasks/{taskId}/move", createRouteHandler(projects.RouteMoveTask, providers)).Methods("POST")
+	rootRouter.HandleFunc("/projects/{projectId}/webhooks", createRouteHandler(webhooks.RouteListWebhooks, providers)).Methods("GET")
+	rootRouter.HandleFunc("/projects/{projectId}/webhooks", createRouteHandler(webhooks.RouteCreateWebhook, providers)).Methods("POST")
+	rootRouter.HandleFunc("/projects/{projectId}/webhooks/{webhookId}", createRouteHandler(webhooks.RouteUpdateWebhook, providers)).Methods("PUT")
+	rootRouter.HandleFunc("/projects/{projectId}/webhooks/{webhookId}", createRouteHandler(webhooks.RouteDeleteWebhook, providers)).Methods("DELETE")
+	rootRouter.HandleFunc("/projects/{projectId}/webhooks/{webhookId}/deliveries", createRouteHandler(webhooks.RouteListDeliveries, providers)).Methods("GET")
+	rootRouter.HandleFunc("/projects/{projectId}/webhooks/{webhookId}/deliveries/{deliveryId}", createRouteHandler(webhooks.RouteGetDelivery, providers)).Methods("GET")
+	rootRouter.HandleFunc("/projects/{projectId}/webhooks/{webhookId}/deliveries/{deliveryId}/redeliver", createRouteHandler(webhooks.RouteRedeliver, providers)).Methods("POST")
+	rootRouter.HandleFunc("/users/me/project-quota", createRouteHandler(projects.RouteGetOwnQuota, providers)).Methods("GET")
+	rootRouter.HandleFunc("/admin/users/{userId}/project-quota", createRouteHandler(projects.RouteGetUserQuota, providers)).Methods("GET")
+	rootRouter.HandleFunc("/admin/users/{userId}/project-quota", createRouteHandler(projects.RouteSetUserQuota, providers)).Methods("PUT")
+	rootRouter.HandleFunc("/projects/{projectId}/transfers", createRouteHandler(projects.RouteStartTransfer, providers)).Methods("POST")
+	rootRouter.HandleFunc("/project-transfers/{transferId}/accept", createRouteHandler(projects.RouteAcceptTransfer, providers)).Methods("POST")
+	rootRouter.HandleFunc("/project-transfers/{transferId}/decline", createRouteHandler(projects.RouteDeclineTransfer, providers)).Methods("POST")
+	rootRouter.HandleFunc("/project-transfers/{transferId}/cancel", createRouteHandler(projects.RouteCancelTransfer, providers)).Methods("POST")
+	rootRouter.HandleFunc("/feeds/projects.{format:atom|rss|json}", createRouteHandler(feeds.RouteProjectsFeed, providers)).Methods("GET")
+	rootRouter.HandleFunc("/oembed", createRouteHandler(embeds.RouteOEmbed, providers)).Methods("GET")
+	rootRouter.HandleFunc("/projects/{projectId}/card", createRouteHandler(embeds.RouteProjectCard, providers)).Methods("GET")
+	rootRouter.HandleFunc("/projects/{projectId}/badge.svg", createRouteHandler(embeds.RouteProjectBadge, providers)).Methods("GET")
+	rootRouter.HandleFunc("/robots.txt", createRouteHandler(sitemap.RouteRobots, providers)).Methods("GET")
+	rootRouter.HandleFunc("/sitemap.xml", createRouteHandler(sitemap.RouteSitemap, providers)).Methods("GET")
+	rootRouter.HandleFunc("/sitemaps/projects-{page:[0-9]+}.xml", createRouteHandler(sitemap.RouteSitemapPage, providers)).Methods("GET")
+	rootRouter.HandleFunc("/users/me/project-transfers", createRouteHandler(projects.RouteListPendingTransfers, providers)).Methods("GET")
+	rootRouter.HandleFunc("/users/me/role-suggestions", createRouteHandler(projects.RouteListRoleSuggestions, providers)).Methods("GET")
+	rootRouter.HandleFunc("/role-suggestions/{suggestionId}/accept", createRouteHandler(projects.RouteAcceptRoleSuggestion, providers)).Methods("POST")
+	rootRouter.HandleFunc("/role-suggestions/{suggestionId}/decline", createRouteHandler(projects.RouteDeclineRoleSuggestion, providers)).Methods("POST")
+	rootRouter.HandleFunc("/users/me/activity", createRouteHandler(activity.RouteListActivities, providers)).Methods("GET")
+	rootRouter.HandleFunc("/reports", createRouteHandler(moderation.RouteCreateReport, providers)).Methods("POST")
+	rootRouter.HandleFunc("/moderation/cases", createRouteHandler(moderation.RouteListCases, providers)).Methods("GET")
+	rootRouter.HandleFunc("/moderation/cases/{caseId}", createRouteHandler(moderation.RouteGetCase, providers)).Methods("GET")
+	rootRouter.HandleFunc("/moderation/cases/{caseId}/triage", createRouteHandler(moderation.RouteTriageCase, providers)).Methods("POST")
+	rootRouter.HandleFunc("/moderation/cases/{caseId}/resolve", createRouteHandler(moderation.RouteResolveCase, providers)).Methods("POST")
+	rootRouter.HandleFunc("/moderation/cases/{caseId}/dismiss", createRouteHandler(moderation.RouteDismissCase, providers)).Methods("POST")
+	rootRouter.HandleFunc("/users/me/warnings", createRouteHandler(moderation.RouteListWarnings, providers)).Methods("GET")
 
 	// Swagger
 	swaggerUi := http.FileServer(http.Dir("swagger-ui/"))
@@ -98,8 +184,9 @@ func createRouteHandler(handler interface{}, providers []interface{}) func(http.
 // Handle an error that was returned by a route.
 //
 // Json syntax and unmarshalling errors return to the client a
-// 400 response with an error description.
-// All other errors return a 500 without a body.
+// 400 response with an error description. Known service errors
+// (e.g. not found, forbidden) are mapped to their respective status
+// codes. All other errors return a 500.
 func handleRouteError(writer http.ResponseWriter, ctx context.Context, routeErr error) {
 	logger := log.FromContext(ctx)
 
@@ -113,10 +200,170 @@ func handleRouteError(writer http.ResponseWriter, ctx context.Context, routeErr
 
 		switch e := routeErr.(type) {
 		default:
-			if errors.Is(routeErr, auth.ErrUnauthenticated) {
+			switch {
+			case errors.Is(routeErr, auth.ErrUnauthenticated):
 				status = http.StatusUnauthorized
 				code = "unauthenticated-error"
-			} else {
+
+			// Blocked users get the same error as anyone else who isn't
+			// allowed, so they can't tell they were blocked
+			case errors.Is(routeErr, auth.ErrForbidden),
+				errors.Is(routeErr, users.ErrBlocked):
+				status = http.StatusForbidden
+				code = "forbidden-error"
+
+			case errors.Is(routeErr, auth.ErrInvalidCredentials):
+				status = http.StatusUnauthorized
+				code = "invalid-credentials"
+
+			// Only returned to authenticated users confirming their password
+			case errors.Is(routeErr, auth.ErrWrongPassword):
+				status = http.StatusForbidden
+				code = "wrong-password"
+
+			case errors.Is(routeErr, auth.ErrUserSuspended):
+				status = http.StatusForbidden
+				code = "user-suspended"
+
+			case errors.Is(routeErr, utils.ErrInvalidParam),
+				errors.Is(routeErr, utils.ErrMissingParam):
+				status = http.StatusBadRequest
+				code = "invalid-param-error"
+
+			case errors.Is(routeErr, projects.ErrProjectNotFound),
+				errors.Is(routeErr, projects.ErrRoleNotFound),
+				errors.Is(routeErr, projects.ErrTransferNotFound),
+				errors.Is(routeErr, projects.ErrSuggestionNotFound),
+				errors.Is(routeErr, projects.ErrMilestoneNotFound),
+				errors.Is(routeErr, projects.ErrTaskNotFound),
+				errors.Is(routeErr, projects.ErrTranslationNotFound),
+				errors.Is(routeErr, sitemap.ErrSitemapNotFound),
+				errors.Is(routeErr, embeds.ErrUnsupportedUrl),
+				errors.Is(routeErr, webhooks.ErrWebhookNotFound),
+				errors.Is(routeErr, webhooks.ErrDeliveryNotFound),
+				errors.Is(routeErr, users.ErrUserNotFound),
+				errors.Is(routeErr, users.ErrSkillNotFound),
+				errors.Is(routeErr, users.ErrEndorsementNotFound),
+				errors.Is(routeErr, users.ErrBlockNotFound),
+				errors.Is(routeErr, moderation.ErrTargetNotFound),
+				errors.Is(routeErr, moderation.ErrCaseNotFound):
+				status = http.StatusNotFound
+				code = "not-found-error"
+
+			case errors.Is(routeErr, projects.ErrInvalidRecipient):
+				status = http.StatusBadRequest
+				code = "invalid-recipient"
+
+			case errors.Is(routeErr, projects.ErrInvalidAssignee):
+				status = http.StatusBadRequest
+				code = "invalid-assignee"
+
+			case errors.Is(routeErr, embeds.ErrUnsupportedFormat):
+				status = http.StatusNotImplemented
+				code = "unsupported-format"
+
+			case errors.Is(routeErr, users.ErrSkillExists):
+				status = http.StatusConflict
+				code = "skill-exists"
+
+			case errors.Is(routeErr, users.ErrTooManySkills):
+				status = http.StatusConflict
+				code = "too-many-skills"
+
+			case errors.Is(routeErr, users.ErrAlreadyEndorsed):
+				status = http.StatusConflict
+				code = "already-endorsed"
+
+			case errors.Is(routeErr, users.ErrEmailNotVerified):
+				status = http.StatusForbidden
+				code = "email-not-verified"
+
+			case errors.Is(routeErr, users.ErrEmailAlreadyVerified):
+				status = http.StatusConflict
+				code = "email-already-verified"
+
+			case errors.Is(routeErr, users.ErrInvalidVerificationToken),
+				errors.Is(routeErr, auth.ErrInvalidResetToken):
+				status = http.StatusBadRequest
+				code = "invalid-token"
+
+			case errors.Is(routeErr, users.ErrVerificationTokenExpired):
+				status = http.StatusBadRequest
+				code = "token-expired"
+
+			case errors.Is(routeErr, users.ErrReservedUsername):
+				status = http.StatusBadRequest
+				code = "reserved-username"
+
+			case errors.Is(routeErr, users.ErrSelfBlock):
+				status = http.StatusBadRequest
+				code = "self-block"
+
+			case errors.Is(routeErr, users.ErrSameEmail):
+				status = http.StatusBadRequest
+				code = "same-email"
+
+			case errors.Is(routeErr, users.ErrMissingTimezone):
+				status = http.StatusBadRequest
+				code = "missing-timezone"
+
+			case errors.Is(routeErr, users.ErrNoWorkingHours):
+				status = http.StatusBadRequest
+				code = "no-working-hours"
+
+			case errors.Is(routeErr, users.ErrInvalidCursor):
+				status = http.StatusBadRequest
+				code = "invalid-cursor"
+
+			case errors.Is(routeErr, users.ErrInvalidHandle):
+				status = http.StatusBadRequest
+				code = "invalid-handle"
+
+			case errors.Is(routeErr, utils.ErrInvalidLocale):
+				status = http.StatusBadRequest
+				code = "invalid-locale"
+
+			case errors.Is(routeErr, projects.ErrDefaultLocaleTranslation):
+				status = http.StatusConflict
+				code = "default-locale-translation"
+
+			case errors.Is(routeErr, projects.ErrTransferAlreadyPending):
+				status = http.StatusConflict
+				code = "transfer-already-pending"
+
+			case errors.Is(routeErr, projects.ErrRoleClosed):
+				status = http.StatusConflict
+				code = "role-closed"
+
+			case errors.Is(routeErr, projects.ErrAlreadyMember):
+				status = http.StatusConflict
+				code = "already-member"
+
+			case errors.Is(routeErr, projects.ErrSuggestionAlreadySent):
+				status = http.StatusConflict
+				code = "suggestion-already-sent"
+
+			case errors.Is(routeErr, projects.ErrSuggestionNotPending):
+				status = http.StatusConflict
+				code = "suggestion-not-pending"
+
+			case errors.Is(routeErr, projects.ErrTransferNotPending):
+				status = http.StatusConflict
+				code = "transfer-not-pending"
+
+			case errors.Is(routeErr, webhooks.ErrTooManyWebhooks):
+				status = http.StatusConflict
+				code = "too-many-webhooks"
+
+			case errors.Is(routeErr, moderation.ErrAlreadyReported):
+				status = http.StatusConflict
+				code = "already-reported"
+
+			case errors.Is(routeErr, moderation.ErrCaseClosed):
+				status = http.StatusConflict
+				code = "case-closed"
+
+			default:
 				status = http.StatusInternalServerError
 			}
 
@@ -130,6 +377,43 @@ func handleRouteError(writer http.ResponseWriter, ctx context.Context, routeErr
 			details[e.Field] = e.Error()
 			status = http.StatusBadRequest
 
+		case *projects.QuotaExceededError:
+			code = "quota-exceeded"
+			details["quota"] = e.Quota
+			details["limit"] = e.Limit
+			details["usage"] = e.Usage
+			status = http.StatusForbidden
+
+		case *users.ConflictError:
+			code = "conflict"
+			details["field"] = e.Field
+			status = http.StatusConflict
+
+		case *users.RateLimitError:
+			code = "rate-limited"
+			details["retryAfter"] = int(e.RetryAfter.Seconds())
+			writer.Header().Set("Retry-After", strconv.Itoa(int(e.RetryAfter.Seconds())))
+			status = http.StatusTooManyRequests
+
+		case *projects.DuplicateProjectError:
+			code = "duplicate-project"
+			if e.ProjectId != 0 {
+				details["projectId"] = e.ProjectId
+				details["slug"] = e.Slug
+			}
+			status = http.StatusConflict
+
+		case *projects.NearDuplicateError:
+			code = "possible-duplicate"
+			details["candidates"] = e.Candidates
+			status = http.StatusConflict
+
+		case *projects.ImportFileError:
+			code = "invalid-import-file"
+			details["line"] = e.Line
+			details["reason"] = e.Reason
+			status = http.StatusBadRequest
+
 		case validator.ValidationErrors:
 			code = "validation-error"
 			for _, fieldError := range e {
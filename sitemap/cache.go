@@ -0,0 +1,98 @@
+package sitemap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/apex/log"
+	"github.com/go-redis/redis/v8"
+	"github.com/open-collaboration/server/events"
+	"time"
+)
+
+// Redis key of the cache version. Cached sitemaps are stored under keys
+// that include the version, so bumping it invalidates all of them at once.
+const cacheVersionKey = "sitemap:version"
+
+// Events after which the projects listed in the sitemap may have changed.
+var invalidatingEvents = map[string]bool{
+	events.ProjectCreated:  true,
+	events.ProjectUpdated:  true,
+	events.ProjectHidden:   true,
+	events.ProjectUnhidden: true,
+}
+
+type cache struct {
+	Redis *redis.Client
+	Ttl   time.Duration
+}
+
+// Get the key of a cached document in the current cache version.
+func (c *cache) key(ctx context.Context, name string) (string, error) {
+	version, err := c.Redis.Get(ctx, cacheVersionKey).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return "", err
+	}
+
+	return fmt.Sprintf("sitemap:%d:%s", version, name), nil
+}
+
+// Get a cached document, or generate and cache it if it isn't cached.
+// Cache failures are logged and the document is generated anyway.
+func (c *cache) getOrGenerate(ctx context.Context, name string, generate func() ([]byte, error)) ([]byte, error) {
+	logger := log.FromContext(ctx).WithField("document", name)
+
+	key, err := c.key(ctx, name)
+	if err != nil {
+		logger.WithError(err).Error("Failed to get sitemap cache version")
+		return generate()
+	}
+
+	cached, err := c.Redis.Get(ctx, key).Bytes()
+	if err == nil {
+		return cached, nil
+	} else if !errors.Is(err, redis.Nil) {
+		logger.WithError(err).Error("Failed to read cached sitemap")
+	}
+
+	document, err := generate()
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.Redis.Set(ctx, key, document, c.Ttl).Err()
+	if err != nil {
+		logger.WithError(err).Error("Failed to cache sitemap")
+	}
+
+	return document, nil
+}
+
+type cacheInvalidator struct {
+	Redis *redis.Client
+}
+
+// Create an events.Listener that invalidates the cached sitemaps whenever a
+// project is created, updated, hidden or unhidden. The cache is only
+// invalidated once the changes are committed, so sitemaps generated after
+// it are generated with the new data.
+func NewCacheInvalidator(redisDb *redis.Client) events.Listener {
+	return &cacheInvalidator{Redis: redisDb}
+}
+
+func (i *cacheInvalidator) Committed(ctx context.Context, committed ...events.Event) {
+	for _, event := range committed {
+		if !invalidatingEvents[event.Type] {
+			continue
+		}
+
+		// Cached sitemaps expire after a while, so one that's out of date
+		// because of a failure is only logged.
+		err := i.Redis.Incr(ctx, cacheVersionKey).Err()
+		if err != nil {
+			log.FromContext(ctx).WithError(err).Error("Failed to invalidate cached sitemaps")
+		}
+
+		return
+	}
+}
@@ -0,0 +1,74 @@
+package sitemap
+
+import (
+	"github.com/open-collaboration/server/utils"
+	"net/http"
+	"strconv"
+)
+
+// @Summary robots.txt
+// @Tags sitemap
+// @Router /robots.txt [get]
+// @Success 200
+func RouteRobots(
+	writer http.ResponseWriter,
+	request *http.Request,
+	sitemapService Service,
+) error {
+	return writeDocument(writer, "text/plain; charset=utf-8", []byte(sitemapService.GetRobots()))
+}
+
+// @Summary Sitemap of public projects
+// @Description A sitemap index pointing to /sitemaps/projects-{page}.xml when there are more
+// @Description projects than fit in a single sitemap.
+// @Tags sitemap
+// @Router /sitemap.xml [get]
+// @Success 200
+func RouteSitemap(
+	writer http.ResponseWriter,
+	request *http.Request,
+	sitemapService Service,
+) error {
+	document, err := sitemapService.GetSitemap(request.Context())
+	if err != nil {
+		return err
+	}
+
+	return writeDocument(writer, "application/xml", document)
+}
+
+// @Summary A page of the sitemap of public projects
+// @Tags sitemap
+// @Router /sitemaps/projects-{page}.xml [get]
+// @Param page path int true "The page, starting from 1"
+// @Success 200
+func RouteSitemapPage(
+	writer http.ResponseWriter,
+	request *http.Request,
+	sitemapService Service,
+) error {
+	page, err := utils.UintFromVars(request, "page")
+	if err != nil {
+		return err
+	}
+
+	document, err := sitemapService.GetSitemapPage(request.Context(), page)
+	if err != nil {
+		return err
+	}
+
+	return writeDocument(writer, "application/xml", document)
+}
+
+func writeDocument(writer http.ResponseWriter, contentType string, document []byte) error {
+	writer.Header().Set("Content-Type", contentType)
+	writer.Header().Set("Content-Length", strconv.Itoa(len(document)))
+	writer.WriteHeader(http.StatusOK)
+
+	_, err := writer.Write(document)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package sitemap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/open-collaboration/server/projects"
+	"strings"
+	"time"
+)
+
+// Maximum amount of URLs in a sitemap, as set by the sitemap protocol.
+// Catalogs with more projects are split into several sitemaps, listed in a
+// sitemap index.
+const sitemapPageSize = 50000
+
+var ErrSitemapNotFound = errors.New("sitemap not found")
+
+type Service interface {
+	// Get the robots.txt of the site, which points crawlers to the sitemap.
+	GetRobots() string
+
+	// Get the sitemap of public projects. If there are too many projects
+	// for a single sitemap, a sitemap index listing the pages of the sitemap
+	// is returned instead.
+	GetSitemap(ctx context.Context) ([]byte, error)
+
+	// Get a page of the sitemap of public projects, starting from 1.
+	// Returns ErrSitemapNotFound if there's no such page.
+	GetSitemapPage(ctx context.Context, page uint) ([]byte, error)
+}
+
+type serviceImpl struct {
+	ProjectsService projects.Service
+	Cache           *cache
+
+	// Public URL of the API, without a trailing slash.
+	PublicUrl string
+}
+
+// Create a sitemap service. Generated sitemaps are cached in redisDb for
+// cacheTtl or until projects change (see NewCacheInvalidator).
+func NewService(projectsService projects.Service, redisDb *redis.Client, publicUrl string, cacheTtl time.Duration) Service {
+	return &serviceImpl{
+		ProjectsService: projectsService,
+		Cache:           &cache{Redis: redisDb, Ttl: cacheTtl},
+		PublicUrl:       strings.TrimSuffix(publicUrl, "/"),
+	}
+}
+
+func (s *serviceImpl) GetRobots() string {
+	return fmt.Sprintf(
+		"User-agent: *\nDisallow: /admin/\nDisallow: /users/me/\nAllow: /\n\nSitemap: %s/sitemap.xml\n",
+		s.PublicUrl,
+	)
+}
+
+func (s *serviceImpl) GetSitemap(ctx context.Context) ([]byte, error) {
+	return s.Cache.getOrGenerate(ctx, "index", func() ([]byte, error) {
+		pages, err := s.ProjectsService.ListProjectLinkPages(ctx, sitemapPageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		// Small catalogs fit in a single sitemap
+		if len(pages) < 2 {
+			return s.generatePage(ctx, 0)
+		}
+
+		index := sitemapIndex{
+			Xmlns:    sitemapNamespace,
+			Sitemaps: make([]sitemapItem, len(pages)),
+		}
+
+		for i, updatedAt := range pages {
+			index.Sitemaps[i] = sitemapItem{
+				Loc:     fmt.Sprintf("%s/sitemaps/projects-%d.xml", s.PublicUrl, i+1),
+				LastMod: lastMod(updatedAt),
+			}
+		}
+
+		return marshalXml(index)
+	})
+}
+
+func (s *serviceImpl) GetSitemapPage(ctx context.Context, page uint) ([]byte, error) {
+	if page < 1 {
+		return nil, ErrSitemapNotFound
+	}
+
+	return s.Cache.getOrGenerate(ctx, fmt.Sprintf("projects:%d", page), func() ([]byte, error) {
+		return s.generatePage(ctx, page-1)
+	})
+}
+
+// Generate the sitemap of a page of projects, starting from 0.
+// Returns ErrSitemapNotFound if the page is empty, unless it's the first one.
+func (s *serviceImpl) generatePage(ctx context.Context, page uint) ([]byte, error) {
+	links, err := s.ProjectsService.ListProjectLinks(ctx, page, sitemapPageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(links) < 1 && page > 0 {
+		return nil, ErrSitemapNotFound
+	}
+
+	urls := urlSet{
+		Xmlns: sitemapNamespace,
+		Urls:  make([]urlItem, len(links)),
+	}
+
+	for i, link := range links {
+		urls.Urls[i] = urlItem{
+			Loc:     s.PublicUrl + "/projects/by-slug/" + link.Slug,
+			LastMod: lastMod(link.UpdatedAt),
+		}
+	}
+
+	return marshalXml(urls)
+}
@@ -0,0 +1,51 @@
+package sitemap
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+const sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// A sitemap, listing pages of the site.
+type urlSet struct {
+	XMLName xml.Name  `xml:"urlset"`
+	Xmlns   string    `xml:"xmlns,attr"`
+	Urls    []urlItem `xml:"url"`
+}
+
+type urlItem struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// A sitemap index, listing other sitemaps.
+type sitemapIndex struct {
+	XMLName  xml.Name      `xml:"sitemapindex"`
+	Xmlns    string        `xml:"xmlns,attr"`
+	Sitemaps []sitemapItem `xml:"sitemap"`
+}
+
+type sitemapItem struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// Format a time as a W3C datetime, as required by lastmod.
+func lastMod(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Serialize a sitemap or sitemap index, with the XML declaration.
+func marshalXml(document interface{}) ([]byte, error) {
+	body, err := xml.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), body...), nil
+}
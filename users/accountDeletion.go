@@ -0,0 +1,216 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"github.com/apex/log"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Everything stored about a user's account, see Service.ExportAccount.
+type AccountExportDto struct {
+	Id              uint       `json:"id"`
+	Username        string     `json:"username"`
+	Email           string     `json:"email"`
+	PendingEmail    string     `json:"pendingEmail,omitempty"`
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt"`
+	Role            string     `json:"role"`
+	DisplayName     string     `json:"displayName"`
+	Bio             string     `json:"bio"`
+	Location        string     `json:"location"`
+	Website         string     `json:"website"`
+	GithubHandle    string     `json:"githubHandle"`
+	GitlabHandle    string     `json:"gitlabHandle"`
+	Timezone        string     `json:"timezone"`
+	SuspendedAt     *time.Time `json:"suspendedAt"`
+	HiddenAt        *time.Time `json:"hiddenAt"`
+
+	DeletionScheduledAt *time.Time `json:"deletionScheduledAt"`
+
+	DirectoryListedAt *time.Time       `json:"directoryListedAt"`
+	Availability      string           `json:"availability"`
+	WeeklyHours       int              `json:"weeklyHours"`
+	WorkingHours      *WorkingHoursDto `json:"workingHours"`
+	PublicFields      []string         `json:"publicFields"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	SecurityEvents []SecurityEventDto `json:"securityEvents"`
+	Blocks         []BlockDto         `json:"blocks"`
+}
+
+func (s *serviceImpl) ExportAccount(ctx context.Context, id uint) (AccountExportDto, error) {
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return AccountExportDto{}, err
+	}
+
+	var events []SecurityEvent
+	err = s.Db.Where("user_id = ?", id).Order("created_at desc").Find(&events).Error
+	if err != nil {
+		log.FromContext(ctx).WithError(err).WithField("userId", id).Error("Failed to export security events")
+		return AccountExportDto{}, err
+	}
+
+	blocks, err := blockDtos(s.Db, id)
+	if err != nil {
+		log.FromContext(ctx).WithError(err).WithField("userId", id).Error("Failed to export blocks")
+		return AccountExportDto{}, err
+	}
+
+	return AccountExportDto{
+		Id:                  user.ID,
+		Username:            user.Username,
+		Email:               user.Email,
+		PendingEmail:        user.PendingEmail,
+		EmailVerifiedAt:     user.EmailVerifiedAt,
+		Role:                user.Role,
+		DisplayName:         user.DisplayName,
+		Bio:                 user.Bio,
+		Location:            user.Location,
+		Website:             user.Website,
+		GithubHandle:        user.GithubHandle,
+		GitlabHandle:        user.GitlabHandle,
+		Timezone:            user.Timezone,
+		SuspendedAt:         user.SuspendedAt,
+		HiddenAt:            user.HiddenAt,
+		DeletionScheduledAt: user.DeletionScheduledAt,
+		DirectoryListedAt:   user.DirectoryListedAt,
+		Availability:        user.Availability,
+		WeeklyHours:         user.WeeklyHours,
+		WorkingHours:        WorkingHoursFromMinutes(user.WorkStart, user.WorkEnd),
+		PublicFields:        user.PublicFields,
+		CreatedAt:           user.CreatedAt,
+		UpdatedAt:           user.UpdatedAt,
+		SecurityEvents:      securityEventDtos(events),
+		Blocks:              blocks,
+	}, nil
+}
+
+func (s *serviceImpl) ScheduleDeletion(ctx context.Context, id uint, at time.Time) error {
+	result := s.Db.
+		Model(&User{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"deletion_scheduled_at": at,
+			"deletion_attempted_at": nil,
+		})
+	if result.Error != nil {
+		log.FromContext(ctx).WithError(result.Error).WithField("userId", id).Error("Failed to schedule account deletion")
+		return result.Error
+	}
+
+	if result.RowsAffected < 1 {
+		return ErrUserNotFound
+	}
+
+	return s.RecordSecurityEvent(ctx, SecurityEvent{
+		UserId: id,
+		Kind:   SecurityEventDeletionRequested,
+	})
+}
+
+func (s *serviceImpl) CancelDeletion(ctx context.Context, id uint) (bool, error) {
+	result := s.Db.
+		Model(&User{}).
+		Where("id = ? AND deletion_scheduled_at IS NOT NULL", id).
+		Update("deletion_scheduled_at", nil)
+	if result.Error != nil {
+		log.FromContext(ctx).WithError(result.Error).WithField("userId", id).Error("Failed to cancel account deletion")
+		return false, result.Error
+	}
+
+	if result.RowsAffected < 1 {
+		return false, nil
+	}
+
+	return true, s.RecordSecurityEvent(ctx, SecurityEvent{
+		UserId: id,
+		Kind:   SecurityEventDeletionCancelled,
+	})
+}
+
+func (s *serviceImpl) ListDueDeletions(ctx context.Context, limit uint) ([]uint, error) {
+	var ids []uint
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Model(&User{}).
+			Where("deletion_scheduled_at <= ?", time.Now()).
+			Order("deletion_attempted_at NULLS FIRST, deletion_scheduled_at").
+			Limit(int(limit)).
+			Pluck("id", &ids).
+			Error
+		if err != nil || len(ids) < 1 {
+			return err
+		}
+
+		return tx.Model(&User{}).Where("id IN ?", ids).Update("deletion_attempted_at", time.Now()).Error
+	})
+	if err != nil {
+		log.FromContext(ctx).WithError(err).Error("Failed to list due account deletions")
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (s *serviceImpl) AnonymizeUser(ctx context.Context, id uint) error {
+	logger := log.FromContext(ctx).WithField("userId", id)
+
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Where("user_id = ?", id).Delete(&SecurityEvent{}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Unscoped().Where("user_id = ? OR blocked_user_id = ?", id, id).Delete(&UserBlock{}).Error
+		if err != nil {
+			return err
+		}
+
+		// The row is kept so that projects, tasks and the like still point
+		// to a user, but nothing in it identifies the person anymore.
+		result := tx.
+			Model(&User{}).
+			Where("id = ?", id).
+			Updates(map[string]interface{}{
+				"username":              fmt.Sprintf("deleted-user-%d", id),
+				"email":                 fmt.Sprintf("deleted-user-%d@invalid", id),
+				"pending_email":         "",
+				"password_hash":         "",
+				"display_name":          "",
+				"bio":                   "",
+				"location":              "",
+				"website":               "",
+				"github_handle":         "",
+				"gitlab_handle":         "",
+				"timezone":              "",
+				"deletion_scheduled_at": nil,
+				"deletion_attempted_at": nil,
+				"directory_listed_at":   nil,
+				"weekly_hours":          0,
+				"work_start":            nil,
+				"work_end":              nil,
+				"public_fields":         pq.StringArray{},
+				"hidden_at":             time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected < 1 {
+			return ErrUserNotFound
+		}
+
+		return tx.Delete(&User{}, id).Error
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to anonymize user")
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,245 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"github.com/apex/log"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+const (
+	// The blocked user can't interact with the user, and their content is
+	// hidden from the user.
+	BlockKindBlock = "block"
+	// The muted user's content is hidden from the user.
+	BlockKindMute = "mute"
+)
+
+// Returned when a user tries to interact with a user who blocked them.
+var ErrBlocked = errors.New("blocked by user")
+var ErrBlockNotFound = errors.New("block not found")
+var ErrSelfBlock = errors.New("users can't block themselves")
+
+// A user blocking or muting another user.
+type UserBlock struct {
+	gorm.Model
+
+	UserId        uint
+	BlockedUserId uint
+
+	// One of BlockKindBlock or BlockKindMute.
+	Kind string
+}
+
+type NewBlockDto struct {
+	Username string `json:"username" validate:"required"`
+	Kind     string `json:"kind" validate:"required,oneof=block mute"`
+}
+
+type BlockDto struct {
+	UserId    uint      `json:"userId"`
+	Username  string    `json:"username"`
+	Kind      string    `json:"kind"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type BlocksService interface {
+	// List the users a user blocked or muted, most recent first.
+	ListBlocks(ctx context.Context, userId uint) ([]BlockDto, error)
+
+	// Block or mute another user. Users have at most one block per other
+	// user, blocking a muted user turns the mute into a block and the other
+	// way around.
+	// Returns ErrUserNotFound if there's no user with the username and
+	// ErrSelfBlock if it's the user's own.
+	BlockUser(ctx context.Context, userId uint, block NewBlockDto) (BlockDto, error)
+
+	// Unblock or unmute a user.
+	// Returns ErrUserNotFound if there's no user with the username and
+	// ErrBlockNotFound if the user didn't block or mute them.
+	UnblockUser(ctx context.Context, userId uint, username string) error
+
+	// Check whether a user can interact with another one, e.g. invite them
+	// to a project or endorse them. Services that handle interactions must
+	// call this before letting actorId reach targetId.
+	// Returns ErrBlocked if targetId blocked actorId.
+	CheckCanInteract(ctx context.Context, actorId uint, targetId uint) error
+}
+
+type blocksServiceImpl struct {
+	Db *gorm.DB
+}
+
+func NewBlocksService(db *gorm.DB) BlocksService {
+	return &blocksServiceImpl{Db: db}
+}
+
+func (s *blocksServiceImpl) ListBlocks(ctx context.Context, userId uint) ([]BlockDto, error) {
+	blocks, err := blockDtos(s.Db, userId)
+	if err != nil {
+		log.FromContext(ctx).WithError(err).WithField("userId", userId).Error("Failed to list blocks")
+		return nil, err
+	}
+
+	return blocks, nil
+}
+
+func (s *blocksServiceImpl) BlockUser(ctx context.Context, userId uint, newBlock NewBlockDto) (BlockDto, error) {
+	logger := log.FromContext(ctx).WithFields(log.Fields{
+		"userId":   userId,
+		"username": newBlock.Username,
+		"kind":     newBlock.Kind,
+	})
+
+	err := validator.New().Struct(newBlock)
+	if err != nil {
+		return BlockDto{}, err
+	}
+
+	blocked, err := s.findUser(ctx, newBlock.Username)
+	if err != nil {
+		return BlockDto{}, err
+	}
+
+	if blocked.ID == userId {
+		return BlockDto{}, ErrSelfBlock
+	}
+
+	block := UserBlock{}
+	err = s.Db.Transaction(func(tx *gorm.DB) error {
+		// Lock the blocking user to avoid creating the same block twice
+		err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").
+			First(&User{}, userId).
+			Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUserNotFound
+			}
+
+			return err
+		}
+
+		err = tx.
+			Where("user_id = ? AND blocked_user_id = ?", userId, blocked.ID).
+			Limit(1).
+			Find(&block).
+			Error
+		if err != nil {
+			return err
+		}
+
+		block.UserId = userId
+		block.BlockedUserId = blocked.ID
+		block.Kind = newBlock.Kind
+
+		return tx.Save(&block).Error
+	})
+	if err != nil {
+		if !errors.Is(err, ErrUserNotFound) {
+			logger.WithError(err).Error("Failed to block user")
+		}
+
+		return BlockDto{}, err
+	}
+
+	return BlockDto{
+		UserId:    blocked.ID,
+		Username:  blocked.Username,
+		Kind:      block.Kind,
+		CreatedAt: block.CreatedAt,
+	}, nil
+}
+
+func (s *blocksServiceImpl) UnblockUser(ctx context.Context, userId uint, username string) error {
+	blocked, err := s.findUser(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	result := s.Db.
+		Where("user_id = ? AND blocked_user_id = ?", userId, blocked.ID).
+		Delete(&UserBlock{})
+	if result.Error != nil {
+		log.FromContext(ctx).WithError(result.Error).WithField("userId", userId).Error("Failed to unblock user")
+		return result.Error
+	}
+
+	if result.RowsAffected < 1 {
+		return ErrBlockNotFound
+	}
+
+	return nil
+}
+
+func (s *blocksServiceImpl) CheckCanInteract(ctx context.Context, actorId uint, targetId uint) error {
+	var count int64
+	err := s.Db.
+		Model(&UserBlock{}).
+		Where("user_id = ? AND blocked_user_id = ? AND kind = ?", targetId, actorId, BlockKindBlock).
+		Count(&count).
+		Error
+	if err != nil {
+		log.FromContext(ctx).
+			WithError(err).
+			WithFields(log.Fields{"actorId": actorId, "targetId": targetId}).
+			Error("Failed to check for blocks")
+
+		return err
+	}
+
+	if count > 0 {
+		return ErrBlocked
+	}
+
+	return nil
+}
+
+// Find a user by username, ignoring case. Hidden users can still be blocked.
+func (s *blocksServiceImpl) findUser(ctx context.Context, username string) (*User, error) {
+	user := &User{}
+	err := s.Db.
+		Select("id", "username").
+		Where("LOWER(username) = LOWER(?)", username).
+		First(user).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+
+		log.FromContext(ctx).WithError(err).Error("Failed to query for user by username")
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// Get the users userId blocked or muted, most recent first.
+func blockDtos(db *gorm.DB, userId uint) ([]BlockDto, error) {
+	blocks := []BlockDto{}
+	err := db.
+		Model(&UserBlock{}).
+		Select("user_blocks.blocked_user_id AS user_id, users.username, user_blocks.kind, user_blocks.created_at").
+		Joins("JOIN users ON users.id = user_blocks.blocked_user_id").
+		Where("user_blocks.user_id = ?", userId).
+		Order("user_blocks.created_at desc").
+		Scan(&blocks).
+		Error
+
+	return blocks, err
+}
+
+// Query for the ids of the users whose content is hidden from userId, i.e.
+// the users userId blocked or muted. Meant to be used as a subquery, e.g. in
+// `Where("owner_id NOT IN (?)", users.HiddenUserIds(db, userId))`.
+func HiddenUserIds(db *gorm.DB, userId uint) *gorm.DB {
+	return db.
+		Model(&UserBlock{}).
+		Select("blocked_user_id").
+		Where("user_id = ?", userId)
+}
@@ -0,0 +1,447 @@
+package users
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"github.com/apex/log"
+	"github.com/go-playground/validator/v10"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// The user is looking for projects to collaborate on.
+	AvailabilityOpen = "open"
+	AvailabilityBusy = "busy"
+)
+
+// Profile fields a user can make public, i.e. show in the contributor
+// directory and on their public profile.
+// Must match the validation of DirectorySettingsDto.PublicFields.
+const (
+	PublicFieldDisplayName  = "displayName"
+	PublicFieldBio          = "bio"
+	PublicFieldLocation     = "location"
+	PublicFieldWebsite      = "website"
+	PublicFieldGithubHandle = "githubHandle"
+	PublicFieldGitlabHandle = "gitlabHandle"
+	PublicFieldTimezone     = "timezone"
+	PublicFieldSkills       = "skills"
+)
+
+// Fields that are public until users choose otherwise. Profiles were fully
+// public before users could choose.
+var defaultPublicFields = []string{
+	PublicFieldDisplayName,
+	PublicFieldBio,
+	PublicFieldLocation,
+	PublicFieldWebsite,
+	PublicFieldGithubHandle,
+	PublicFieldGitlabHandle,
+	PublicFieldTimezone,
+	PublicFieldSkills,
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type DirectoryService interface {
+	// Get a user's contributor directory settings.
+	// Returns ErrUserNotFound if the user can't be found.
+	GetDirectorySettings(ctx context.Context, userId uint) (DirectorySettingsDto, error)
+
+	// Replace a user's contributor directory settings. Users who weren't
+	// listed go to the top of the directory when they list themselves.
+	// Returns ErrUserNotFound if the user can't be found and
+	// ErrMissingTimezone if working hours are set but the user has no time
+	// zone.
+	UpdateDirectorySettings(ctx context.Context, userId uint, settings DirectorySettingsDto) (DirectorySettingsDto, error)
+
+	// Whether a user is listed in the contributor directory. Hidden and
+	// suspended users and users whose account is being deleted are never
+	// listed.
+	IsListed(ctx context.Context, userId uint) (bool, error)
+
+	// Search the contributor directory, most recently listed users first.
+	// Users the viewer blocked or muted are left out.
+	// Filtering by skills, time zone or working hours only finds users who
+	// made their skills or time zone public.
+	// Returns ErrInvalidCursor if params.After is not a cursor returned by
+	// a previous search.
+	SearchDirectory(ctx context.Context, params DirectorySearchDto) (DirectoryPageDto, error)
+}
+
+type directoryServiceImpl struct {
+	Db *gorm.DB
+}
+
+func NewDirectoryService(db *gorm.DB) DirectoryService {
+	return &directoryServiceImpl{Db: db}
+}
+
+func (s *directoryServiceImpl) GetDirectorySettings(ctx context.Context, userId uint) (DirectorySettingsDto, error) {
+	user := User{}
+	err := s.Db.
+		Select("id", "directory_listed_at", "availability", "weekly_hours", "work_start", "work_end", "public_fields").
+		First(&user, userId).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return DirectorySettingsDto{}, ErrUserNotFound
+		}
+
+		log.FromContext(ctx).WithError(err).WithField("userId", userId).Error("Failed to get directory settings")
+		return DirectorySettingsDto{}, err
+	}
+
+	return directorySettingsDto(user), nil
+}
+
+func (s *directoryServiceImpl) UpdateDirectorySettings(
+	ctx context.Context,
+	userId uint,
+	settings DirectorySettingsDto,
+) (DirectorySettingsDto, error) {
+	logger := log.FromContext(ctx).WithField("userId", userId)
+
+	err := validator.New().Struct(settings)
+	if err != nil {
+		return DirectorySettingsDto{}, err
+	}
+
+	publicFields := map[string]bool{}
+	for _, field := range settings.PublicFields {
+		publicFields[field] = true
+	}
+
+	fields := make([]string, 0, len(publicFields))
+	for field := range publicFields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	updates := map[string]interface{}{
+		"availability":        settings.Availability,
+		"weekly_hours":        settings.WeeklyHours,
+		"work_start":          nil,
+		"work_end":            nil,
+		"public_fields":       pq.StringArray(fields),
+		"directory_listed_at": nil,
+	}
+
+	if settings.WorkingHours != nil {
+		user := User{}
+		err := s.Db.Select("id", "timezone").First(&user, userId).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return DirectorySettingsDto{}, ErrUserNotFound
+			}
+
+			logger.WithError(err).Error("Failed to query for user's time zone")
+			return DirectorySettingsDto{}, err
+		}
+
+		if user.Timezone == "" {
+			return DirectorySettingsDto{}, ErrMissingTimezone
+		}
+
+		updates["work_start"], updates["work_end"] = settings.WorkingHours.Minutes()
+	}
+
+	if settings.Listed {
+		// Users who were already listed keep their place
+		updates["directory_listed_at"] = gorm.Expr("COALESCE(directory_listed_at, ?)", time.Now())
+	}
+
+	result := s.Db.Model(&User{}).Where("id = ?", userId).Updates(updates)
+	if result.Error != nil {
+		logger.WithError(result.Error).Error("Failed to update directory settings")
+		return DirectorySettingsDto{}, result.Error
+	}
+
+	if result.RowsAffected < 1 {
+		return DirectorySettingsDto{}, ErrUserNotFound
+	}
+
+	settings.PublicFields = fields
+
+	return settings, nil
+}
+
+func (s *directoryServiceImpl) IsListed(ctx context.Context, userId uint) (bool, error) {
+	var count int64
+	err := listedUsers(s.Db).Where("id = ?", userId).Count(&count).Error
+	if err != nil {
+		log.FromContext(ctx).WithError(err).WithField("userId", userId).Error("Failed to check whether user is listed")
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (s *directoryServiceImpl) SearchDirectory(ctx context.Context, params DirectorySearchDto) (DirectoryPageDto, error) {
+	logger := log.FromContext(ctx).WithFields(log.Fields{
+		"skills":       params.Skills,
+		"availability": params.Availability,
+	})
+
+	err := validator.New().Struct(params)
+	if err != nil {
+		return DirectoryPageDto{}, err
+	}
+
+	query := listedUsers(s.Db)
+
+	if params.ViewerId != 0 {
+		query = query.Where("id NOT IN (?)", HiddenUserIds(s.Db, params.ViewerId))
+	}
+
+	if params.Availability != "" {
+		query = query.Where("availability = ?", params.Availability)
+	}
+
+	if params.MinWeeklyHours > 0 {
+		query = query.Where("weekly_hours >= ?", params.MinWeeklyHours)
+	}
+
+	if params.MaxWeeklyHours > 0 {
+		query = query.Where("weekly_hours <= ?", params.MaxWeeklyHours)
+	}
+
+	if len(params.Skills) > 0 || params.MinProficiency != "" {
+		matchingSkills := s.Db.Model(&UserSkill{}).Select("user_id")
+
+		if len(params.Skills) > 0 {
+			names := make([]string, len(params.Skills))
+			for i, name := range params.Skills {
+				names[i] = normalizeSkill(name)
+			}
+
+			matchingSkills = matchingSkills.Where("name = ANY(?)", pq.StringArray(names))
+		}
+
+		if params.MinProficiency != "" {
+			matchingSkills = matchingSkills.Where("proficiency = ANY(?)", pq.StringArray(proficienciesFrom(params.MinProficiency)))
+		}
+
+		query = query.
+			Where("? = ANY(public_fields)", PublicFieldSkills).
+			Where("id IN (?)", matchingSkills)
+	}
+
+	if params.MinUtcOffset != nil || params.MaxUtcOffset != nil {
+		minOffset, maxOffset := -12*60, 14*60
+		if params.MinUtcOffset != nil {
+			minOffset = *params.MinUtcOffset
+		}
+
+		if params.MaxUtcOffset != nil {
+			maxOffset = *params.MaxUtcOffset
+		}
+
+		// Postgres knows the current offset of each time zone, daylight
+		// saving time included.
+		timezones := s.Db.
+			Table("pg_timezone_names").
+			Select("name").
+			Where("utc_offset BETWEEN make_interval(mins => ?) AND make_interval(mins => ?)", minOffset, maxOffset)
+
+		query = query.
+			Where("? = ANY(public_fields)", PublicFieldTimezone).
+			Where("timezone IN (?)", timezones)
+	}
+
+	if params.Overlap != nil {
+		// The user's working day is compared with the given working hours
+		// of the days around it, at the actual offsets of both time zones
+		// on that day.
+		query = query.
+			Where("? = ANY(public_fields)", PublicFieldTimezone).
+			Where(
+				"working_hours_overlap(NULLIF(timezone, ''), work_start, work_end, ?, ?, ?, ?) >= ?",
+				params.Overlap.Timezone,
+				params.Overlap.Start,
+				params.Overlap.End,
+				params.Overlap.Day,
+				params.Overlap.MinMinutes,
+			)
+	}
+
+	if params.After != "" {
+		listedAt, id, err := decodeDirectoryCursor(params.After)
+		if err != nil {
+			return DirectoryPageDto{}, err
+		}
+
+		query = query.Where("(directory_listed_at, id) < (?, ?)", listedAt, id)
+	}
+
+	// Fetch one more user than asked for, to know whether there's a
+	// next page.
+	var found []User
+	err = query.
+		Order("directory_listed_at DESC, id DESC").
+		Limit(int(params.PageSize) + 1).
+		Find(&found).
+		Error
+	if err != nil {
+		logger.WithError(err).Error("Failed to search directory")
+		return DirectoryPageDto{}, err
+	}
+
+	page := DirectoryPageDto{}
+	if len(found) > int(params.PageSize) {
+		found = found[:params.PageSize]
+
+		last := found[len(found)-1]
+		page.NextCursor = encodeDirectoryCursor(*last.DirectoryListedAt, last.ID)
+	}
+
+	skillsByUser, err := s.publicSkills(ctx, found)
+	if err != nil {
+		return DirectoryPageDto{}, err
+	}
+
+	page.Users = make([]DirectoryEntryDto, len(found))
+	for i, user := range found {
+		page.Users[i] = directoryEntryDto(user, skillsByUser[user.ID])
+	}
+
+	return page, nil
+}
+
+// Get the skills of the given users who made their skills public, grouped by
+// user id.
+func (s *directoryServiceImpl) publicSkills(ctx context.Context, users []User) (map[uint][]SkillDto, error) {
+	var userIds []uint
+	for _, user := range users {
+		if hasPublicField(user, PublicFieldSkills) {
+			userIds = append(userIds, user.ID)
+		}
+	}
+
+	skillsByUser := map[uint][]SkillDto{}
+	if len(userIds) < 1 {
+		return skillsByUser, nil
+	}
+
+	var skills []UserSkill
+	err := s.Db.Where("user_id IN ?", userIds).Order("name").Find(&skills).Error
+	if err != nil {
+		log.FromContext(ctx).WithError(err).Error("Failed to query for skills of listed users")
+		return nil, err
+	}
+
+	dtos, err := skillDtos(ctx, s.Db, skills)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, skill := range skills {
+		skillsByUser[skill.UserId] = append(skillsByUser[skill.UserId], dtos[i])
+	}
+
+	return skillsByUser, nil
+}
+
+// Query for the users listed in the contributor directory.
+func listedUsers(db *gorm.DB) *gorm.DB {
+	return db.
+		Model(&User{}).
+		Where("directory_listed_at IS NOT NULL").
+		Where("hidden_at IS NULL AND suspended_at IS NULL AND deletion_scheduled_at IS NULL")
+}
+
+func hasPublicField(user User, field string) bool {
+	for _, publicField := range user.PublicFields {
+		if publicField == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+func directorySettingsDto(user User) DirectorySettingsDto {
+	fields := []string(user.PublicFields)
+	if fields == nil {
+		fields = []string{}
+	}
+
+	return DirectorySettingsDto{
+		Listed:       user.DirectoryListedAt != nil,
+		Availability: user.Availability,
+		WeeklyHours:  user.WeeklyHours,
+		WorkingHours: WorkingHoursFromMinutes(user.WorkStart, user.WorkEnd),
+		PublicFields: fields,
+	}
+}
+
+func directoryEntryDto(user User, skills []SkillDto) DirectoryEntryDto {
+	entry := DirectoryEntryDto{
+		Id:           user.ID,
+		Username:     user.Username,
+		Availability: user.Availability,
+		WeeklyHours:  user.WeeklyHours,
+	}
+
+	for _, field := range user.PublicFields {
+		switch field {
+		case PublicFieldDisplayName:
+			entry.DisplayName = user.DisplayName
+		case PublicFieldBio:
+			entry.Bio = user.Bio
+		case PublicFieldLocation:
+			entry.Location = user.Location
+		case PublicFieldWebsite:
+			entry.Website = user.Website
+		case PublicFieldGithubHandle:
+			entry.GithubHandle = user.GithubHandle
+		case PublicFieldGitlabHandle:
+			entry.GitlabHandle = user.GitlabHandle
+		case PublicFieldTimezone:
+			entry.Timezone = user.Timezone
+			entry.WorkingHours = WorkingHoursFromMinutes(user.WorkStart, user.WorkEnd)
+		case PublicFieldSkills:
+			entry.Skills = skills
+		}
+	}
+
+	return entry
+}
+
+// Cursors point at the last user of a page, they're the time the user was
+// listed and the user's id.
+func encodeDirectoryCursor(listedAt time.Time, id uint) string {
+	cursor := listedAt.UTC().Format(time.RFC3339Nano) + "/" + strconv.FormatUint(uint64(id), 10)
+
+	return base64.RawURLEncoding.EncodeToString([]byte(cursor))
+}
+
+func decodeDirectoryCursor(cursor string) (time.Time, uint, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+
+	parts := strings.SplitN(string(decoded), "/", 2)
+	if len(parts) != 2 {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+
+	listedAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+
+	id, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+
+	return listedAt, uint(id), nil
+}
@@ -0,0 +1,85 @@
+package users
+
+// A user's contributor directory settings.
+type DirectorySettingsDto struct {
+	// Whether the user is listed in the directory.
+	Listed bool `json:"listed"`
+
+	Availability string `json:"availability" validate:"required,oneof=open busy"`
+	WeeklyHours  int    `json:"weeklyHours" validate:"min=0,max=168"`
+
+	// Daily working hours in the user's time zone, nil if not said. Used to
+	// match users and projects whose working hours overlap, whether or not
+	// the user is listed.
+	WorkingHours *WorkingHoursDto `json:"workingHours"`
+
+	// Profile fields shown in the directory and on the user's public
+	// profile. Must match PublicFields.
+	PublicFields []string `json:"publicFields" validate:"max=8,dive,oneof=displayName bio location website githubHandle gitlabHandle timezone skills"`
+}
+
+type DirectorySearchDto struct {
+	// Only list users with at least one of these skills.
+	Skills []string
+
+	// Only count skills at this proficiency or above. Empty counts all of
+	// them.
+	MinProficiency string `validate:"omitempty,oneof=learning comfortable expert"`
+
+	// Only list users with this availability. Empty lists everyone.
+	Availability string `validate:"omitempty,oneof=open busy"`
+
+	// Only list users who can give this many hours a week or more. Zero
+	// doesn't filter.
+	MinWeeklyHours int `validate:"min=0,max=168"`
+
+	// Only list users who can give this many hours a week or less. Zero
+	// doesn't filter.
+	MaxWeeklyHours int `validate:"min=0,max=168"`
+
+	// Only list users whose time zone is currently between these UTC
+	// offsets, in minutes. Nil doesn't filter.
+	MinUtcOffset *int `validate:"omitempty,min=-720,max=840"`
+	MaxUtcOffset *int `validate:"omitempty,min=-720,max=840"`
+
+	// Only list users whose working hours overlap these. Nil doesn't filter.
+	Overlap *OverlapFilter
+
+	// User searching the directory, 0 if anonymous. Users the viewer
+	// blocked or muted are not listed.
+	ViewerId uint
+
+	// Cursor of the previous page, empty for the first one.
+	After string
+
+	PageSize uint
+}
+
+// A user listed in the contributor directory. Profile fields the user didn't
+// make public are left out.
+type DirectoryEntryDto struct {
+	Id           uint   `json:"id"`
+	Username     string `json:"username"`
+	Availability string `json:"availability"`
+	WeeklyHours  int    `json:"weeklyHours"`
+
+	DisplayName  string `json:"displayName,omitempty"`
+	Bio          string `json:"bio,omitempty"`
+	Location     string `json:"location,omitempty"`
+	Website      string `json:"website,omitempty"`
+	GithubHandle string `json:"githubHandle,omitempty"`
+	GitlabHandle string `json:"gitlabHandle,omitempty"`
+	Timezone     string `json:"timezone,omitempty"`
+
+	// Shown along with the time zone.
+	WorkingHours *WorkingHoursDto `json:"workingHours,omitempty"`
+
+	Skills []SkillDto `json:"skills,omitempty"`
+}
+
+type DirectoryPageDto struct {
+	Users []DirectoryEntryDto `json:"users"`
+
+	// Cursor to get the next page with, empty on the last page.
+	NextCursor string `json:"nextCursor,omitempty"`
+}
@@ -0,0 +1,173 @@
+package users
+
+import (
+	"context"
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"github.com/apex/log"
+	"github.com/go-playground/validator/v10"
+	"github.com/open-collaboration/server/mail"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+var ErrSameEmail = errors.New("new email is the current email")
+
+func (s *serviceImpl) RequestEmailChange(ctx context.Context, id uint, newEmail string) error {
+	logger := log.FromContext(ctx).WithField("userId", id)
+
+	err := validator.New().Var(newEmail, "required,email,max=255")
+	if err != nil {
+		return err
+	}
+
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if strings.EqualFold(user.Email, newEmail) {
+		return ErrSameEmail
+	}
+
+	taken, err := s.isTaken(ctx, "email", newEmail, id)
+	if err != nil {
+		return err
+	}
+
+	if taken {
+		return &ConflictError{Field: "email"}
+	}
+
+	// Email changes share the cooldown of verification emails
+	now := time.Now()
+	result := s.Db.
+		Model(&User{}).
+		Where("id = ?", id).
+		Where("verification_sent_at IS NULL OR verification_sent_at <= ?", now.Add(-s.Verification.ResendCooldown)).
+		Updates(map[string]interface{}{
+			"pending_email":        newEmail,
+			"verification_sent_at": now,
+		})
+	if result.Error != nil {
+		logger.WithError(result.Error).Error("Failed to stage email change")
+		return result.Error
+	}
+
+	if result.RowsAffected < 1 {
+		return &RateLimitError{
+			RetryAfter: user.VerificationSentAt.Add(s.Verification.ResendCooldown).Sub(now).Round(time.Second),
+		}
+	}
+
+	expiresAt := now.Add(s.Verification.TokenTtl)
+	link := s.verificationLink(s.Verification.EmailChangeUrl, tokenPurposeEmailChange, user.ID, newEmail, expiresAt)
+
+	err = s.Mailer.Send(ctx, mail.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\n"+
+				"Please confirm that you want to use this address for your account by opening the link below. "+
+				"The link is valid until %s.\n\n"+
+				"%s\n\n"+
+				"If you didn't ask for this change, you can ignore this email.\n",
+			user.Username,
+			expiresAt.UTC().Format("Jan 2, 2006 15:04 MST"),
+			link,
+		),
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to send email change confirmation")
+		return err
+	}
+
+	// The change is staged at this point, failing to warn the old address
+	// is only logged.
+	err = s.Mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Your email address is being changed",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\n"+
+				"Someone asked to change the email address of your account to %s. "+
+				"The change only happens once the new address is confirmed.\n\n"+
+				"If it wasn't you, please reset your password.\n",
+			user.Username,
+			newEmail,
+		),
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to notify old email of email change")
+	}
+
+	return s.RecordSecurityEvent(ctx, SecurityEvent{
+		UserId:   user.ID,
+		Kind:     SecurityEventEmailChangeRequested,
+		OldEmail: user.Email,
+		NewEmail: newEmail,
+	})
+}
+
+func (s *serviceImpl) ConfirmEmailChange(ctx context.Context, token string) error {
+	logger := log.FromContext(ctx)
+
+	id, expiresAt, signature, err := parseVerificationToken(token)
+	if err != nil {
+		return err
+	}
+
+	user := User{}
+	err = s.Db.First(&user, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidVerificationToken
+		}
+
+		logger.WithError(err).Error("Failed to query for user")
+		return err
+	}
+
+	// Tokens sent to an address that is no longer pending stop working
+	expected := s.signVerificationToken(tokenPurposeEmailChange, user.ID, user.PendingEmail, expiresAt)
+	if user.PendingEmail == "" || !hmac.Equal(signature, expected) {
+		return ErrInvalidVerificationToken
+	}
+
+	if time.Now().After(expiresAt) {
+		return ErrVerificationTokenExpired
+	}
+
+	// Another user may have taken the email since it was requested, the
+	// unique index on emails catches it.
+	result := s.Db.
+		Model(&User{}).
+		Where("id = ? AND pending_email = ?", user.ID, user.PendingEmail).
+		Updates(map[string]interface{}{
+			"email":             user.PendingEmail,
+			"pending_email":     "",
+			"email_verified_at": time.Now(),
+		})
+	if result.Error != nil {
+		err = conflictFromDbError(result.Error)
+
+		var conflictErr *ConflictError
+		if !errors.As(err, &conflictErr) {
+			logger.WithError(err).Error("Failed to change email")
+		}
+
+		return err
+	}
+
+	if result.RowsAffected < 1 {
+		return ErrInvalidVerificationToken
+	}
+
+	return s.RecordSecurityEvent(ctx, SecurityEvent{
+		UserId:   user.ID,
+		Kind:     SecurityEventEmailChanged,
+		OldEmail: user.Email,
+		NewEmail: user.PendingEmail,
+	})
+}
@@ -0,0 +1,101 @@
+package users
+
+import (
+	"context"
+	"github.com/apex/log"
+	"github.com/open-collaboration/server/utils"
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	SecurityEventPasswordChanged      = "password-changed"
+	SecurityEventPasswordReset        = "password-reset"
+	SecurityEventEmailChangeRequested = "email-change-requested"
+	SecurityEventEmailChanged         = "email-changed"
+	SecurityEventDeletionRequested    = "deletion-requested"
+	SecurityEventDeletionCancelled    = "deletion-cancelled"
+)
+
+// A change to a user's credentials, kept as an audit trail.
+type SecurityEvent struct {
+	gorm.Model
+
+	UserId uint
+	Kind   string
+
+	// Client that made the change, empty if unknown.
+	Ip        string
+	UserAgent string
+
+	// Email before and after an email change.
+	OldEmail string
+	NewEmail string
+}
+
+type SecurityEventDto struct {
+	Id        uint      `json:"id"`
+	Kind      string    `json:"kind"`
+	Ip        string    `json:"ip"`
+	UserAgent string    `json:"userAgent"`
+	OldEmail  string    `json:"oldEmail,omitempty"`
+	NewEmail  string    `json:"newEmail,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (s *serviceImpl) RecordSecurityEvent(ctx context.Context, event SecurityEvent) error {
+	client := utils.ClientFromContext(ctx)
+	event.Ip = client.Ip
+	event.UserAgent = client.UserAgent
+
+	err := s.Db.Create(&event).Error
+	if err != nil {
+		log.FromContext(ctx).
+			WithError(err).
+			WithFields(log.Fields{"userId": event.UserId, "kind": event.Kind}).
+			Error("Failed to record security event")
+
+		return err
+	}
+
+	return nil
+}
+
+func (s *serviceImpl) ListSecurityEvents(
+	ctx context.Context,
+	userId uint,
+	pageSize uint,
+	pageOffset uint,
+) ([]SecurityEventDto, error) {
+	var events []SecurityEvent
+	err := s.Db.
+		Where("user_id = ?", userId).
+		Order("created_at desc").
+		Limit(int(pageSize)).
+		Offset(int(pageOffset * pageSize)).
+		Find(&events).
+		Error
+	if err != nil {
+		log.FromContext(ctx).WithError(err).WithField("userId", userId).Error("Failed to list security events")
+		return nil, err
+	}
+
+	return securityEventDtos(events), nil
+}
+
+func securityEventDtos(events []SecurityEvent) []SecurityEventDto {
+	dtos := make([]SecurityEventDto, len(events))
+	for i, event := range events {
+		dtos[i] = SecurityEventDto{
+			Id:        event.ID,
+			Kind:      event.Kind,
+			Ip:        event.Ip,
+			UserAgent: event.UserAgent,
+			OldEmail:  event.OldEmail,
+			NewEmail:  event.NewEmail,
+			CreatedAt: event.CreatedAt,
+		}
+	}
+
+	return dtos
+}
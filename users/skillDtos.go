@@ -0,0 +1,17 @@
+package users
+
+type NewSkillDto struct {
+	Name              string `json:"name" validate:"required,min=1,max=40"`
+	Proficiency       string `json:"proficiency" validate:"required,oneof=learning comfortable expert"`
+	YearsOfExperience int    `json:"yearsOfExperience" validate:"min=0,max=80"`
+}
+
+type SkillDto struct {
+	Id                uint   `json:"id"`
+	Name              string `json:"name"`
+	Proficiency       string `json:"proficiency"`
+	YearsOfExperience int    `json:"yearsOfExperience"`
+
+	// Amount of users that endorsed the skill.
+	Endorsements int `json:"endorsements"`
+}
@@ -0,0 +1,48 @@
+package users
+
+import "gorm.io/gorm"
+
+// Proficiency levels of a skill, from lowest to highest.
+const (
+	ProficiencyLearning    = "learning"
+	ProficiencyComfortable = "comfortable"
+	ProficiencyExpert      = "expert"
+)
+
+var proficiencies = []string{ProficiencyLearning, ProficiencyComfortable, ProficiencyExpert}
+
+// A skill a user declared.
+type UserSkill struct {
+	gorm.Model
+
+	UserId uint
+
+	// Lower cased and trimmed, so that skills can be matched regardless
+	// of how users write them.
+	Name string
+
+	// One of the Proficiency constants.
+	Proficiency       string
+	YearsOfExperience int
+}
+
+// A user vouching for another user's skill. Only users who have been part of
+// a project together can endorse each other.
+type SkillEndorsement struct {
+	gorm.Model
+
+	SkillId    uint
+	EndorserId uint
+}
+
+// Get the proficiency levels at or above the given one.
+// Returns nil if the proficiency is unknown.
+func proficienciesFrom(minimum string) []string {
+	for i, proficiency := range proficiencies {
+		if proficiency == minimum {
+			return proficiencies[i:]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,413 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"github.com/apex/log"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sort"
+	"strings"
+)
+
+// Maximum amount of skills a user can declare.
+const maxSkills = 50
+
+var ErrSkillNotFound = errors.New("skill not found")
+var ErrSkillExists = errors.New("skill already declared")
+var ErrTooManySkills = errors.New("too many skills")
+var ErrAlreadyEndorsed = errors.New("skill already endorsed")
+var ErrEndorsementNotFound = errors.New("endorsement not found")
+
+type SkillsService interface {
+	// List a user's skills, most endorsed first.
+	ListSkills(ctx context.Context, userId uint) ([]SkillDto, error)
+
+	// List a user's skills like ListSkills if the user made them public.
+	// Returns no skills otherwise.
+	ListPublicSkills(ctx context.Context, userId uint) ([]SkillDto, error)
+
+	// Declare a skill.
+	// Returns ErrSkillExists if the user already has a skill with the same
+	// name and ErrTooManySkills if the user has too many skills.
+	AddSkill(ctx context.Context, userId uint, skill NewSkillDto) (SkillDto, error)
+
+	// Change a skill's name, proficiency or experience. Endorsements are kept.
+	// Returns ErrSkillNotFound if the user has no such skill and
+	// ErrSkillExists if the user has another skill with the new name.
+	UpdateSkill(ctx context.Context, userId uint, skillId uint, skill NewSkillDto) (SkillDto, error)
+
+	// Remove a skill along with its endorsements.
+	// Returns ErrSkillNotFound if the user has no such skill.
+	DeleteSkill(ctx context.Context, userId uint, skillId uint) error
+
+	// Endorse a user's skill. It's up to the caller to check whether the
+	// endorser can endorse the user.
+	// Returns ErrSkillNotFound if the user has no such skill and
+	// ErrAlreadyEndorsed if the endorser already endorsed it.
+	AddEndorsement(ctx context.Context, userId uint, skillId uint, endorserId uint) error
+
+	// Withdraw an endorsement.
+	// Returns ErrEndorsementNotFound if the endorser didn't endorse the skill.
+	RemoveEndorsement(ctx context.Context, userId uint, skillId uint, endorserId uint) error
+
+	// Get a user's skills and the endorsements the user gave.
+	ExportSkills(ctx context.Context, userId uint) (SkillsExportDto, error)
+
+	// Permanently delete a user's skills, their endorsements and the
+	// endorsements the user gave.
+	DeleteUserSkills(ctx context.Context, userId uint) error
+}
+
+type skillsServiceImpl struct {
+	Db *gorm.DB
+}
+
+func NewSkillsService(db *gorm.DB) SkillsService {
+	return &skillsServiceImpl{Db: db}
+}
+
+func (s *skillsServiceImpl) ListSkills(ctx context.Context, userId uint) ([]SkillDto, error) {
+	var skills []UserSkill
+	err := s.Db.
+		Where("user_id = ?", userId).
+		Order("name").
+		Find(&skills).
+		Error
+	if err != nil {
+		log.FromContext(ctx).WithError(err).WithField("userId", userId).Error("Failed to list skills")
+		return nil, err
+	}
+
+	dtos, err := skillDtos(ctx, s.Db, skills)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(dtos, func(i, j int) bool {
+		return dtos[i].Endorsements > dtos[j].Endorsements
+	})
+
+	return dtos, nil
+}
+
+func (s *skillsServiceImpl) ListPublicSkills(ctx context.Context, userId uint) ([]SkillDto, error) {
+	user := User{}
+	result := s.Db.Select("id", "public_fields").Limit(1).Find(&user, userId)
+	if result.Error != nil {
+		log.FromContext(ctx).WithError(result.Error).WithField("userId", userId).Error("Failed to query for public fields")
+		return nil, result.Error
+	}
+
+	if !hasPublicField(user, PublicFieldSkills) {
+		return []SkillDto{}, nil
+	}
+
+	return s.ListSkills(ctx, userId)
+}
+
+func (s *skillsServiceImpl) AddSkill(ctx context.Context, userId uint, newSkill NewSkillDto) (SkillDto, error) {
+	logger := log.FromContext(ctx).WithField("userId", userId)
+
+	err := validator.New().Struct(newSkill)
+	if err != nil {
+		return SkillDto{}, err
+	}
+
+	skill := UserSkill{
+		UserId:            userId,
+		Name:              normalizeSkill(newSkill.Name),
+		Proficiency:       newSkill.Proficiency,
+		YearsOfExperience: newSkill.YearsOfExperience,
+	}
+
+	err = s.Db.Transaction(func(tx *gorm.DB) error {
+		// Lock the user so that concurrent requests can't go over the
+		// limit or add the same skill twice.
+		err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").
+			First(&User{}, userId).
+			Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUserNotFound
+			}
+
+			return err
+		}
+
+		var count int64
+		err = tx.Model(&UserSkill{}).Where("user_id = ?", userId).Count(&count).Error
+		if err != nil {
+			return err
+		}
+
+		if count >= maxSkills {
+			return ErrTooManySkills
+		}
+
+		err = checkSkillName(tx, userId, skill.Name, 0)
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&skill).Error
+	})
+	if err != nil {
+		if !isSkillError(err) {
+			logger.WithError(err).Error("Failed to add skill")
+		}
+
+		return SkillDto{}, err
+	}
+
+	return skillDto(skill, 0), nil
+}
+
+func (s *skillsServiceImpl) UpdateSkill(ctx context.Context, userId uint, skillId uint, newSkill NewSkillDto) (SkillDto, error) {
+	logger := log.FromContext(ctx).WithFields(log.Fields{
+		"userId":  userId,
+		"skillId": skillId,
+	})
+
+	err := validator.New().Struct(newSkill)
+	if err != nil {
+		return SkillDto{}, err
+	}
+
+	skill := UserSkill{}
+	err = s.Db.Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").
+			First(&User{}, userId).
+			Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUserNotFound
+			}
+
+			return err
+		}
+
+		skill, err = findSkill(tx, userId, skillId)
+		if err != nil {
+			return err
+		}
+
+		skill.Name = normalizeSkill(newSkill.Name)
+		skill.Proficiency = newSkill.Proficiency
+		skill.YearsOfExperience = newSkill.YearsOfExperience
+
+		err = checkSkillName(tx, userId, skill.Name, skillId)
+		if err != nil {
+			return err
+		}
+
+		return tx.
+			Model(&skill).
+			Select("name", "proficiency", "years_of_experience").
+			Updates(&skill).
+			Error
+	})
+	if err != nil {
+		if !isSkillError(err) {
+			logger.WithError(err).Error("Failed to update skill")
+		}
+
+		return SkillDto{}, err
+	}
+
+	dtos, err := skillDtos(ctx, s.Db, []UserSkill{skill})
+	if err != nil {
+		return SkillDto{}, err
+	}
+
+	return dtos[0], nil
+}
+
+func (s *skillsServiceImpl) DeleteSkill(ctx context.Context, userId uint, skillId uint) error {
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		result := tx.
+			Where("id = ? AND user_id = ?", skillId, userId).
+			Delete(&UserSkill{})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected < 1 {
+			return ErrSkillNotFound
+		}
+
+		return tx.Where("skill_id = ?", skillId).Delete(&SkillEndorsement{}).Error
+	})
+	if err != nil {
+		if !errors.Is(err, ErrSkillNotFound) {
+			log.FromContext(ctx).WithError(err).WithField("skillId", skillId).Error("Failed to delete skill")
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (s *skillsServiceImpl) AddEndorsement(ctx context.Context, userId uint, skillId uint, endorserId uint) error {
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		// Locking the skill keeps the same endorser from endorsing
+		// it twice concurrently.
+		skill := UserSkill{}
+		err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ?", skillId, userId).
+			First(&skill).
+			Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrSkillNotFound
+			}
+
+			return err
+		}
+
+		var count int64
+		err = tx.
+			Model(&SkillEndorsement{}).
+			Where("skill_id = ? AND endorser_id = ?", skillId, endorserId).
+			Count(&count).
+			Error
+		if err != nil {
+			return err
+		}
+
+		if count > 0 {
+			return ErrAlreadyEndorsed
+		}
+
+		return tx.Create(&SkillEndorsement{
+			SkillId:    skillId,
+			EndorserId: endorserId,
+		}).Error
+	})
+	if err != nil {
+		if !isSkillError(err) {
+			log.FromContext(ctx).WithError(err).WithField("skillId", skillId).Error("Failed to endorse skill")
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (s *skillsServiceImpl) RemoveEndorsement(ctx context.Context, userId uint, skillId uint, endorserId uint) error {
+	result := s.Db.
+		Where("skill_id = ? AND endorser_id = ?", skillId, endorserId).
+		Where("skill_id IN (?)", s.Db.Model(&UserSkill{}).Select("id").Where("user_id = ?", userId)).
+		Delete(&SkillEndorsement{})
+	if result.Error != nil {
+		log.FromContext(ctx).WithError(result.Error).WithField("skillId", skillId).Error("Failed to remove endorsement")
+		return result.Error
+	}
+
+	if result.RowsAffected < 1 {
+		return ErrEndorsementNotFound
+	}
+
+	return nil
+}
+
+// Convert skills to DTOs along with their endorsement counts. The DTOs are
+// in the same order as the skills.
+func skillDtos(ctx context.Context, db *gorm.DB, skills []UserSkill) ([]SkillDto, error) {
+	skillIds := make([]uint, len(skills))
+	for i, skill := range skills {
+		skillIds[i] = skill.ID
+	}
+
+	var counts []struct {
+		SkillId uint
+		Count   int
+	}
+	err := db.
+		Model(&SkillEndorsement{}).
+		Select("skill_id, count(*) AS count").
+		Where("skill_id IN ?", skillIds).
+		Group("skill_id").
+		Scan(&counts).
+		Error
+	if err != nil {
+		log.FromContext(ctx).WithError(err).Error("Failed to count endorsements")
+		return nil, err
+	}
+
+	endorsements := map[uint]int{}
+	for _, count := range counts {
+		endorsements[count.SkillId] = count.Count
+	}
+
+	dtos := make([]SkillDto, len(skills))
+	for i, skill := range skills {
+		dtos[i] = skillDto(skill, endorsements[skill.ID])
+	}
+
+	return dtos, nil
+}
+
+func findSkill(tx *gorm.DB, userId uint, skillId uint) (UserSkill, error) {
+	skill := UserSkill{}
+	err := tx.
+		Where("id = ? AND user_id = ?", skillId, userId).
+		First(&skill).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return UserSkill{}, ErrSkillNotFound
+	}
+
+	return skill, err
+}
+
+// Check that a user has no skill other than skillId with the given name.
+// Returns ErrSkillExists if there is one.
+func checkSkillName(tx *gorm.DB, userId uint, name string, skillId uint) error {
+	var count int64
+	err := tx.
+		Model(&UserSkill{}).
+		Where("user_id = ? AND name = ? AND id <> ?", userId, name, skillId).
+		Count(&count).
+		Error
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return ErrSkillExists
+	}
+
+	return nil
+}
+
+func normalizeSkill(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func skillDto(skill UserSkill, endorsements int) SkillDto {
+	return SkillDto{
+		Id:                skill.ID,
+		Name:              skill.Name,
+		Proficiency:       skill.Proficiency,
+		YearsOfExperience: skill.YearsOfExperience,
+		Endorsements:      endorsements,
+	}
+}
+
+// Whether err is one of the expected errors returned when managing skills.
+func isSkillError(err error) bool {
+	return errors.Is(err, ErrUserNotFound) ||
+		errors.Is(err, ErrSkillNotFound) ||
+		errors.Is(err, ErrSkillExists) ||
+		errors.Is(err, ErrTooManySkills) ||
+		errors.Is(err, ErrAlreadyEndorsed)
+}
@@ -0,0 +1,71 @@
+package users
+
+import (
+	"context"
+	"github.com/apex/log"
+	"gorm.io/gorm"
+	"time"
+)
+
+// A user's skills and the endorsements the user gave to others.
+type SkillsExportDto struct {
+	Skills            []SkillDto             `json:"skills"`
+	EndorsementsGiven []EndorsementExportDto `json:"endorsementsGiven"`
+}
+
+type EndorsementExportDto struct {
+	// Owner of the endorsed skill.
+	UserId    uint      `json:"userId"`
+	SkillId   uint      `json:"skillId"`
+	SkillName string    `json:"skillName"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (s *skillsServiceImpl) ExportSkills(ctx context.Context, userId uint) (SkillsExportDto, error) {
+	logger := log.FromContext(ctx).WithField("userId", userId)
+
+	skills, err := s.ListSkills(ctx, userId)
+	if err != nil {
+		return SkillsExportDto{}, err
+	}
+
+	endorsements := []EndorsementExportDto{}
+	err = s.Db.
+		Model(&SkillEndorsement{}).
+		Select("user_skills.user_id, skill_endorsements.skill_id, user_skills.name AS skill_name, skill_endorsements.created_at").
+		Joins("JOIN user_skills ON user_skills.id = skill_endorsements.skill_id AND user_skills.deleted_at IS NULL").
+		Where("skill_endorsements.endorser_id = ?", userId).
+		Order("skill_endorsements.created_at desc").
+		Scan(&endorsements).
+		Error
+	if err != nil {
+		logger.WithError(err).Error("Failed to export endorsements")
+		return SkillsExportDto{}, err
+	}
+
+	return SkillsExportDto{
+		Skills:            skills,
+		EndorsementsGiven: endorsements,
+	}, nil
+}
+
+func (s *skillsServiceImpl) DeleteUserSkills(ctx context.Context, userId uint) error {
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Unscoped().
+			Where("endorser_id = ? OR skill_id IN (?)", userId, tx.Unscoped().Model(&UserSkill{}).Select("id").Where("user_id = ?", userId)).
+			Delete(&SkillEndorsement{}).
+			Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Unscoped().Where("user_id = ?", userId).Delete(&UserSkill{}).Error
+	})
+	if err != nil {
+		log.FromContext(ctx).WithError(err).WithField("userId", userId).Error("Failed to delete user skills")
+		return err
+	}
+
+	return nil
+}
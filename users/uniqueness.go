@@ -0,0 +1,203 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/apex/log"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgconn"
+	"strings"
+)
+
+var ErrReservedUsername = errors.New("reserved username")
+
+// Returned when a username or email is already used by another user.
+// Usernames and emails are compared ignoring case.
+type ConflictError struct {
+	// Either "username" or "email".
+	Field string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s already taken", e.Field)
+}
+
+// Names of the unique indexes on users, created by migration "16".
+const (
+	usernameIndex = "idx_users_username_lower"
+	emailIndex    = "idx_users_email_lower"
+)
+
+// Usernames that would be confusing (e.g. look like they belong to the
+// team) or clash with routes under /users.
+var reservedUsernames = map[string]bool{
+	"about":         true,
+	"admin":         true,
+	"administrator": true,
+	"api":           true,
+	"availability":  true,
+	"confirm-email": true,
+	"help":          true,
+	"login":         true,
+	"logout":        true,
+	"me":            true,
+	"mod":           true,
+	"moderator":     true,
+	"null":          true,
+	"official":      true,
+	"register":      true,
+	"root":          true,
+	"security":      true,
+	"settings":      true,
+	"signup":        true,
+	"staff":         true,
+	"support":       true,
+	"system":        true,
+	"team":          true,
+	"undefined":     true,
+	"verify-email":  true,
+	"www":           true,
+}
+
+// Whether a username can't be registered. Names given to deleted accounts
+// are reserved too.
+func IsReservedUsername(username string) bool {
+	username = strings.ToLower(strings.TrimSpace(username))
+
+	return reservedUsernames[username] || strings.HasPrefix(username, "deleted-user-")
+}
+
+const (
+	UnavailableTaken    = "taken"
+	UnavailableReserved = "reserved"
+	UnavailableInvalid  = "invalid"
+)
+
+type AvailabilityDto struct {
+	// Nil if no username was asked for.
+	Username *FieldAvailabilityDto `json:"username,omitempty"`
+
+	// Nil if no email was asked for.
+	Email *FieldAvailabilityDto `json:"email,omitempty"`
+}
+
+type FieldAvailabilityDto struct {
+	Available bool `json:"available"`
+
+	// Why the value can't be used, one of the Unavailable constants.
+	// Empty if the value is available.
+	Reason string `json:"reason,omitempty"`
+}
+
+func (s *serviceImpl) CheckAvailability(ctx context.Context, username string, email string) (AvailabilityDto, error) {
+	availability := AvailabilityDto{}
+
+	if username != "" {
+		field := FieldAvailabilityDto{Available: true}
+
+		switch {
+		case validator.New().Var(username, usernameValidation) != nil:
+			field = FieldAvailabilityDto{Reason: UnavailableInvalid}
+		case IsReservedUsername(username):
+			field = FieldAvailabilityDto{Reason: UnavailableReserved}
+		default:
+			taken, err := s.isTaken(ctx, "username", username)
+			if err != nil {
+				return AvailabilityDto{}, err
+			}
+
+			if taken {
+				field = FieldAvailabilityDto{Reason: UnavailableTaken}
+			}
+		}
+
+		availability.Username = &field
+	}
+
+	if email != "" {
+		field := FieldAvailabilityDto{Available: true}
+
+		if validator.New().Var(email, "email") != nil {
+			field = FieldAvailabilityDto{Reason: UnavailableInvalid}
+		} else {
+			taken, err := s.isTaken(ctx, "email", email)
+			if err != nil {
+				return AvailabilityDto{}, err
+			}
+
+			if taken {
+				field = FieldAvailabilityDto{Reason: UnavailableTaken}
+			}
+		}
+
+		availability.Email = &field
+	}
+
+	return availability, nil
+}
+
+// Whether a user other than the given ones already uses a username or email.
+func (s *serviceImpl) isTaken(ctx context.Context, column string, value string, exceptIds ...uint) (bool, error) {
+	query := s.Db.Model(&User{}).Where("LOWER("+column+") = LOWER(?)", value)
+	if len(exceptIds) > 0 {
+		query = query.Where("id NOT IN ?", exceptIds)
+	}
+
+	var count int64
+	err := query.Count(&count).Error
+	if err != nil {
+		log.FromContext(ctx).WithError(err).WithField("column", column).Error("Failed to check whether a value is taken")
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// Check that a new user's username and email are free.
+// Returns ErrReservedUsername if the username is reserved and
+// *ConflictError if the username or email is taken.
+func (s *serviceImpl) checkNewUser(ctx context.Context, newUser NewUserDto) error {
+	if IsReservedUsername(newUser.Username) {
+		return ErrReservedUsername
+	}
+
+	taken, err := s.isTaken(ctx, "username", newUser.Username)
+	if err != nil {
+		return err
+	}
+
+	if taken {
+		return &ConflictError{Field: "username"}
+	}
+
+	taken, err = s.isTaken(ctx, "email", newUser.Email)
+	if err != nil {
+		return err
+	}
+
+	if taken {
+		return &ConflictError{Field: "email"}
+	}
+
+	return nil
+}
+
+// Turn violations of the username and email unique indexes, which happen
+// when two requests race for the same value, into a *ConflictError.
+// Other errors are returned as they are.
+func conflictFromDbError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23505" {
+		return err
+	}
+
+	switch pgErr.ConstraintName {
+	case usernameIndex:
+		return &ConflictError{Field: "username"}
+	case emailIndex:
+		return &ConflictError{Field: "email"}
+	default:
+		return err
+	}
+}
@@ -1,13 +1,50 @@
 package users
 
+import "time"
+
+// Must match the validation of NewUserDto.Username.
+const usernameValidation = "min=4,max=32,excludesall=@"
+
 type NewUserDto struct {
-	Username       string `json:"username" validate:"required,min=4,max=32"`
+	Username       string `json:"username" validate:"required,min=4,max=32,excludesall=@"`
 	Email          string `json:"email" validate:"required,email"`
 	Password       string `json:"password" validate:"required,min=6,max=255"`
 	RecaptchaToken string `json:"recaptchaToken"`
 }
 
 type UserDataDto struct {
-	Username string `json:"username"`
-	Email    string `json:"email"`
+	Username      string `json:"username"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"emailVerified"`
+}
+
+type VerifyEmailDto struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// A user's profile, as seen by anyone. Must never include private data such
+// as the user's email.
+type PublicProfileDto struct {
+	Id           uint      `json:"id"`
+	Username     string    `json:"username"`
+	DisplayName  string    `json:"displayName"`
+	Bio          string    `json:"bio"`
+	Location     string    `json:"location"`
+	Website      string    `json:"website"`
+	GithubHandle string    `json:"githubHandle"`
+	GitlabHandle string    `json:"gitlabHandle"`
+	Timezone     string    `json:"timezone"`
+	JoinedAt     time.Time `json:"joinedAt"`
+}
+
+// Changes to a user's profile. Fields that are not set are left as they are
+// and fields set to an empty string are cleared.
+type UpdateProfileDto struct {
+	DisplayName  *string `json:"displayName" validate:"omitempty,max=64"`
+	Bio          *string `json:"bio" validate:"omitempty,max=2000"`
+	Location     *string `json:"location" validate:"omitempty,max=100"`
+	Website      *string `json:"website" validate:"omitempty,max=255,url,startswith=http"`
+	GithubHandle *string `json:"githubHandle" validate:"omitempty,max=39"`
+	GitlabHandle *string `json:"gitlabHandle" validate:"omitempty,max=255"`
+	Timezone     *string `json:"timezone" validate:"omitempty,timezone,ne=Local"`
 }
@@ -2,8 +2,16 @@ package users
 
 import (
 	"errors"
+	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
 )
 
 type User struct {
@@ -12,6 +20,64 @@ type User struct {
 	Username     string
 	Email        string
 	PasswordHash string
+
+	// Public profile. Empty fields are not set.
+	DisplayName  string
+	Bio          string
+	Location     string
+	Website      string
+	GithubHandle string
+	GitlabHandle string
+
+	// IANA time zone, e.g. "Europe/Lisbon".
+	Timezone string
+
+	// Set while the user is listed in the contributor directory.
+	DirectoryListedAt *time.Time
+
+	// One of AvailabilityOpen or AvailabilityBusy.
+	Availability string `gorm:"default: busy"`
+
+	// Hours a week the user can give to projects, 0 if not said.
+	WeeklyHours int
+
+	// Daily working hours in Timezone, in minutes after midnight. Nil if
+	// not said.
+	WorkStart *int
+	WorkEnd   *int
+
+	// Profile fields shown in the contributor directory and on the user's
+	// public profile, from PublicFields.
+	PublicFields pq.StringArray `gorm:"type: TEXT[]"`
+
+	// Set when the user confirms their email. Unverified users can log in,
+	// but can't create projects, join teams or receive project transfers.
+	EmailVerifiedAt *time.Time
+
+	// When the last verification email was sent, used to rate limit them.
+	VerificationSentAt *time.Time
+
+	// New email the user asked to change to, waiting to be confirmed.
+	PendingEmail string
+
+	// When the user's account will be anonymized, set when the user asks
+	// for it to be deleted.
+	DeletionScheduledAt *time.Time
+
+	// When the account's deletion was last attempted. Accounts whose
+	// deletion keeps failing are retried after the other due ones.
+	DeletionAttemptedAt *time.Time
+
+	// One of RoleUser, RoleModerator or RoleAdmin.
+	Role string `gorm:"default: user"`
+
+	// Set when a moderator suspends the account. Suspended
+	// users can't authenticate.
+	SuspendedAt *time.Time
+
+	// Set when the user's profile is hidden by the moderation
+	// team (e.g. after being reported too many times).
+	HiddenAt *time.Time
 }
 
 func (user *User) SetPassword(plainTextPassword string) error {
@@ -37,3 +103,21 @@ func (user *User) ComparePassword(plainTextPassword string) (bool, error) {
 		return true, nil
 	}
 }
+
+// Whether the user can review reports and take moderation actions.
+// Admins are moderators too.
+func (user *User) IsModerator() bool {
+	return user.Role == RoleModerator || user.Role == RoleAdmin
+}
+
+func (user *User) IsAdmin() bool {
+	return user.Role == RoleAdmin
+}
+
+func (user *User) IsEmailVerified() bool {
+	return user.EmailVerifiedAt != nil
+}
+
+func (user *User) IsSuspended() bool {
+	return user.SuspendedAt != nil
+}
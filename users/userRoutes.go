@@ -30,3 +30,85 @@ func RouteRegisterUser(
 
 	return nil
 }
+
+// @Summary Verify your email
+// @Description Confirm an email with the token sent to it. Verifying an email twice is not an error.
+// @Tags users
+// @Router /users/verify-email [post]
+// @Param token body dtos.VerifyEmailDto true "The verification token"
+// @Success 204
+func RouteVerifyEmail(
+	writer http.ResponseWriter,
+	request *http.Request,
+	usersService Service,
+) error {
+	dto := VerifyEmailDto{}
+	err := utils.ReadJson(request.Context(), request, &dto)
+	if err != nil {
+		return err
+	}
+
+	err = usersService.VerifyEmail(request.Context(), dto.Token)
+	if err != nil {
+		return err
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// @Summary Confirm a new email
+// @Description Confirm an email change with the token sent to the new email.
+// @Tags users
+// @Router /users/confirm-email [post]
+// @Param token body dtos.VerifyEmailDto true "The confirmation token"
+// @Success 204
+func RouteConfirmEmailChange(
+	writer http.ResponseWriter,
+	request *http.Request,
+	usersService Service,
+) error {
+	dto := VerifyEmailDto{}
+	err := utils.ReadJson(request.Context(), request, &dto)
+	if err != nil {
+		return err
+	}
+
+	err = usersService.ConfirmEmailChange(request.Context(), dto.Token)
+	if err != nil {
+		return err
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// @Summary Check whether a username and email are available
+// @Description For the signup form. Only the given values are checked. Usernames and emails are compared ignoring
+// @Description case.
+// @Tags users
+// @Router /users/availability [get]
+// @Param username query string false "The username to check"
+// @Param email query string false "The email to check"
+// @Success 200 {object} dtos.AvailabilityDto
+func RouteCheckAvailability(
+	writer http.ResponseWriter,
+	request *http.Request,
+	usersService Service,
+) error {
+	query := request.URL.Query()
+
+	availability, err := usersService.CheckAvailability(request.Context(), query.Get("username"), query.Get("email"))
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusOK, availability)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
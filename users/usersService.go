@@ -4,46 +4,188 @@ import (
 	"context"
 	"errors"
 	"github.com/apex/log"
+	"github.com/go-playground/validator/v10"
+	"github.com/open-collaboration/server/mail"
 	"gorm.io/gorm"
+	"regexp"
+	"strings"
+	"time"
 )
 
 var ErrUserNotFound = errors.New("user not found")
 
+// Returned when a GitHub or GitLab handle has characters that can't be part
+// of a username on those sites.
+var ErrInvalidHandle = errors.New("invalid handle")
+
+var (
+	githubHandle = regexp.MustCompile(`^[A-Za-z0-9](-?[A-Za-z0-9])*$`)
+	gitlabHandle = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*$`)
+)
+
 type Service interface {
-	// Create a user.
+	// Create a user. New users start with an unverified email and are
+	// sent a verification email. Failing to send the email doesn't fail
+	// the registration, the user can ask for it to be sent again.
+	// Returns ErrReservedUsername if the username is reserved and
+	// *ConflictError if the username or email is taken, ignoring case.
 	CreateUser(ctx context.Context, newUser NewUserDto) error
 
+	// Check whether a username and email can be used to register. Empty
+	// values are not checked.
+	CheckAvailability(ctx context.Context, username string, email string) (AvailabilityDto, error)
+
+	// Send a new verification email to a user. Only one email is sent per
+	// VerificationConfig.ResendCooldown.
+	// Returns ErrUserNotFound if the user can't be found, ErrEmailAlreadyVerified
+	// if the user's email is already verified and *RateLimitError if an email
+	// was sent too recently.
+	SendVerificationEmail(ctx context.Context, id uint) error
+
+	// Mark the email of the user a verification token was sent to as
+	// verified. Verifying an email twice is not an error.
+	// Returns ErrInvalidVerificationToken if the token isn't valid (including
+	// tokens sent to an email the user no longer has) and
+	// ErrVerificationTokenExpired if it expired.
+	VerifyEmail(ctx context.Context, token string) error
+
+	// Check whether a user verified their email. Unverified users can log
+	// in, but can't create projects, join teams or receive project transfers.
+	// Returns ErrUserNotFound if the user can't be found and
+	// ErrEmailNotVerified if the user's email isn't verified.
+	CheckEmailVerified(ctx context.Context, id uint) error
+
 	// Get a user by id.
 	// Returns ErrUserNotFound if a user with the specified id cannot be found.
 	GetUser(ctx context.Context, id uint) (*User, error)
 
+	// Find a user by username or, if the value has an @, by email and then
+	// by username, for usernames from before @ was forbidden. Both are
+	// matched ignoring case.
+	// Returns ErrUserNotFound if no user matches.
 	FindUserByUsernameOrEmail(ctx context.Context, usernameOrEmail string) (*User, error)
+
+	// Change a user's password.
+	// Returns ErrUserNotFound if a user with the specified id cannot be found.
+	SetPassword(ctx context.Context, id uint, plainTextPassword string) error
+
+	// Stage a change of a user's email. The new email only replaces the
+	// current one once confirmed with a token sent to it, and the current
+	// email is told about the change. Shares the cooldown of
+	// SendVerificationEmail. Callers are expected to check the user's
+	// password first.
+	// Returns ErrUserNotFound if the user can't be found, ErrSameEmail if
+	// newEmail is the user's email, *ConflictError if another user has it and
+	// *RateLimitError if a verification email was sent too recently.
+	RequestEmailChange(ctx context.Context, id uint, newEmail string) error
+
+	// Replace a user's email with the one a token was sent to by
+	// RequestEmailChange. The new email counts as verified.
+	// Returns ErrInvalidVerificationToken if the token isn't valid (including
+	// tokens for an email that is no longer pending), ErrVerificationTokenExpired
+	// if it expired and *ConflictError if another user took the email in the
+	// meantime.
+	ConfirmEmailChange(ctx context.Context, token string) error
+
+	// Add an event to a user's security audit trail. The client in ctx (see
+	// utils.ClientFromContext) is recorded along with it.
+	RecordSecurityEvent(ctx context.Context, event SecurityEvent) error
+
+	// List the events of a user's security audit trail, newest to oldest.
+	ListSecurityEvents(ctx context.Context, userId uint, pageSize uint, pageOffset uint) ([]SecurityEventDto, error)
+
+	// Get everything stored about a user's account, including the user's
+	// security events.
+	// Returns ErrUserNotFound if a user with the specified id cannot be found.
+	ExportAccount(ctx context.Context, id uint) (AccountExportDto, error)
+
+	// Schedule the deletion of a user's account.
+	// Returns ErrUserNotFound if a user with the specified id cannot be found.
+	ScheduleDeletion(ctx context.Context, id uint, at time.Time) error
+
+	// Cancel the scheduled deletion of a user's account. Returns whether a
+	// deletion was scheduled.
+	CancelDeletion(ctx context.Context, id uint) (bool, error)
+
+	// List the ids of users whose deletion is due, the ones never attempted
+	// first and then the least recently attempted, and mark them as
+	// attempted. Accounts whose deletion keeps failing thus don't keep the
+	// others from being deleted.
+	ListDueDeletions(ctx context.Context, limit uint) ([]uint, error)
+
+	// Remove all personal data from a user's account and delete it, along
+	// with the user's security events. The account's row is kept so that
+	// content the user authored stays attributed to an anonymous user.
+	// Returns ErrUserNotFound if a user with the specified id cannot be found.
+	AnonymizeUser(ctx context.Context, id uint) error
+
+	// Suspend a user's account. Suspended users can't authenticate, it's up
+	// to the caller to invalidate the user's sessions.
+	// Returns ErrUserNotFound if a user with the specified id cannot be found.
+	SuspendUser(ctx context.Context, id uint) error
+
+	// Hide or unhide a user's profile.
+	// Returns ErrUserNotFound if a user with the specified id cannot be found.
+	SetUserHidden(ctx context.Context, id uint, hidden bool) error
+
+	// Get a user's public profile by username. Fields the user didn't make
+	// public are left empty. Hidden users are treated as if they didn't
+	// exist.
+	// Returns ErrUserNotFound if the user can't be found.
+	GetPublicProfile(ctx context.Context, username string) (PublicProfileDto, error)
+
+	// Update a user's profile.
+	// Returns ErrUserNotFound if the user can't be found and ErrInvalidHandle
+	// if a GitHub or GitLab handle is invalid.
+	UpdateProfile(ctx context.Context, id uint, profile UpdateProfileDto) (PublicProfileDto, error)
 }
 
 type serviceImpl struct {
-	Db *gorm.DB
+	Db           *gorm.DB
+	Mailer       mail.Mailer
+	Verification VerificationConfig
 }
 
-func NewService(db *gorm.DB) Service {
-	return &serviceImpl{Db: db}
+func NewService(db *gorm.DB, mailer mail.Mailer, verification VerificationConfig) Service {
+	return &serviceImpl{
+		Db:           db,
+		Mailer:       mailer,
+		Verification: verification,
+	}
 }
 
 func (s *serviceImpl) CreateUser(ctx context.Context, newUser NewUserDto) error {
+	err := validator.New().Struct(newUser)
+	if err != nil {
+		return err
+	}
+
+	err = s.checkNewUser(ctx, newUser)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
 	user := User{
-		Username: newUser.Username,
-		Email:    newUser.Email,
+		Username:           newUser.Username,
+		Email:              newUser.Email,
+		VerificationSentAt: &now,
+		PublicFields:       defaultPublicFields,
 	}
 
-	err := user.SetPassword(newUser.Password)
+	err = user.SetPassword(newUser.Password)
 	if err != nil {
 		return err
 	}
 
 	result := s.Db.Create(&user)
 	if result.Error != nil {
-		return result.Error
+		return conflictFromDbError(result.Error)
 	}
 
+	// Already logged
+	_ = s.sendVerificationEmail(ctx, &user, now)
+
 	return nil
 }
 
@@ -72,11 +214,24 @@ func (s *serviceImpl) FindUserByUsernameOrEmail(ctx context.Context, usernameOrE
 
 	logger.Debug("Searching for user on database")
 
+	// Usernames can't have an @, so the value usually matches only one of
+	// them. Usernames from before that rule can still have one though, so
+	// they're looked for when no email matches.
+	columns := []string{"username"}
+	if strings.Contains(usernameOrEmail, "@") {
+		columns = []string{"email", "username"}
+	}
+
 	user := &User{}
-	result := s.Db.
-		Where("username = ?", usernameOrEmail).
-		Or("email = ?", usernameOrEmail).
-		First(user)
+	var result *gorm.DB
+	for _, column := range columns {
+		result = s.Db.
+			Where("LOWER("+column+") = LOWER(?)", usernameOrEmail).
+			First(user)
+		if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			break
+		}
+	}
 
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
@@ -94,3 +249,221 @@ func (s *serviceImpl) FindUserByUsernameOrEmail(ctx context.Context, usernameOrE
 
 	return user, nil
 }
+
+func (s *serviceImpl) SetPassword(ctx context.Context, id uint, plainTextPassword string) error {
+	logger := log.FromContext(ctx).WithField("userId", id)
+
+	user := User{}
+	err := user.SetPassword(plainTextPassword)
+	if err != nil {
+		return err
+	}
+
+	result := s.Db.
+		Model(&User{}).
+		Where("id = ?", id).
+		Update("password_hash", user.PasswordHash)
+	if result.Error != nil {
+		logger.WithError(result.Error).Error("Failed to change password")
+
+		return result.Error
+	}
+
+	if result.RowsAffected < 1 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (s *serviceImpl) SuspendUser(ctx context.Context, id uint) error {
+	logger := log.FromContext(ctx).WithField("userId", id)
+
+	logger.Debug("Suspending user")
+
+	result := s.Db.
+		Model(&User{}).
+		Where("id = ? AND suspended_at IS NULL", id).
+		Update("suspended_at", time.Now())
+	if result.Error != nil {
+		logger.WithError(result.Error).Error("Failed to suspend user")
+
+		return result.Error
+	}
+
+	if result.RowsAffected < 1 {
+		// The user is either already suspended or doesn't exist
+		_, err := s.GetUser(ctx, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *serviceImpl) SetUserHidden(ctx context.Context, id uint, hidden bool) error {
+	logger := log.FromContext(ctx).WithFields(log.Fields{
+		"userId": id,
+		"hidden": hidden,
+	})
+
+	logger.Debug("Changing user visibility")
+
+	var hiddenAt *time.Time
+	if hidden {
+		now := time.Now()
+		hiddenAt = &now
+	}
+
+	result := s.Db.
+		Model(&User{}).
+		Where("id = ?", id).
+		Update("hidden_at", hiddenAt)
+	if result.Error != nil {
+		logger.WithError(result.Error).Error("Failed to change user visibility")
+
+		return result.Error
+	}
+
+	if result.RowsAffected < 1 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (s *serviceImpl) GetPublicProfile(ctx context.Context, username string) (PublicProfileDto, error) {
+	user := User{}
+	err := s.Db.
+		Where("LOWER(username) = LOWER(?) AND hidden_at IS NULL", username).
+		First(&user).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return PublicProfileDto{}, ErrUserNotFound
+		}
+
+		log.FromContext(ctx).WithError(err).Error("Failed to query for user by username")
+		return PublicProfileDto{}, err
+	}
+
+	return publicFieldsOnly(publicProfileDto(&user), user.PublicFields), nil
+}
+
+func (s *serviceImpl) UpdateProfile(ctx context.Context, id uint, profile UpdateProfileDto) (PublicProfileDto, error) {
+	logger := log.FromContext(ctx).WithField("userId", id)
+
+	err := validator.New().Struct(profile)
+	if err != nil {
+		return PublicProfileDto{}, err
+	}
+
+	updates := map[string]interface{}{}
+
+	setField := func(column string, value *string) {
+		if value != nil {
+			updates[column] = strings.TrimSpace(*value)
+		}
+	}
+
+	setField("display_name", profile.DisplayName)
+	setField("bio", profile.Bio)
+	setField("location", profile.Location)
+	setField("website", profile.Website)
+	setField("timezone", profile.Timezone)
+
+	// Handles are often written as @handle
+	if profile.GithubHandle != nil {
+		handle := strings.TrimPrefix(strings.TrimSpace(*profile.GithubHandle), "@")
+		if handle != "" && !githubHandle.MatchString(handle) {
+			return PublicProfileDto{}, ErrInvalidHandle
+		}
+
+		updates["github_handle"] = handle
+	}
+
+	if profile.GitlabHandle != nil {
+		handle := strings.TrimPrefix(strings.TrimSpace(*profile.GitlabHandle), "@")
+		if handle != "" && !gitlabHandle.MatchString(handle) {
+			return PublicProfileDto{}, ErrInvalidHandle
+		}
+
+		updates["gitlab_handle"] = handle
+	}
+
+	user := User{}
+	err = s.Db.Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			result := tx.Model(&User{}).Where("id = ?", id).Updates(updates)
+			if result.Error != nil {
+				return result.Error
+			}
+
+			if result.RowsAffected < 1 {
+				return ErrUserNotFound
+			}
+		}
+
+		err := tx.First(&user, id).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+
+		return err
+	})
+	if err != nil {
+		if !errors.Is(err, ErrUserNotFound) {
+			logger.WithError(err).Error("Failed to update profile")
+		}
+
+		return PublicProfileDto{}, err
+	}
+
+	return publicProfileDto(&user), nil
+}
+
+// Clear the fields of a profile that aren't in publicFields.
+func publicFieldsOnly(profile PublicProfileDto, publicFields []string) PublicProfileDto {
+	public := PublicProfileDto{
+		Id:       profile.Id,
+		Username: profile.Username,
+		JoinedAt: profile.JoinedAt,
+	}
+
+	for _, field := range publicFields {
+		switch field {
+		case PublicFieldDisplayName:
+			public.DisplayName = profile.DisplayName
+		case PublicFieldBio:
+			public.Bio = profile.Bio
+		case PublicFieldLocation:
+			public.Location = profile.Location
+		case PublicFieldWebsite:
+			public.Website = profile.Website
+		case PublicFieldGithubHandle:
+			public.GithubHandle = profile.GithubHandle
+		case PublicFieldGitlabHandle:
+			public.GitlabHandle = profile.GitlabHandle
+		case PublicFieldTimezone:
+			public.Timezone = profile.Timezone
+		}
+	}
+
+	return public
+}
+
+func publicProfileDto(user *User) PublicProfileDto {
+	return PublicProfileDto{
+		Id:           user.ID,
+		Username:     user.Username,
+		DisplayName:  user.DisplayName,
+		Bio:          user.Bio,
+		Location:     user.Location,
+		Website:      user.Website,
+		GithubHandle: user.GithubHandle,
+		GitlabHandle: user.GitlabHandle,
+		Timezone:     user.Timezone,
+		JoinedAt:     user.CreatedAt,
+	}
+}
@@ -0,0 +1,229 @@
+package users
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/apex/log"
+	"github.com/open-collaboration/server/mail"
+	"gorm.io/gorm"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrEmailNotVerified = errors.New("email not verified")
+var ErrEmailAlreadyVerified = errors.New("email already verified")
+var ErrInvalidVerificationToken = errors.New("invalid verification token")
+var ErrVerificationTokenExpired = errors.New("verification token expired")
+
+// What a token signed by signVerificationToken is for, tokens for one
+// purpose can't be used for the other.
+const (
+	tokenPurposeVerification = "email-verification"
+	tokenPurposeEmailChange  = "email-change"
+)
+
+// Returned when an action is attempted again too soon.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited, retry after %s", e.RetryAfter)
+}
+
+// Settings of email verification.
+type VerificationConfig struct {
+	// Key used to sign verification tokens.
+	Secret []byte
+
+	// How long verification tokens are valid for.
+	TokenTtl time.Duration
+
+	// Minimum time between two verification emails sent to the same user.
+	ResendCooldown time.Duration
+
+	// Page where users confirm their email. The token is added to it as
+	// the token query parameter.
+	VerifyUrl string
+
+	// Page where users confirm a new email, like VerifyUrl.
+	EmailChangeUrl string
+}
+
+func (s *serviceImpl) SendVerificationEmail(ctx context.Context, id uint) error {
+	logger := log.FromContext(ctx).WithField("userId", id)
+
+	now := time.Now()
+
+	// Claim the right to send an email in the same query that checks the
+	// cooldown, so concurrent requests can't both send one.
+	result := s.Db.
+		Model(&User{}).
+		Where("id = ? AND email_verified_at IS NULL", id).
+		Where("verification_sent_at IS NULL OR verification_sent_at <= ?", now.Add(-s.Verification.ResendCooldown)).
+		Update("verification_sent_at", now)
+	if result.Error != nil {
+		logger.WithError(result.Error).Error("Failed to update verification email timestamp")
+		return result.Error
+	}
+
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected < 1 {
+		if user.IsEmailVerified() {
+			return ErrEmailAlreadyVerified
+		}
+
+		return &RateLimitError{
+			RetryAfter: user.VerificationSentAt.Add(s.Verification.ResendCooldown).Sub(now).Round(time.Second),
+		}
+	}
+
+	return s.sendVerificationEmail(ctx, user, now)
+}
+
+func (s *serviceImpl) VerifyEmail(ctx context.Context, token string) error {
+	logger := log.FromContext(ctx)
+
+	id, expiresAt, signature, err := parseVerificationToken(token)
+	if err != nil {
+		return err
+	}
+
+	user := User{}
+	err = s.Db.First(&user, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidVerificationToken
+		}
+
+		logger.WithError(err).Error("Failed to query for user")
+		return err
+	}
+
+	// The email is part of the signature, tokens sent to an old address
+	// stop working when the email changes.
+	expected := s.signVerificationToken(tokenPurposeVerification, user.ID, user.Email, expiresAt)
+	if !hmac.Equal(signature, expected) {
+		return ErrInvalidVerificationToken
+	}
+
+	if user.IsEmailVerified() {
+		return nil
+	}
+
+	if time.Now().After(expiresAt) {
+		return ErrVerificationTokenExpired
+	}
+
+	err = s.Db.
+		Model(&User{}).
+		Where("id = ? AND email = ? AND email_verified_at IS NULL", user.ID, user.Email).
+		Update("email_verified_at", time.Now()).
+		Error
+	if err != nil {
+		logger.WithError(err).Error("Failed to mark email as verified")
+		return err
+	}
+
+	return nil
+}
+
+func (s *serviceImpl) CheckEmailVerified(ctx context.Context, id uint) error {
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if !user.IsEmailVerified() {
+		return ErrEmailNotVerified
+	}
+
+	return nil
+}
+
+func (s *serviceImpl) sendVerificationEmail(ctx context.Context, user *User, now time.Time) error {
+	expiresAt := now.Add(s.Verification.TokenTtl)
+	link := s.verificationLink(s.Verification.VerifyUrl, tokenPurposeVerification, user.ID, user.Email, expiresAt)
+
+	err := s.Mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\n"+
+				"Please confirm your email address by opening the link below. "+
+				"The link is valid until %s.\n\n"+
+				"%s\n\n"+
+				"If you didn't create an account, you can ignore this email.\n",
+			user.Username,
+			expiresAt.UTC().Format("Jan 2, 2006 15:04 MST"),
+			link,
+		),
+	})
+	if err != nil {
+		log.FromContext(ctx).WithError(err).WithField("userId", user.ID).Error("Failed to send verification email")
+		return err
+	}
+
+	return nil
+}
+
+// Create a link to a page with a token that proves the user can read the
+// email's inbox.
+func (s *serviceImpl) verificationLink(page string, purpose string, id uint, email string, expiresAt time.Time) string {
+	token := fmt.Sprintf(
+		"%d.%d.%s",
+		id,
+		expiresAt.Unix(),
+		base64.RawURLEncoding.EncodeToString(s.signVerificationToken(purpose, id, email, expiresAt)),
+	)
+
+	if strings.Contains(page, "?") {
+		return page + "&token=" + url.QueryEscape(token)
+	}
+
+	return page + "?token=" + url.QueryEscape(token)
+}
+
+// Sign the contents of a verification token. Signatures of different
+// purposes, emails or expiry times never match.
+func (s *serviceImpl) signVerificationToken(purpose string, id uint, email string, expiresAt time.Time) []byte {
+	mac := hmac.New(sha256.New, s.Verification.Secret)
+	mac.Write([]byte(fmt.Sprintf("%s\x00%d\x00%d\x00%s", purpose, id, expiresAt.Unix(), strings.ToLower(email))))
+
+	return mac.Sum(nil)
+}
+
+// Split a token in the format "<user id>.<expiry unix time>.<signature>".
+func parseVerificationToken(token string) (uint, time.Time, []byte, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, time.Time{}, nil, ErrInvalidVerificationToken
+	}
+
+	id, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return 0, time.Time{}, nil, ErrInvalidVerificationToken
+	}
+
+	expiresAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, time.Time{}, nil, ErrInvalidVerificationToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return 0, time.Time{}, nil, ErrInvalidVerificationToken
+	}
+
+	return uint(id), time.Unix(expiresAt, 0), signature, nil
+}
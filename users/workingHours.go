@@ -0,0 +1,94 @@
+package users
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Returned when setting working hours without a time zone to read them in.
+var ErrMissingTimezone = errors.New("working hours need a time zone")
+
+// Returned when filtering by overlap with the working hours of a user or
+// project that didn't set them.
+var ErrNoWorkingHours = errors.New("no working hours set")
+
+// A daily working-hours window in local time, as HH:MM. Windows that end
+// before they start cross midnight, e.g. 22:00 to 02:00.
+type WorkingHoursDto struct {
+	Start string `json:"start" validate:"required,datetime=15:04"`
+	End   string `json:"end" validate:"required,datetime=15:04,nefield=Start"`
+}
+
+// Get the window's start and end in minutes after midnight. The window must
+// be valid.
+func (dto WorkingHoursDto) Minutes() (int, int) {
+	return clockMinutes(dto.Start), clockMinutes(dto.End)
+}
+
+// Build the DTO of a window stored in minutes after midnight. Returns nil if
+// the window isn't set.
+func WorkingHoursFromMinutes(start *int, end *int) *WorkingHoursDto {
+	if start == nil || end == nil {
+		return nil
+	}
+
+	return &WorkingHoursDto{
+		Start: fmt.Sprintf("%02d:%02d", *start/60, *start%60),
+		End:   fmt.Sprintf("%02d:%02d", *end/60, *end%60),
+	}
+}
+
+// Working hours to compare others with, see the working_hours_overlap SQL
+// function.
+type OverlapFilter struct {
+	// IANA time zone of Start and End.
+	Timezone string
+
+	// Minutes after midnight, in Timezone. Windows that end before they start
+	// cross midnight.
+	Start int
+	End   int
+
+	// Least amount of overlap, in minutes.
+	MinMinutes int
+
+	// Day on which the overlap is computed. Time zones change offsets on
+	// different days, so overlaps vary over the year.
+	Day time.Time
+}
+
+// Get a filter for the users or projects whose working hours overlap the
+// given ones by at least minMinutes today, and by at least a minute when
+// minMinutes is 0.
+// Returns ErrNoWorkingHours if the time zone or working hours are not set.
+func NewOverlapFilter(timezone string, start *int, end *int, minMinutes int) (*OverlapFilter, error) {
+	if timezone == "" || start == nil || end == nil {
+		return nil, ErrNoWorkingHours
+	}
+
+	if minMinutes < 1 {
+		minMinutes = 1
+	}
+
+	return &OverlapFilter{
+		Timezone:   timezone,
+		Start:      *start,
+		End:        *end,
+		MinMinutes: minMinutes,
+		Day:        time.Now().UTC(),
+	}, nil
+}
+
+func (user *User) OverlapFilter(minMinutes int) (*OverlapFilter, error) {
+	return NewOverlapFilter(user.Timezone, user.WorkStart, user.WorkEnd, minMinutes)
+}
+
+func clockMinutes(clock string) int {
+	parsed, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0
+	}
+
+	return parsed.Hour()*60 + parsed.Minute()
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+// Who made a request, as far as the server can tell.
+type Client struct {
+	// Address the request came from. Proxies in front of the server are not
+	// taken into account.
+	Ip        string
+	UserAgent string
+}
+
+type clientKey struct{}
+
+// Get the client of a request. The ip is the request's remote address
+// without its port.
+func ClientFromRequest(request *http.Request) Client {
+	ip, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		ip = request.RemoteAddr
+	}
+
+	return Client{
+		Ip:        ip,
+		UserAgent: request.UserAgent(),
+	}
+}
+
+// Add a request's client to a context.
+func WithClient(ctx context.Context, client Client) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+// Get the client added to a context by WithClient. Returns an empty client
+// if there is none, e.g. for work not started by a request.
+func ClientFromContext(ctx context.Context) Client {
+	client, _ := ctx.Value(clientKey{}).(Client)
+
+	return client
+}
@@ -3,6 +3,7 @@ package utils
 import (
 	"fmt"
 	"os"
+	"strconv"
 )
 
 // Get an environment variable or panic if it is not set.
@@ -14,3 +15,29 @@ func GetEnvOrPanic(key string) string {
 
 	return val
 }
+
+// Get an environment variable. If the variable is not set, `def` is returned.
+func GetEnvOrDefault(key string, def string) string {
+	val, present := os.LookupEnv(key)
+	if !present || val == "" {
+		return def
+	}
+
+	return val
+}
+
+// Get an integer environment variable. If the variable is not set, `def`
+// is returned. Panics if the variable is set but is not an integer.
+func GetEnvIntOrDefault(key string, def int) int {
+	val, present := os.LookupEnv(key)
+	if !present || val == "" {
+		return def
+	}
+
+	intVal, err := strconv.Atoi(val)
+	if err != nil {
+		panic(fmt.Sprintf("\"%s\" environment variable is not an integer", key))
+	}
+
+	return intVal
+}
@@ -2,15 +2,23 @@ package utils
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"github.com/apex/log"
 	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
 	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
+var ErrInvalidParam = errors.New("invalid parameter")
+var ErrMissingParam = errors.New("missing parameter")
+
 // Read the request body as JSON and unmarshal it into `dto`.
 // The request body is unmarshalled with json.Unmarshal.
 func ReadJson(ctx context.Context, request *http.Request, dto interface{}) error {
@@ -83,6 +91,42 @@ func IntFromQuery(request *http.Request, param string, def int) (int, bool) {
 	}
 }
 
+// Get a list of values from query parameter `param`. Values can be given as
+// multiple parameters (?tags=a&tags=b) or comma separated (?tags=a,b). Empty
+// values are dropped.
+// Returns nil if the parameter was not set.
+func ListFromQuery(request *http.Request, param string) []string {
+	var list []string
+	for _, value := range request.URL.Query()[param] {
+		for _, item := range strings.Split(value, ",") {
+			item = strings.TrimSpace(item)
+			if item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+
+	return list
+}
+
+// Get an unsigned int value from the route parameter `param` (e.g. "projectId"
+// in "/projects/{projectId}").
+// Returns ErrMissingParam if the route has no such parameter and ErrInvalidParam
+// if the parameter value is not an unsigned integer.
+func UintFromVars(request *http.Request, param string) (uint, error) {
+	value, ok := mux.Vars(request)[param]
+	if !ok {
+		return 0, ErrMissingParam
+	}
+
+	val, err := strconv.ParseUint(value, 10, 0)
+	if err != nil {
+		return 0, ErrInvalidParam
+	}
+
+	return uint(val), nil
+}
+
 // Read the request's body into a slice of bytes.
 func ReadBody(r *http.Request) ([]byte, error) {
 	bytes := make([]byte, 0)
@@ -109,3 +153,39 @@ func ReadBody(r *http.Request) ([]byte, error) {
 
 	return bytes, nil
 }
+
+// Get a strong ETag for a response body.
+func ETag(body []byte) string {
+	hash := sha256.Sum256(body)
+
+	return "\"" + hex.EncodeToString(hash[:16]) + "\""
+}
+
+// Whether a conditional request's cached copy is still fresh. As in RFC 7232,
+// If-Modified-Since is ignored when If-None-Match is present.
+func IsNotModified(request *http.Request, etag string, lastModified time.Time) bool {
+	ifNoneMatch := request.Header.Get("If-None-Match")
+	if ifNoneMatch != "" {
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	ifModifiedSince := request.Header.Get("If-Modified-Since")
+	if ifModifiedSince == "" || lastModified.IsZero() {
+		return false
+	}
+
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+
+	// HTTP dates have a precision of one second
+	return !lastModified.Truncate(time.Second).After(since)
+}
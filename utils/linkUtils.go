@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Normalize a repository link so that different ways of writing the same
+// link can be compared, e.g. "https://www.GitHub.com/Foo/Bar.git/" and
+// "github.com/foo/bar" both become "github.com/foo/bar". The scheme, "www."
+// prefix, query, fragment, ".git" suffix and trailing slashes are removed and
+// the link is lower cased. Links that can't be parsed are only trimmed and
+// lower cased.
+func NormalizeRepoLink(link string) string {
+	link = strings.ToLower(strings.TrimSpace(link))
+	if link == "" {
+		return ""
+	}
+
+	if !strings.Contains(link, "://") {
+		link = "https://" + link
+	}
+
+	parsed, err := url.Parse(link)
+	if err != nil || parsed.Host == "" {
+		return strings.TrimPrefix(strings.TrimPrefix(link, "https://"), "http://")
+	}
+
+	host := strings.TrimPrefix(parsed.Host, "www.")
+	path := strings.TrimRight(parsed.Path, "/")
+	path = strings.TrimSuffix(path, ".git")
+	path = strings.TrimRight(path, "/")
+
+	return host + path
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"errors"
+	"golang.org/x/text/language"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+var ErrInvalidLocale = errors.New("invalid locale")
+
+// Get the locales preferred by the client of a request, most preferred
+// first. Locales in the `lang` query parameter (e.g. ?lang=pt-BR,pt) come
+// before the ones in the Accept-Language header. Invalid locales are
+// dropped, the valid ones around them are still used.
+func LocalesFromRequest(request *http.Request) []language.Tag {
+	var locales []language.Tag
+
+	for _, lang := range ListFromQuery(request, "lang") {
+		tag, err := language.Parse(lang)
+		if err == nil {
+			locales = append(locales, tag)
+		}
+	}
+
+	// Entries are parsed one by one, because a single invalid entry makes
+	// language.ParseAcceptLanguage drop the whole header.
+	type acceptedLocale struct {
+		Tag    language.Tag
+		Weight float32
+	}
+
+	var accepted []acceptedLocale
+	for _, entry := range strings.Split(request.Header.Get("Accept-Language"), ",") {
+		tags, weights, err := language.ParseAcceptLanguage(entry)
+		if err != nil {
+			continue
+		}
+
+		for i, tag := range tags {
+			accepted = append(accepted, acceptedLocale{Tag: tag, Weight: weights[i]})
+		}
+	}
+
+	// Most preferred first, entries with the same weight keep their order
+	sort.SliceStable(accepted, func(i, j int) bool {
+		return accepted[i].Weight > accepted[j].Weight
+	})
+
+	for _, locale := range accepted {
+		locales = append(locales, locale.Tag)
+	}
+
+	return locales
+}
+
+// Normalize a BCP 47 locale, e.g. "PT-br" becomes "pt-BR".
+// Returns ErrInvalidLocale if the locale can't be parsed.
+func CanonicalLocale(locale string) (string, error) {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return "", ErrInvalidLocale
+	}
+
+	return tag.String(), nil
+}
+
+// Pick the locale in `available` that best matches the preferred ones.
+// Returns the index of the chosen locale, the first locale is the fallback
+// when none of them match.
+func MatchLocale(available []string, preferred []language.Tag) int {
+	tags := make([]language.Tag, len(available))
+	for i, locale := range available {
+		tags[i] = language.Make(locale)
+	}
+
+	_, index, confidence := language.NewMatcher(tags).Match(preferred...)
+	if confidence == language.No {
+		return 0
+	}
+
+	return index
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"strings"
+	"unicode"
+)
+
+// Maximum length of a slug generated by Slugify.
+const MaxSlugLength = 48
+
+// Turn a name into a URL friendly slug, e.g. "Open Collab: Server!" into
+// "open-collab-server". Accents are removed and every other character that
+// isn't an ASCII letter or digit becomes a dash. Returns an empty string if
+// the name has no usable characters.
+func Slugify(name string) string {
+	removeAccents := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	name, _, err := transform.String(removeAccents, name)
+	if err != nil {
+		return ""
+	}
+
+	builder := strings.Builder{}
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			builder.WriteRune(r)
+			dash = false
+		} else if !dash && builder.Len() > 0 {
+			builder.WriteRune('-')
+			dash = true
+		}
+
+		if builder.Len() >= MaxSlugLength {
+			break
+		}
+	}
+
+	return strings.Trim(builder.String(), "-")
+}
+
+const base62Alphabet = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// Encode a number in base 62, used to build short links.
+func EncodeBase62(n uint64) string {
+	if n == 0 {
+		return "0"
+	}
+
+	var digits []byte
+	for n > 0 {
+		digits = append([]byte{base62Alphabet[n%62]}, digits...)
+		n /= 62
+	}
+
+	return string(digits)
+}
+
+// Decode a base 62 number encoded with EncodeBase62. Returns false if
+// the string isn't a valid base 62 number.
+func DecodeBase62(s string) (uint64, bool) {
+	if s == "" || len(s) > 10 {
+		return 0, false
+	}
+
+	var n uint64
+	for _, r := range s {
+		digit := strings.IndexRune(base62Alphabet, r)
+		if digit < 0 {
+			return 0, false
+		}
+
+		n = n*62 + uint64(digit)
+	}
+
+	return n, true
+}
@@ -0,0 +1,102 @@
+package webhooks
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// Returned when a webhook's host resolves to an address deliveries can't be
+// sent to.
+var ErrForbiddenAddress = errors.New("webhook address is not public")
+
+// Address ranges deliveries can't be sent to, on top of the loopback,
+// link-local and unspecified ones.
+var forbiddenNetworks = parseNetworks(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"fc00::/7",
+)
+
+// Create the client deliveries are sent with. Webhook URLs are chosen by
+// project maintainers, so the client only connects to public addresses and
+// doesn't follow redirects, otherwise webhooks could be used to reach
+// services in the server's network and read their responses in the delivery
+// log.
+func NewClient(timeout time.Duration) *http.Client {
+	// Addresses are checked after the host is resolved, right before
+	// connecting, so hosts can't resolve to a public address when checked
+	// and a private one when used.
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   checkAddress,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// Going through a proxy would check the proxy's address instead of the
+	// webhook's.
+	transport.Proxy = nil
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Dialer control that refuses to connect to non public addresses.
+// Returns ErrForbiddenAddress if the address isn't public.
+func checkAddress(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublicIp(ip) {
+		return ErrForbiddenAddress
+	}
+
+	return nil
+}
+
+func isPublicIp(ip net.IP) bool {
+	if ip.IsLoopback() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsUnspecified() {
+		return false
+	}
+
+	for _, network := range forbiddenNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+
+		networks[i] = network
+	}
+
+	return networks
+}
@@ -0,0 +1,60 @@
+package webhooks
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIsPublicIp(t *testing.T) {
+	cases := map[string]bool{
+		"8.8.8.8":              true,
+		"2001:4860:4860::8888": true,
+		"127.0.0.1":            false,
+		"::1":                  false,
+		"0.0.0.0":              false,
+		"::":                   false,
+		"10.1.2.3":             false,
+		"172.16.0.1":           false,
+		"192.168.1.1":          false,
+		"100.64.0.1":           false,
+		"169.254.169.254":      false,
+		"fe80::1":              false,
+		"fd00::1":              false,
+		"::ffff:10.0.0.1":      false,
+		"::ffff:127.0.0.1":     false,
+	}
+
+	for address, public := range cases {
+		if got := isPublicIp(net.ParseIP(address)); got != public {
+			t.Errorf("isPublicIp(%s) = %v, want %v", address, got, public)
+		}
+	}
+}
+
+func TestClientRefusesLoopback(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	_, err := NewClient(time.Second).Post(server.URL, "application/json", nil)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("expected ErrForbiddenAddress, got %v", err)
+	}
+
+	if requests > 0 {
+		t.Fatalf("expected no requests to reach the server, got %d", requests)
+	}
+}
+
+func TestClientDoesNotFollowRedirects(t *testing.T) {
+	err := NewClient(time.Second).CheckRedirect(nil, nil)
+	if !errors.Is(err, http.ErrUseLastResponse) {
+		t.Fatalf("expected http.ErrUseLastResponse, got %v", err)
+	}
+}
@@ -0,0 +1,236 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/apex/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers sent with each delivery.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderSignature = "X-Webhook-Signature-256"
+)
+
+// Maximum amount of deliveries claimed by a dispatcher at a time.
+const deliveryBatchSize = 20
+
+// How long a claimed delivery is kept from other dispatchers. If a
+// dispatcher dies mid-delivery, the delivery is retried after this.
+const deliveryClaimTimeout = 5 * time.Minute
+
+// Amount of bytes of the response body kept in the delivery log.
+const maxLoggedResponse = 1024
+
+type RetryPolicy struct {
+	// Amount of attempts after which a delivery is given up on.
+	MaxAttempts int
+
+	// Delay before the first retry. Each retry after it waits twice as
+	// long as the previous one, up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// How long to wait before retrying a delivery that failed `attempts` times.
+func (p RetryPolicy) Backoff(attempts int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempts && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	return delay
+}
+
+// Sends the deliveries queued by the publisher. Several dispatchers can run
+// at once, each delivery is only claimed by one of them at a time.
+type Dispatcher struct {
+	Db     *gorm.DB
+	Client *http.Client
+	Retry  RetryPolicy
+
+	// How often to look for due deliveries.
+	PollInterval time.Duration
+}
+
+func NewDispatcher(db *gorm.DB, client *http.Client, retry RetryPolicy, pollInterval time.Duration) *Dispatcher {
+	return &Dispatcher{
+		Db:           db,
+		Client:       client,
+		Retry:        retry,
+		PollInterval: pollInterval,
+	}
+}
+
+// Send due deliveries every PollInterval until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Keep going while there are full batches of due deliveries
+			for {
+				sent, err := d.DeliverPending(ctx)
+				if err != nil || sent < deliveryBatchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// Send a batch of due deliveries.
+// Returns the amount of deliveries that were attempted.
+func (d *Dispatcher) DeliverPending(ctx context.Context) (int, error) {
+	logger := log.FromContext(ctx)
+
+	var deliveries []WebhookDelivery
+	err := d.Db.Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", DeliveryStatusPending, time.Now()).
+			Order("next_attempt_at").
+			Limit(deliveryBatchSize).
+			Find(&deliveries).
+			Error
+		if err != nil || len(deliveries) < 1 {
+			return err
+		}
+
+		ids := make([]uint, len(deliveries))
+		for i, delivery := range deliveries {
+			ids[i] = delivery.ID
+		}
+
+		return tx.
+			Model(&WebhookDelivery{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", time.Now().Add(deliveryClaimTimeout)).
+			Error
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to claim webhook deliveries")
+		return 0, err
+	}
+
+	for i := range deliveries {
+		err = d.attempt(ctx, &deliveries[i])
+		if err != nil {
+			logger.
+				WithError(err).
+				WithField("deliveryId", deliveries[i].ID).
+				Error("Failed to record webhook delivery attempt")
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+// Send a delivery once and record the outcome.
+func (d *Dispatcher) attempt(ctx context.Context, delivery *WebhookDelivery) error {
+	attempt := WebhookDeliveryAttempt{DeliveryId: delivery.ID}
+
+	hook := ProjectWebhook{}
+	err := d.Db.First(&hook, delivery.WebhookId).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		attempt.Error = "webhook was deleted"
+	case err != nil:
+		return err
+	case !hook.Active:
+		attempt.Error = "webhook is inactive"
+	default:
+		start := time.Now()
+		attempt.StatusCode, attempt.ResponseBody, err = d.send(ctx, hook, delivery)
+		attempt.DurationMs = time.Since(start).Milliseconds()
+
+		if err != nil {
+			attempt.Error = err.Error()
+		} else if attempt.StatusCode < 200 || attempt.StatusCode > 299 {
+			attempt.Error = "unexpected status " + strconv.Itoa(attempt.StatusCode)
+		}
+	}
+
+	delivery.Attempts++
+	switch {
+	case attempt.Error == "":
+		delivery.Status = DeliveryStatusSucceeded
+	case hook.ID == 0 || !hook.Active || delivery.Attempts >= d.Retry.MaxAttempts:
+		delivery.Status = DeliveryStatusFailed
+	default:
+		delivery.NextAttemptAt = time.Now().Add(d.Retry.Backoff(delivery.Attempts))
+	}
+
+	return d.Db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(&attempt).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.
+			Model(delivery).
+			Select("status", "attempts", "next_attempt_at").
+			Updates(delivery).
+			Error
+	})
+}
+
+// Post a delivery's payload to a webhook.
+// Returns the response's status code and the beginning of its body.
+func (d *Dispatcher) send(ctx context.Context, hook ProjectWebhook, delivery *WebhookDelivery) (int, string, error) {
+	body := []byte(delivery.Payload)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "OpenCollaboration-Webhooks")
+	request.Header.Set(HeaderEvent, delivery.EventType)
+	request.Header.Set(HeaderDelivery, strconv.Itoa(int(delivery.ID)))
+	request.Header.Set(HeaderSignature, Sign(hook.Secret, body))
+
+	response, err := d.Client.Do(request)
+	if err != nil {
+		return 0, "", err
+	}
+	defer response.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(io.LimitReader(response.Body, maxLoggedResponse))
+	if err != nil {
+		return response.StatusCode, "", err
+	}
+
+	return response.StatusCode, string(responseBody), nil
+}
+
+// Sign a payload with a webhook's secret. Receivers can verify deliveries by
+// computing the HMAC-SHA256 of the request body with the secret and comparing
+// it with the signature header, which is formatted as "sha256=<hex digest>".
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,390 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/open-collaboration/server/events"
+	"github.com/open-collaboration/server/migrations"
+	"github.com/open-collaboration/server/projects"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The tests that send deliveries need a Postgres database, given as a DSN in
+// the TEST_PG_DSN environment variable, e.g.
+// "host=localhost port=5432 user=root password=changeme dbname=opencollab_test sslmode=disable".
+// The database is migrated and its webhook tables are emptied, so it must
+// not be one whose data matters.
+const testDsnVariable = "TEST_PG_DSN"
+
+// A request received by a testReceiver.
+type receivedRequest struct {
+	Header http.Header
+	Body   []byte
+}
+
+// A local webhook receiver that records the requests it gets and answers
+// them with a fixed status and body.
+type testReceiver struct {
+	*httptest.Server
+
+	mutex    sync.Mutex
+	requests []receivedRequest
+}
+
+func newTestReceiver(t *testing.T, status int, body string) *testReceiver {
+	receiver := &testReceiver{}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		requestBody, err := ioutil.ReadAll(request.Body)
+		if err != nil {
+			t.Errorf("failed to read delivery body: %v", err)
+		}
+
+		receiver.mutex.Lock()
+		receiver.requests = append(receiver.requests, receivedRequest{Header: request.Header, Body: requestBody})
+		receiver.mutex.Unlock()
+
+		writer.WriteHeader(status)
+		_, _ = writer.Write([]byte(body))
+	}))
+	t.Cleanup(receiver.Close)
+
+	return receiver
+}
+
+func (r *testReceiver) Requests() []receivedRequest {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]receivedRequest{}, r.requests...)
+}
+
+// A project with a webhook pointing to a receiver.
+type testFixture struct {
+	Db         *gorm.DB
+	Service    Service
+	Dispatcher *Dispatcher
+	Receiver   *testReceiver
+	OwnerId    uint
+	ProjectId  uint
+	Webhook    WebhookDto
+}
+
+func newTestFixture(t *testing.T, receiver *testReceiver, retry RetryPolicy) *testFixture {
+	dsn := testDsn(t)
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("failed to connect to the test database: %v", err)
+	}
+
+	err = migrations.GetMigration(db).Migrate()
+	if err != nil {
+		t.Fatalf("failed to migrate the test database: %v", err)
+	}
+
+	// Deliveries left by other tests would be claimed by the dispatcher
+	err = db.Exec("TRUNCATE webhook_delivery_attempts, webhook_deliveries, project_webhooks").Error
+	if err != nil {
+		t.Fatalf("failed to empty the webhook tables: %v", err)
+	}
+
+	ownerId := uint(time.Now().UnixNano() % 1000000000)
+	project := projects.Project{
+		Name:       "Webhook test",
+		Slug:       fmt.Sprintf("webhook-test-%d", time.Now().UnixNano()),
+		OwnerId:    ownerId,
+		Visibility: projects.VisibilityPublic,
+	}
+
+	err = db.Create(&project).Error
+	if err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+
+	err = db.Create(&projects.ProjectMember{ProjectId: project.ID, UserId: ownerId, Role: projects.MemberRoleOwner}).Error
+	if err != nil {
+		t.Fatalf("failed to create project member: %v", err)
+	}
+
+	service := NewService(db, projects.NewService(db, projects.QuotaPolicy{}, NewPublisher(), nil))
+
+	hook, err := service.CreateWebhook(context.Background(), ownerId, project.ID, NewWebhookDto{Url: receiver.URL})
+	if err != nil {
+		t.Fatalf("failed to create webhook: %v", err)
+	}
+
+	return &testFixture{
+		Db:         db,
+		Service:    service,
+		Dispatcher: NewDispatcher(db, receiver.Client(), retry, time.Second),
+		Receiver:   receiver,
+		OwnerId:    ownerId,
+		ProjectId:  project.ID,
+		Webhook:    hook,
+	}
+}
+
+func testDsn(t *testing.T) string {
+	dsn := strings.TrimSpace(os.Getenv(testDsnVariable))
+	if dsn == "" {
+		t.Skipf("%s is not set", testDsnVariable)
+	}
+
+	return dsn
+}
+
+// Publish an event of the fixture's project and return its delivery.
+func (f *testFixture) publish(t *testing.T) WebhookDelivery {
+	err := NewPublisher().Publish(context.Background(), f.Db, events.Event{
+		Type:      events.ProjectUpdated,
+		ProjectId: f.ProjectId,
+		ActorId:   f.OwnerId,
+		Data:      map[string]string{"name": "Webhook test"},
+	})
+	if err != nil {
+		t.Fatalf("failed to publish event: %v", err)
+	}
+
+	delivery := WebhookDelivery{}
+	err = f.Db.Where("webhook_id = ?", f.Webhook.Id).Order("id desc").First(&delivery).Error
+	if err != nil {
+		t.Fatalf("failed to find the delivery: %v", err)
+	}
+
+	return delivery
+}
+
+// Send the due deliveries, checking that the expected amount was attempted.
+func (f *testFixture) deliverPending(t *testing.T, expected int) {
+	sent, err := f.Dispatcher.DeliverPending(context.Background())
+	if err != nil {
+		t.Fatalf("failed to deliver: %v", err)
+	}
+
+	if sent != expected {
+		t.Fatalf("expected %d deliveries to be attempted, got %d", expected, sent)
+	}
+}
+
+func (f *testFixture) reload(t *testing.T, delivery *WebhookDelivery) {
+	err := f.Db.First(delivery, delivery.ID).Error
+	if err != nil {
+		t.Fatalf("failed to reload delivery: %v", err)
+	}
+}
+
+func TestDeliverPendingSignsPayload(t *testing.T) {
+	receiver := newTestReceiver(t, http.StatusOK, "ok")
+	fixture := newTestFixture(t, receiver, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour})
+
+	delivery := fixture.publish(t)
+	fixture.deliverPending(t, 1)
+
+	requests := receiver.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(requests))
+	}
+
+	request := requests[0]
+
+	mac := hmac.New(sha256.New, []byte(fixture.Webhook.Secret))
+	mac.Write(request.Body)
+	expectedSignature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	signature := request.Header.Get(HeaderSignature)
+	if !hmac.Equal([]byte(signature), []byte(expectedSignature)) {
+		t.Errorf("signature %q doesn't match the body, expected %q", signature, expectedSignature)
+	}
+
+	if request.Header.Get(HeaderEvent) != events.ProjectUpdated {
+		t.Errorf("expected event header %q, got %q", events.ProjectUpdated, request.Header.Get(HeaderEvent))
+	}
+
+	if request.Header.Get(HeaderDelivery) != strconv.Itoa(int(delivery.ID)) {
+		t.Errorf("expected delivery header %d, got %q", delivery.ID, request.Header.Get(HeaderDelivery))
+	}
+
+	if string(request.Body) != delivery.Payload {
+		t.Errorf("expected the stored payload to be sent, got %s", request.Body)
+	}
+
+	fixture.reload(t, &delivery)
+	if delivery.Status != DeliveryStatusSucceeded || delivery.Attempts != 1 {
+		t.Errorf("expected a succeeded delivery with 1 attempt, got %s with %d", delivery.Status, delivery.Attempts)
+	}
+}
+
+func TestDeliverPendingRetriesWithBackoff(t *testing.T) {
+	// Longer than what's kept in the attempt log
+	responseBody := "error: " + strings.Repeat("x", 2*maxLoggedResponse)
+	receiver := newTestReceiver(t, http.StatusInternalServerError, responseBody)
+
+	retry := RetryPolicy{MaxAttempts: 4, BaseDelay: time.Minute, MaxDelay: time.Hour}
+	fixture := newTestFixture(t, receiver, retry)
+
+	delivery := fixture.publish(t)
+
+	for attempts := 1; attempts <= retry.MaxAttempts; attempts++ {
+		before := time.Now()
+		fixture.deliverPending(t, 1)
+		after := time.Now()
+
+		fixture.reload(t, &delivery)
+
+		if delivery.Attempts != attempts {
+			t.Fatalf("expected %d attempts, got %d", attempts, delivery.Attempts)
+		}
+
+		if attempts == retry.MaxAttempts {
+			if delivery.Status != DeliveryStatusFailed {
+				t.Fatalf("expected the delivery to fail after %d attempts, got %s", attempts, delivery.Status)
+			}
+
+			break
+		}
+
+		if delivery.Status != DeliveryStatusPending {
+			t.Fatalf("expected the delivery to be retried after %d attempts, got %s", attempts, delivery.Status)
+		}
+
+		// Each retry waits twice as long as the previous one
+		backoff := retry.BaseDelay << (attempts - 1)
+		earliest := before.Add(backoff).Add(-time.Second)
+		latest := after.Add(backoff).Add(time.Second)
+		if delivery.NextAttemptAt.Before(earliest) || delivery.NextAttemptAt.After(latest) {
+			t.Fatalf(
+				"expected attempt %d to be retried in %s, got %s",
+				attempts,
+				backoff,
+				delivery.NextAttemptAt.Sub(after),
+			)
+		}
+
+		// Nothing is due until the backoff is over
+		fixture.deliverPending(t, 0)
+
+		err := fixture.Db.Model(&delivery).Update("next_attempt_at", time.Now()).Error
+		if err != nil {
+			t.Fatalf("failed to make the delivery due: %v", err)
+		}
+	}
+
+	// Failed deliveries are not retried
+	fixture.deliverPending(t, 0)
+
+	if len(receiver.Requests()) != retry.MaxAttempts {
+		t.Fatalf("expected %d requests, got %d", retry.MaxAttempts, len(receiver.Requests()))
+	}
+
+	var attempts []WebhookDeliveryAttempt
+	err := fixture.Db.Where("delivery_id = ?", delivery.ID).Order("id").Find(&attempts).Error
+	if err != nil {
+		t.Fatalf("failed to list attempts: %v", err)
+	}
+
+	if len(attempts) != retry.MaxAttempts {
+		t.Fatalf("expected %d logged attempts, got %d", retry.MaxAttempts, len(attempts))
+	}
+
+	for i, attempt := range attempts {
+		if attempt.StatusCode != http.StatusInternalServerError {
+			t.Errorf("attempt %d: expected status %d, got %d", i+1, http.StatusInternalServerError, attempt.StatusCode)
+		}
+
+		if attempt.ResponseBody != responseBody[:maxLoggedResponse] {
+			t.Errorf("attempt %d: expected the first %d bytes of the response to be logged, got %d bytes",
+				i+1, maxLoggedResponse, len(attempt.ResponseBody))
+		}
+
+		if attempt.Error == "" {
+			t.Errorf("attempt %d: expected an error to be logged", i+1)
+		}
+	}
+}
+
+func TestRedeliveryKeepsEventId(t *testing.T) {
+	receiver := newTestReceiver(t, http.StatusOK, "ok")
+	fixture := newTestFixture(t, receiver, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour})
+
+	original := fixture.publish(t)
+	fixture.deliverPending(t, 1)
+
+	redelivery, err := fixture.Service.Redeliver(
+		context.Background(),
+		fixture.OwnerId,
+		fixture.ProjectId,
+		fixture.Webhook.Id,
+		original.ID,
+	)
+	if err != nil {
+		t.Fatalf("failed to redeliver: %v", err)
+	}
+
+	fixture.deliverPending(t, 1)
+
+	requests := receiver.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+
+	payloads := make([]PayloadDto, len(requests))
+	for i, request := range requests {
+		err = json.Unmarshal(request.Body, &payloads[i])
+		if err != nil {
+			t.Fatalf("failed to parse payload: %v", err)
+		}
+	}
+
+	if payloads[0].Id == "" || payloads[1].Id != payloads[0].Id {
+		t.Errorf("expected the redelivery to keep event id %q, got %q", payloads[0].Id, payloads[1].Id)
+	}
+
+	if redelivery.EventId != original.EventId {
+		t.Errorf("expected the redelivery to keep event id %q, got %q", original.EventId, redelivery.EventId)
+	}
+
+	if redelivery.RedeliveryOf == nil || *redelivery.RedeliveryOf != original.ID {
+		t.Errorf("expected the redelivery to point to delivery %d", original.ID)
+	}
+
+	if requests[1].Header.Get(HeaderDelivery) != strconv.Itoa(int(redelivery.Id)) {
+		t.Errorf("expected delivery header %d, got %q", redelivery.Id, requests[1].Header.Get(HeaderDelivery))
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	retry := RetryPolicy{MaxAttempts: 8, BaseDelay: 30 * time.Second, MaxDelay: time.Hour}
+
+	expected := []time.Duration{
+		30 * time.Second,
+		time.Minute,
+		2 * time.Minute,
+		4 * time.Minute,
+		8 * time.Minute,
+		16 * time.Minute,
+		32 * time.Minute,
+		time.Hour,
+		time.Hour,
+	}
+
+	for i, delay := range expected {
+		if got := retry.Backoff(i + 1); got != delay {
+			t.Errorf("Backoff(%d) = %s, want %s", i+1, got, delay)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/apex/log"
+	"github.com/open-collaboration/server/events"
+	"gorm.io/gorm"
+	"time"
+)
+
+type publisherImpl struct{}
+
+// Create an events.Publisher that queues a delivery for each webhook
+// subscribed to an event. Deliveries are stored along with the changes that
+// caused the events and sent later by a Dispatcher.
+func NewPublisher() events.Publisher {
+	return &publisherImpl{}
+}
+
+func (p *publisherImpl) Publish(ctx context.Context, db *gorm.DB, published ...events.Event) error {
+	logger := log.FromContext(ctx)
+
+	for _, event := range published {
+		var hooks []ProjectWebhook
+		err := db.
+			Select("id").
+			Where("project_id = ? AND active", event.ProjectId).
+			Where("cardinality(event_types) = 0 OR ? = ANY(event_types)", event.Type).
+			Find(&hooks).
+			Error
+		if err != nil {
+			logger.WithError(err).Error("Failed to find webhooks")
+			return err
+		}
+
+		if len(hooks) < 1 {
+			continue
+		}
+
+		eventId, err := randomHex(16)
+		if err != nil {
+			return err
+		}
+
+		payload, err := json.Marshal(PayloadDto{
+			Id:         eventId,
+			Type:       event.Type,
+			ProjectId:  event.ProjectId,
+			ActorId:    event.ActorId,
+			OccurredAt: time.Now().UTC(),
+			Data:       event.Data,
+		})
+		if err != nil {
+			logger.WithError(err).Error("Failed to serialize webhook payload")
+			return err
+		}
+
+		deliveries := make([]WebhookDelivery, len(hooks))
+		for i, hook := range hooks {
+			deliveries[i] = WebhookDelivery{
+				WebhookId:     hook.ID,
+				EventId:       eventId,
+				EventType:     event.Type,
+				Payload:       string(payload),
+				Status:        DeliveryStatusPending,
+				NextAttemptAt: time.Now(),
+			}
+		}
+
+		err = db.Create(&deliveries).Error
+		if err != nil {
+			logger.WithError(err).Error("Failed to queue webhook deliveries")
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Generate n random bytes, encoded as hex.
+func randomHex(n int) (string, error) {
+	bytes := make([]byte, n)
+	_, err := rand.Read(bytes)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(bytes), nil
+}
@@ -0,0 +1,64 @@
+package webhooks
+
+import (
+	"github.com/lib/pq"
+	"time"
+)
+
+type NewWebhookDto struct {
+	Url        string   `json:"url" validate:"required,url,startswith=http,max=2000"`
+	EventTypes []string `json:"eventTypes" validate:"max=20,dive,oneof=project.updated project.transferred project.hidden project.unhidden role.opened role.closed milestone.created milestone.updated task.created task.updated task.moved task.deleted"`
+}
+
+type UpdateWebhookDto struct {
+	Url        string   `json:"url" validate:"required,url,startswith=http,max=2000"`
+	EventTypes []string `json:"eventTypes" validate:"max=20,dive,oneof=project.updated project.transferred project.hidden project.unhidden role.opened role.closed milestone.created milestone.updated task.created task.updated task.moved task.deleted"`
+	Active     bool     `json:"active"`
+}
+
+type WebhookDto struct {
+	Id         uint           `json:"id"`
+	Url        string         `json:"url"`
+	EventTypes pq.StringArray `json:"eventTypes" swaggertype:"array,string"`
+	Active     bool           `json:"active"`
+	CreatedAt  time.Time      `json:"createdAt"`
+
+	// Only sent when the webhook is created.
+	Secret string `json:"secret,omitempty"`
+}
+
+type DeliverySummaryDto struct {
+	Id            uint      `json:"id"`
+	EventId       string    `json:"eventId"`
+	EventType     string    `json:"eventType"`
+	Status        string    `json:"status"`
+	Attempts      int       `json:"attempts"`
+	NextAttemptAt time.Time `json:"nextAttemptAt"`
+	RedeliveryOf  *uint     `json:"redeliveryOf"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+type AttemptDto struct {
+	StatusCode   int       `json:"statusCode"`
+	Error        string    `json:"error"`
+	ResponseBody string    `json:"responseBody"`
+	DurationMs   int64     `json:"durationMs"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+type DeliveryDto struct {
+	DeliverySummaryDto
+
+	Payload    string       `json:"payload"`
+	AttemptLog []AttemptDto `json:"attemptLog"`
+}
+
+// Body of the requests sent to webhooks.
+type PayloadDto struct {
+	Id         string      `json:"id"`
+	Type       string      `json:"type"`
+	ProjectId  uint        `json:"projectId"`
+	ActorId    uint        `json:"actorId,omitempty"`
+	OccurredAt time.Time   `json:"occurredAt"`
+	Data       interface{} `json:"data"`
+}
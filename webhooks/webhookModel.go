@@ -0,0 +1,70 @@
+package webhooks
+
+import (
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusSucceeded = "succeeded"
+	DeliveryStatusFailed    = "failed"
+)
+
+// A URL that receives a project's events.
+type ProjectWebhook struct {
+	gorm.Model
+
+	ProjectId uint
+	Url       string
+
+	// Key used to sign deliveries with HMAC-SHA256.
+	Secret string
+
+	// Types of events sent to the webhook. Empty means all types.
+	EventTypes pq.StringArray `gorm:"type: TEXT[]"`
+
+	// Inactive webhooks don't get new deliveries.
+	Active bool
+}
+
+// An event to be sent, or already sent, to a webhook.
+type WebhookDelivery struct {
+	gorm.Model
+
+	WebhookId uint
+	EventId   string
+	EventType string
+
+	// Request body sent to the webhook.
+	Payload string
+
+	Status   string
+	Attempts int
+
+	// When the next attempt is due, if the delivery is pending.
+	NextAttemptAt time.Time
+
+	// Id of the delivery this one sends again, if it is a manual
+	// redelivery.
+	RedeliveryOf *uint
+}
+
+// The outcome of one attempt at sending a delivery.
+type WebhookDeliveryAttempt struct {
+	gorm.Model
+
+	DeliveryId uint
+
+	// Status code of the response, 0 if there was no response.
+	StatusCode int
+
+	// Why the attempt failed, if it did.
+	Error string
+
+	// Beginning of the response body.
+	ResponseBody string
+
+	DurationMs int64
+}
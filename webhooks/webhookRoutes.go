@@ -0,0 +1,313 @@
+package webhooks
+
+import (
+	"github.com/open-collaboration/server/auth"
+	"github.com/open-collaboration/server/utils"
+	"net/http"
+)
+
+// @Summary List a project's webhooks
+// @Tags webhooks
+// @Router /projects/{projectId}/webhooks [get]
+// @Param projectId path int true "The project ID"
+// @Success 200 {array} dtos.WebhookDto
+func RouteListWebhooks(
+	writer http.ResponseWriter,
+	request *http.Request,
+	webhooksService Service,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	projectId, err := utils.UintFromVars(request, "projectId")
+	if err != nil {
+		return err
+	}
+
+	hooks, err := webhooksService.ListWebhooks(request.Context(), session.UserId(), projectId)
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusOK, hooks)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Add a webhook to a project
+// @Description Deliveries are only sent to public addresses and redirects aren't followed. Attempts to reach
+// @Description other addresses fail and are logged like any other failed attempt.
+// @Tags webhooks
+// @Router /projects/{projectId}/webhooks [post]
+// @Param projectId path int true "The project ID"
+// @Param webhook body dtos.NewWebhookDto true "The webhook. No event types means all event types."
+// @Success 201 {object} dtos.WebhookDto
+func RouteCreateWebhook(
+	writer http.ResponseWriter,
+	request *http.Request,
+	webhooksService Service,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	projectId, err := utils.UintFromVars(request, "projectId")
+	if err != nil {
+		return err
+	}
+
+	dto := NewWebhookDto{}
+	err = utils.ReadJson(request.Context(), request, &dto)
+	if err != nil {
+		return err
+	}
+
+	hook, err := webhooksService.CreateWebhook(request.Context(), session.UserId(), projectId, dto)
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusCreated, hook)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Update a webhook
+// @Tags webhooks
+// @Router /projects/{projectId}/webhooks/{webhookId} [put]
+// @Param projectId path int true "The project ID"
+// @Param webhookId path int true "The webhook ID"
+// @Param webhook body dtos.UpdateWebhookDto true "The webhook"
+// @Success 200 {object} dtos.WebhookDto
+func RouteUpdateWebhook(
+	writer http.ResponseWriter,
+	request *http.Request,
+	webhooksService Service,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	projectId, err := utils.UintFromVars(request, "projectId")
+	if err != nil {
+		return err
+	}
+
+	webhookId, err := utils.UintFromVars(request, "webhookId")
+	if err != nil {
+		return err
+	}
+
+	dto := UpdateWebhookDto{}
+	err = utils.ReadJson(request.Context(), request, &dto)
+	if err != nil {
+		return err
+	}
+
+	hook, err := webhooksService.UpdateWebhook(request.Context(), session.UserId(), projectId, webhookId, dto)
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusOK, hook)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Delete a webhook
+// @Tags webhooks
+// @Router /projects/{projectId}/webhooks/{webhookId} [delete]
+// @Param projectId path int true "The project ID"
+// @Param webhookId path int true "The webhook ID"
+// @Success 204
+func RouteDeleteWebhook(
+	writer http.ResponseWriter,
+	request *http.Request,
+	webhooksService Service,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	projectId, err := utils.UintFromVars(request, "projectId")
+	if err != nil {
+		return err
+	}
+
+	webhookId, err := utils.UintFromVars(request, "webhookId")
+	if err != nil {
+		return err
+	}
+
+	err = webhooksService.DeleteWebhook(request.Context(), session.UserId(), projectId, webhookId)
+	if err != nil {
+		return err
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// @Summary List a webhook's deliveries
+// @Tags webhooks
+// @Router /projects/{projectId}/webhooks/{webhookId}/deliveries [get]
+// @Param projectId path int true "The project ID"
+// @Param webhookId path int true "The webhook ID"
+// @Param pageSize query int false "Maximum amount of deliveries in the response. Default is 20, max is 50."
+// @Param pageOffset query int false "Response page number."
+// @Success 200 {array} dtos.DeliverySummaryDto
+func RouteListDeliveries(
+	writer http.ResponseWriter,
+	request *http.Request,
+	webhooksService Service,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	projectId, err := utils.UintFromVars(request, "projectId")
+	if err != nil {
+		return err
+	}
+
+	webhookId, err := utils.UintFromVars(request, "webhookId")
+	if err != nil {
+		return err
+	}
+
+	pageSize, _ := utils.IntFromQuery(request, "pageSize", 20)
+	pageOffset, _ := utils.IntFromQuery(request, "pageOffset", 0)
+
+	if pageSize < 1 || pageSize > 50 {
+		pageSize = 20
+	}
+
+	if pageOffset < 1 {
+		pageOffset = 0
+	}
+
+	deliveries, err := webhooksService.ListDeliveries(
+		request.Context(),
+		session.UserId(),
+		projectId,
+		webhookId,
+		uint(pageSize),
+		uint(pageOffset),
+	)
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusOK, deliveries)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Get a webhook delivery with its attempts
+// @Tags webhooks
+// @Router /projects/{projectId}/webhooks/{webhookId}/deliveries/{deliveryId} [get]
+// @Param projectId path int true "The project ID"
+// @Param webhookId path int true "The webhook ID"
+// @Param deliveryId path int true "The delivery ID"
+// @Success 200 {object} dtos.DeliveryDto
+func RouteGetDelivery(
+	writer http.ResponseWriter,
+	request *http.Request,
+	webhooksService Service,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	projectId, err := utils.UintFromVars(request, "projectId")
+	if err != nil {
+		return err
+	}
+
+	webhookId, err := utils.UintFromVars(request, "webhookId")
+	if err != nil {
+		return err
+	}
+
+	deliveryId, err := utils.UintFromVars(request, "deliveryId")
+	if err != nil {
+		return err
+	}
+
+	delivery, err := webhooksService.GetDelivery(request.Context(), session.UserId(), projectId, webhookId, deliveryId)
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusOK, delivery)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Send a webhook delivery again
+// @Tags webhooks
+// @Router /projects/{projectId}/webhooks/{webhookId}/deliveries/{deliveryId}/redeliver [post]
+// @Param projectId path int true "The project ID"
+// @Param webhookId path int true "The webhook ID"
+// @Param deliveryId path int true "The delivery ID"
+// @Success 202 {object} dtos.DeliverySummaryDto
+func RouteRedeliver(
+	writer http.ResponseWriter,
+	request *http.Request,
+	webhooksService Service,
+) error {
+	session, err := auth.CheckSession(request)
+	if err != nil {
+		return err
+	}
+
+	projectId, err := utils.UintFromVars(request, "projectId")
+	if err != nil {
+		return err
+	}
+
+	webhookId, err := utils.UintFromVars(request, "webhookId")
+	if err != nil {
+		return err
+	}
+
+	deliveryId, err := utils.UintFromVars(request, "deliveryId")
+	if err != nil {
+		return err
+	}
+
+	delivery, err := webhooksService.Redeliver(request.Context(), session.UserId(), projectId, webhookId, deliveryId)
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteJson(writer, request.Context(), http.StatusAccepted, delivery)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,423 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"github.com/apex/log"
+	"github.com/go-playground/validator/v10"
+	"github.com/open-collaboration/server/auth"
+	"github.com/open-collaboration/server/projects"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Maximum amount of webhooks per project.
+const maxWebhooksPerProject = 10
+
+var ErrWebhookNotFound = errors.New("webhook not found")
+var ErrDeliveryNotFound = errors.New("webhook delivery not found")
+var ErrTooManyWebhooks = errors.New("project has too many webhooks")
+
+type Service interface {
+	// List a project's webhooks. Only the project's owner and maintainers
+	// can manage webhooks.
+	// Returns auth.ErrForbidden if userId can't manage the project.
+	ListWebhooks(ctx context.Context, userId uint, projectId uint) ([]WebhookDto, error)
+
+	// Add a webhook to a project. A secret is generated for the webhook, it's
+	// only returned here.
+	// Returns auth.ErrForbidden if userId can't manage the project and
+	// ErrTooManyWebhooks if the project already has the maximum amount of
+	// webhooks.
+	CreateWebhook(ctx context.Context, userId uint, projectId uint, newWebhook NewWebhookDto) (WebhookDto, error)
+
+	// Change a webhook's URL, event types or whether it's active.
+	// Returns auth.ErrForbidden if userId can't manage the project and
+	// ErrWebhookNotFound if the webhook isn't one of the project's.
+	UpdateWebhook(
+		ctx context.Context,
+		userId uint,
+		projectId uint,
+		webhookId uint,
+		webhook UpdateWebhookDto,
+	) (WebhookDto, error)
+
+	// Delete a webhook. Its pending deliveries fail.
+	// Returns the same errors as UpdateWebhook.
+	DeleteWebhook(ctx context.Context, userId uint, projectId uint, webhookId uint) error
+
+	// List a webhook's deliveries, newest to oldest.
+	// Returns the same errors as UpdateWebhook.
+	ListDeliveries(
+		ctx context.Context,
+		userId uint,
+		projectId uint,
+		webhookId uint,
+		pageSize uint,
+		pageOffset uint,
+	) ([]DeliverySummaryDto, error)
+
+	// Get a delivery with its payload and its attempts.
+	// Returns the same errors as UpdateWebhook and ErrDeliveryNotFound if the
+	// delivery isn't one of the webhook's.
+	GetDelivery(ctx context.Context, userId uint, projectId uint, webhookId uint, deliveryId uint) (DeliveryDto, error)
+
+	// Queue a delivery to be sent again, with the same payload. The
+	// redelivery is a new delivery with its own attempts.
+	// Returns the same errors as GetDelivery.
+	Redeliver(ctx context.Context, userId uint, projectId uint, webhookId uint, deliveryId uint) (DeliverySummaryDto, error)
+}
+
+type serviceImpl struct {
+	Db              *gorm.DB
+	ProjectsService projects.Service
+}
+
+func NewService(db *gorm.DB, projectsService projects.Service) Service {
+	return &serviceImpl{
+		Db:              db,
+		ProjectsService: projectsService,
+	}
+}
+
+func (s *serviceImpl) ListWebhooks(ctx context.Context, userId uint, projectId uint) ([]WebhookDto, error) {
+	err := s.checkCanManage(ctx, userId, projectId)
+	if err != nil {
+		return nil, err
+	}
+
+	var hooks []ProjectWebhook
+	err = s.Db.
+		Where("project_id = ?", projectId).
+		Order("id").
+		Find(&hooks).
+		Error
+	if err != nil {
+		log.FromContext(ctx).WithError(err).Error("Failed to list webhooks")
+		return nil, err
+	}
+
+	dtos := make([]WebhookDto, len(hooks))
+	for i, hook := range hooks {
+		dtos[i] = webhookDto(hook)
+	}
+
+	return dtos, nil
+}
+
+func (s *serviceImpl) CreateWebhook(
+	ctx context.Context,
+	userId uint,
+	projectId uint,
+	newWebhook NewWebhookDto,
+) (WebhookDto, error) {
+	logger := log.FromContext(ctx).WithField("projectId", projectId)
+
+	err := validator.New().Struct(newWebhook)
+	if err != nil {
+		return WebhookDto{}, err
+	}
+
+	err = s.checkCanManage(ctx, userId, projectId)
+	if err != nil {
+		return WebhookDto{}, err
+	}
+
+	secret, err := randomHex(32)
+	if err != nil {
+		return WebhookDto{}, err
+	}
+
+	if newWebhook.EventTypes == nil {
+		newWebhook.EventTypes = []string{}
+	}
+
+	hook := ProjectWebhook{
+		ProjectId:  projectId,
+		Url:        newWebhook.Url,
+		Secret:     secret,
+		EventTypes: newWebhook.EventTypes,
+		Active:     true,
+	}
+
+	err = s.Db.Transaction(func(tx *gorm.DB) error {
+		// Serialize webhook creation per project so that the limit holds
+		err := tx.Exec("SELECT id FROM projects WHERE id = ? FOR UPDATE", projectId).Error
+		if err != nil {
+			return err
+		}
+
+		var count int64
+		err = tx.Model(&ProjectWebhook{}).Where("project_id = ?", projectId).Count(&count).Error
+		if err != nil {
+			return err
+		}
+
+		if count >= maxWebhooksPerProject {
+			return ErrTooManyWebhooks
+		}
+
+		return tx.Create(&hook).Error
+	})
+	if err != nil {
+		if !errors.Is(err, ErrTooManyWebhooks) {
+			logger.WithError(err).Error("Failed to create webhook")
+		}
+
+		return WebhookDto{}, err
+	}
+
+	dto := webhookDto(hook)
+	dto.Secret = hook.Secret
+
+	return dto, nil
+}
+
+func (s *serviceImpl) UpdateWebhook(
+	ctx context.Context,
+	userId uint,
+	projectId uint,
+	webhookId uint,
+	webhookData UpdateWebhookDto,
+) (WebhookDto, error) {
+	err := validator.New().Struct(webhookData)
+	if err != nil {
+		return WebhookDto{}, err
+	}
+
+	hook, err := s.findWebhook(ctx, userId, projectId, webhookId)
+	if err != nil {
+		return WebhookDto{}, err
+	}
+
+	if webhookData.EventTypes == nil {
+		webhookData.EventTypes = []string{}
+	}
+
+	hook.Url = webhookData.Url
+	hook.EventTypes = webhookData.EventTypes
+	hook.Active = webhookData.Active
+
+	err = s.Db.Select("url", "event_types", "active").Updates(hook).Error
+	if err != nil {
+		log.FromContext(ctx).WithError(err).Error("Failed to update webhook")
+		return WebhookDto{}, err
+	}
+
+	return webhookDto(*hook), nil
+}
+
+func (s *serviceImpl) DeleteWebhook(ctx context.Context, userId uint, projectId uint, webhookId uint) error {
+	hook, err := s.findWebhook(ctx, userId, projectId, webhookId)
+	if err != nil {
+		return err
+	}
+
+	err = s.Db.Delete(hook).Error
+	if err != nil {
+		log.FromContext(ctx).WithError(err).Error("Failed to delete webhook")
+		return err
+	}
+
+	return nil
+}
+
+func (s *serviceImpl) ListDeliveries(
+	ctx context.Context,
+	userId uint,
+	projectId uint,
+	webhookId uint,
+	pageSize uint,
+	pageOffset uint,
+) ([]DeliverySummaryDto, error) {
+	hook, err := s.findWebhook(ctx, userId, projectId, webhookId)
+	if err != nil {
+		return nil, err
+	}
+
+	var deliveries []WebhookDelivery
+	err = s.Db.
+		Where("webhook_id = ?", hook.ID).
+		Order("created_at desc, id desc").
+		Limit(int(pageSize)).
+		Offset(int(pageOffset * pageSize)).
+		Find(&deliveries).
+		Error
+	if err != nil {
+		log.FromContext(ctx).WithError(err).Error("Failed to list webhook deliveries")
+		return nil, err
+	}
+
+	dtos := make([]DeliverySummaryDto, len(deliveries))
+	for i, delivery := range deliveries {
+		dtos[i] = deliverySummaryDto(delivery)
+	}
+
+	return dtos, nil
+}
+
+func (s *serviceImpl) GetDelivery(
+	ctx context.Context,
+	userId uint,
+	projectId uint,
+	webhookId uint,
+	deliveryId uint,
+) (DeliveryDto, error) {
+	delivery, err := s.findDelivery(ctx, userId, projectId, webhookId, deliveryId)
+	if err != nil {
+		return DeliveryDto{}, err
+	}
+
+	var attempts []WebhookDeliveryAttempt
+	err = s.Db.
+		Where("delivery_id = ?", delivery.ID).
+		Order("created_at").
+		Find(&attempts).
+		Error
+	if err != nil {
+		log.FromContext(ctx).WithError(err).Error("Failed to list webhook delivery attempts")
+		return DeliveryDto{}, err
+	}
+
+	dto := DeliveryDto{
+		DeliverySummaryDto: deliverySummaryDto(*delivery),
+		Payload:            delivery.Payload,
+		AttemptLog:         make([]AttemptDto, len(attempts)),
+	}
+
+	for i, attempt := range attempts {
+		dto.AttemptLog[i] = AttemptDto{
+			StatusCode:   attempt.StatusCode,
+			Error:        attempt.Error,
+			ResponseBody: attempt.ResponseBody,
+			DurationMs:   attempt.DurationMs,
+			CreatedAt:    attempt.CreatedAt,
+		}
+	}
+
+	return dto, nil
+}
+
+func (s *serviceImpl) Redeliver(
+	ctx context.Context,
+	userId uint,
+	projectId uint,
+	webhookId uint,
+	deliveryId uint,
+) (DeliverySummaryDto, error) {
+	original, err := s.findDelivery(ctx, userId, projectId, webhookId, deliveryId)
+	if err != nil {
+		return DeliverySummaryDto{}, err
+	}
+
+	// Receivers can tell a redelivery apart by the delivery id, the event
+	// id stays the same.
+	delivery := WebhookDelivery{
+		WebhookId:     original.WebhookId,
+		EventId:       original.EventId,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		Status:        DeliveryStatusPending,
+		NextAttemptAt: time.Now(),
+		RedeliveryOf:  &original.ID,
+	}
+
+	err = s.Db.Create(&delivery).Error
+	if err != nil {
+		log.FromContext(ctx).WithError(err).Error("Failed to queue webhook redelivery")
+		return DeliverySummaryDto{}, err
+	}
+
+	return deliverySummaryDto(delivery), nil
+}
+
+// Check whether a user is the owner or a maintainer of a project.
+// Returns auth.ErrForbidden if not.
+func (s *serviceImpl) checkCanManage(ctx context.Context, userId uint, projectId uint) error {
+	role, err := s.ProjectsService.GetMemberRole(ctx, projectId, userId)
+	if err != nil {
+		return err
+	}
+
+	if role != projects.MemberRoleOwner && role != projects.MemberRoleMaintainer {
+		return auth.ErrForbidden
+	}
+
+	return nil
+}
+
+// Find one of a project's webhooks, checking that userId can manage it.
+// Returns auth.ErrForbidden if userId can't manage the project and
+// ErrWebhookNotFound if the webhook can't be found.
+func (s *serviceImpl) findWebhook(ctx context.Context, userId uint, projectId uint, webhookId uint) (*ProjectWebhook, error) {
+	err := s.checkCanManage(ctx, userId, projectId)
+	if err != nil {
+		return nil, err
+	}
+
+	hook := &ProjectWebhook{}
+	err = s.Db.Where("project_id = ?", projectId).First(hook, webhookId).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+
+		log.FromContext(ctx).WithError(err).Error("Failed to find webhook")
+		return nil, err
+	}
+
+	return hook, nil
+}
+
+// Find one of a webhook's deliveries, checking that userId can manage the
+// webhook.
+// Returns the same errors as findWebhook and ErrDeliveryNotFound if the
+// delivery can't be found.
+func (s *serviceImpl) findDelivery(
+	ctx context.Context,
+	userId uint,
+	projectId uint,
+	webhookId uint,
+	deliveryId uint,
+) (*WebhookDelivery, error) {
+	hook, err := s.findWebhook(ctx, userId, projectId, webhookId)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := &WebhookDelivery{}
+	err = s.Db.Where("webhook_id = ?", hook.ID).First(delivery, deliveryId).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDeliveryNotFound
+		}
+
+		log.FromContext(ctx).WithError(err).Error("Failed to find webhook delivery")
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+func webhookDto(hook ProjectWebhook) WebhookDto {
+	return WebhookDto{
+		Id:         hook.ID,
+		Url:        hook.Url,
+		EventTypes: hook.EventTypes,
+		Active:     hook.Active,
+		CreatedAt:  hook.CreatedAt,
+	}
+}
+
+func deliverySummaryDto(delivery WebhookDelivery) DeliverySummaryDto {
+	return DeliverySummaryDto{
+		Id:            delivery.ID,
+		EventId:       delivery.EventId,
+		EventType:     delivery.EventType,
+		Status:        delivery.Status,
+		Attempts:      delivery.Attempts,
+		NextAttemptAt: delivery.NextAttemptAt,
+		RedeliveryOf:  delivery.RedeliveryOf,
+		CreatedAt:     delivery.CreatedAt,
+	}
+}